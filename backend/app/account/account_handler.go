@@ -0,0 +1,164 @@
+package account
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   AccountService
+	validator *validator.Validate
+}
+
+func NewHandler(service AccountService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// RequestExportHandler starts a data export, the archive is downloaded once its status is ready
+func (h *Handler) RequestExportHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := ExportRequest{UserId: c.GetString("userId")}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.RequestExportService(ctx, req)
+	if err != nil {
+		logger.Error("error while request data export : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) GetExportHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := ExportRequest{
+		UserId:   c.GetString("userId"),
+		ExportId: c.Param("exportID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetExportService(ctx, req)
+	if err != nil {
+		logger.Error("error while get data export : " + err.Error())
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) DownloadExportHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := ExportRequest{
+		UserId:   c.GetString("userId"),
+		ExportId: c.Param("exportID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	archive, err := h.service.DownloadExportService(ctx, req)
+	if err != nil {
+		logger.Error("error while download data export : " + err.Error())
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="ailaw-export-`+req.ExportId+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// DeleteAccountHandler erases the signed in user's account and all of their data
+func (h *Handler) DeleteAccountHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := DeleteAccountRequest{UserId: c.GetString("userId")}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.DeleteAccountService(ctx, req)
+	if err != nil {
+		logger.Error("error while delete account : " + err.Error())
+		respondError(c, err)
+		return
+	}
+
+	logger.Info("account deleted")
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrAccountNotFound), errors.Is(err, ErrExportNotFound):
+		c.JSON(http.StatusNotFound, app.Response{
+			Code:    app.NotFoundErrorCode,
+			Message: app.NotFoundErrorMessage,
+		})
+	case errors.Is(err, ErrExportNotReady):
+		c.JSON(http.StatusConflict, app.Response{
+			Code:    app.ExportNotReadyErrorCode,
+			Message: app.ExportNotReadyErrorMessage,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+	}
+}
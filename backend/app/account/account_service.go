@@ -0,0 +1,256 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/app/auth"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/quota"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	cfg         *config.Account
+	storage     AccountStorage
+	quota       quota.QuotaService
+	revocations *auth.Revocations
+	audit       audit.AuditService
+	exportSlots chan struct{}
+}
+
+func NewService(cfg *config.Account, storage AccountStorage, quotaService quota.QuotaService, revocations *auth.Revocations, auditService audit.AuditService) *Service {
+	slots := cfg.MaxConcurrentExports
+	if slots <= 0 {
+		slots = 1
+	}
+	return &Service{
+		cfg:         cfg,
+		storage:     storage,
+		quota:       quotaService,
+		revocations: revocations,
+		audit:       auditService,
+		exportSlots: make(chan struct{}, slots),
+	}
+}
+
+func (s *Service) RequestExportService(ctx context.Context, req ExportRequest) (ExportResponse, error) {
+	now := time.Now()
+
+	// an export older than the timeout was given up, or lost with a restart, and does not block a new one
+	running, err := s.storage.GetRunningExportStorage(ctx, req.UserId, now.Add(-s.cfg.ExportTimeout))
+	if err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to get running export in storage error : %w", err)
+	}
+	if running != nil {
+		return toExportResponse(*running), nil
+	}
+
+	data := ExportData{
+		ExportId:  uuid.New().String(),
+		UserId:    req.UserId,
+		Status:    ExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.ExportTTL),
+	}
+	if err := s.storage.CreateExportStorage(ctx, data); err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to create export in storage error : %w", err)
+	}
+
+	// the archive is built after the request returns, under the request's logger and audit client
+	exportCtx, cancel := context.WithDeadline(context.WithoutCancel(ctx), now.Add(s.cfg.ExportTimeout))
+	go func() {
+		defer cancel()
+		s.runExport(exportCtx, data)
+	}()
+
+	return toExportResponse(data), nil
+}
+
+func (s *Service) runExport(ctx context.Context, data ExportData) {
+	logger := logging.FromContext(ctx).With("exportId", data.ExportId)
+
+	select {
+	case s.exportSlots <- struct{}{}:
+		defer func() { <-s.exportSlots }()
+	case <-ctx.Done():
+		s.failExport(ctx, data, fmt.Errorf("error when wait for an export slot: %v", ctx.Err()))
+		return
+	}
+
+	archive, err := s.buildArchive(ctx, data.UserId)
+	if err != nil {
+		s.failExport(ctx, data, err)
+		return
+	}
+
+	if err := s.storage.CompleteExportStorage(ctx, data.ExportId, archive); err != nil {
+		s.failExport(ctx, data, fmt.Errorf("failed to complete export in storage error : %w", err))
+		return
+	}
+
+	logger.Info("data export ready", "sizeBytes", len(archive))
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    data.UserId,
+		Action:     audit.ActionAccountExport,
+		TargetType: audit.TargetUser,
+		TargetId:   data.UserId,
+		After: map[string]any{
+			"exportId":  data.ExportId,
+			"sizeBytes": len(archive),
+		},
+	})
+}
+
+func (s *Service) failExport(ctx context.Context, data ExportData, cause error) {
+	logger := logging.FromContext(ctx).With("exportId", data.ExportId)
+	logger.Error("data export failed", "error", cause)
+
+	// the deadline may be what failed the export, marking it failed must not depend on it
+	if err := s.storage.FailExportStorage(context.WithoutCancel(ctx), data.ExportId, "export failed"); err != nil {
+		logger.Error("failed to mark data export failed", "error", err)
+	}
+}
+
+// buildArchive assembles the user's data as a ZIP of one JSON file per kind of data,
+// messages are written one JSON object per line as they are read
+func (s *Service) buildArchive(ctx context.Context, userId string) ([]byte, error) {
+	profile, err := s.storage.GetProfileStorage(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile in storage error : %w", err)
+	}
+	folders, err := s.storage.GetFoldersStorage(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders in storage error : %w", err)
+	}
+	sessions, err := s.storage.GetSessionsStorage(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions in storage error : %w", err)
+	}
+	feedback, err := s.storage.GetFeedbackStorage(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback in storage error : %w", err)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"profile.json", profile},
+		{"folders.json", folders},
+		{"sessions.json", sessions},
+		{"feedback.json", feedback},
+	}
+	for _, file := range files {
+		if err := writeJSONFile(archive, file.name, file.value); err != nil {
+			return nil, err
+		}
+	}
+
+	w, err := archive.Create("messages.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("error when create messages.jsonl: %v", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	err = s.storage.StreamMessagesStorage(ctx, userId, func(data MessageData) error {
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("error when encode message: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream messages in storage error : %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("error when close export archive: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, value any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("error when create %s: %v", name, err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("error when encode %s: %v", name, err)
+	}
+	return nil
+}
+
+func (s *Service) GetExportService(ctx context.Context, req ExportRequest) (ExportResponse, error) {
+	data, err := s.storage.GetExportStorage(ctx, req.UserId, req.ExportId)
+	if err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to get export in storage error : %w", err)
+	}
+	return toExportResponse(*data), nil
+}
+
+func (s *Service) DownloadExportService(ctx context.Context, req ExportRequest) ([]byte, error) {
+	archive, err := s.storage.GetExportArchiveStorage(ctx, req.UserId, req.ExportId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export archive in storage error : %w", err)
+	}
+	return archive, nil
+}
+
+// DeleteAccountService erases the user's data and signs them out everywhere. Refresh tokens go with
+// the user row, access tokens still in flight are revoked in Redis
+func (s *Service) DeleteAccountService(ctx context.Context, req DeleteAccountRequest) (DeletionSummary, error) {
+	logger := logging.FromContext(ctx)
+
+	summary, err := s.storage.DeleteAccountStorage(ctx, req.UserId)
+	if err != nil {
+		return DeletionSummary{}, fmt.Errorf("failed to delete account in storage error : %w", err)
+	}
+
+	if err := s.revocations.RevokeUser(ctx, req.UserId); err != nil {
+		logger.Error("failed to revoke access tokens of deleted account", "error", err)
+	}
+	if err := s.quota.ClearQuota(ctx, req.UserId); err != nil {
+		logger.Error("failed to clear quota of deleted account", "error", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAccountDelete,
+		TargetType: audit.TargetUser,
+		TargetId:   req.UserId,
+		After:      summary,
+	})
+	return summary, nil
+}
+
+func (s *Service) PurgeExpiredExportsService(ctx context.Context) (int64, error) {
+	purged, err := s.storage.PurgeExportsStorage(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge exports in storage error : %w", err)
+	}
+	return purged, nil
+}
+
+func toExportResponse(data ExportData) ExportResponse {
+	return ExportResponse{
+		ExportId:    data.ExportId,
+		Status:      data.Status,
+		SizeBytes:   data.SizeBytes,
+		Error:       data.Error,
+		CreatedAt:   data.CreatedAt,
+		CompletedAt: data.CompletedAt,
+		ExpiresAt:   data.ExpiresAt,
+	}
+}
@@ -0,0 +1,434 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db     *pgxpool.Pool
+	cipher encryption.Cipher
+}
+
+func NewStorage(db *pgxpool.Pool, cipher encryption.Cipher) *Storage {
+	return &Storage{db: db, cipher: cipher}
+}
+
+func (s *Storage) CreateExportStorage(ctx context.Context, data ExportData) error {
+	query := `INSERT INTO data_exports
+				(export_id, user_id, status, created_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.Exec(ctx, query, data.ExportId, data.UserId, data.Status, data.CreatedAt, data.ExpiresAt)
+	return err
+}
+
+// GetRunningExportStorage returns the user's pending export started after startedAfter, nil when there is none
+func (s *Storage) GetRunningExportStorage(ctx context.Context, userId string, startedAfter time.Time) (*ExportData, error) {
+	query := `
+		SELECT export_id, user_id, status, size_bytes, error, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE user_id = $1 AND status = 'pending' AND created_at > $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	data, err := scanExport(s.db.QueryRow(ctx, query, userId, startedAfter))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *Storage) GetExportStorage(ctx context.Context, userId, exportId string) (*ExportData, error) {
+	query := `
+		SELECT export_id, user_id, status, size_bytes, error, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE export_id = $1 AND user_id = $2 AND expires_at > NOW()
+	`
+
+	data, err := scanExport(s.db.QueryRow(ctx, query, exportId, userId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	return data, err
+}
+
+func (s *Storage) GetExportArchiveStorage(ctx context.Context, userId, exportId string) ([]byte, error) {
+	query := `
+		SELECT status, archive
+		FROM data_exports
+		WHERE export_id = $1 AND user_id = $2 AND expires_at > NOW()
+	`
+
+	var status string
+	var archive []byte
+	err := s.db.QueryRow(ctx, query, exportId, userId).Scan(&status, &archive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+
+	if status != ExportReady {
+		return nil, ErrExportNotReady
+	}
+	return archive, nil
+}
+
+func (s *Storage) CompleteExportStorage(ctx context.Context, exportId string, archive []byte) error {
+	query := `UPDATE data_exports
+			  SET status = 'ready', archive = $1, size_bytes = $2, completed_at = NOW()
+			  WHERE export_id = $3`
+
+	_, err := s.db.Exec(ctx, query, archive, len(archive), exportId)
+	return err
+}
+
+func (s *Storage) FailExportStorage(ctx context.Context, exportId string, reason string) error {
+	query := `UPDATE data_exports
+			  SET status = 'failed', error = $1, completed_at = NOW()
+			  WHERE export_id = $2`
+
+	_, err := s.db.Exec(ctx, query, reason, exportId)
+	return err
+}
+
+func (s *Storage) PurgeExportsStorage(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.Exec(ctx, `DELETE FROM data_exports WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func scanExport(row pgx.Row) (*ExportData, error) {
+	var data ExportData
+	err := row.Scan(
+		&data.ExportId,
+		&data.UserId,
+		&data.Status,
+		&data.SizeBytes,
+		&data.Error,
+		&data.CreatedAt,
+		&data.CompletedAt,
+		&data.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *Storage) GetProfileStorage(ctx context.Context, userId string) (ProfileData, error) {
+	query := `
+		SELECT user_id, email, username, picture, role, plan, org_id, created_at, updated_at
+		FROM users
+		WHERE user_id = $1
+	`
+
+	var data ProfileData
+	err := s.db.QueryRow(ctx, query, userId).Scan(
+		&data.UserId,
+		&data.Email,
+		&data.Username,
+		&data.Picture,
+		&data.Role,
+		&data.Plan,
+		&data.OrgId,
+		&data.CreatedAt,
+		&data.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ProfileData{}, ErrAccountNotFound
+	}
+	return data, err
+}
+
+func (s *Storage) GetFoldersStorage(ctx context.Context, userId string) ([]FolderData, error) {
+	query := `
+		SELECT folder_id, name, created_at, updated_at
+		FROM session_folders
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []FolderData{}
+
+	for rows.Next() {
+		var data FolderData
+		if err := rows.Scan(&data.FolderId, &data.Name, &data.CreatedAt, &data.UpdatedAt); err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+// GetSessionsStorage returns every session of the user, including the ones in the trash
+func (s *Storage) GetSessionsStorage(ctx context.Context, userId string) ([]SessionData, error) {
+	query := `
+		SELECT
+			s.session_id,
+			s.title,
+			s.folder_id,
+			COALESCE(array_agg(t.tag ORDER BY t.tag) FILTER (WHERE t.tag IS NOT NULL), '{}') AS tags,
+			s.pinned_at,
+			s.archived_at,
+			s.deleted_at,
+			s.created_at,
+			s.last_message_at
+		FROM chat_sessions s
+		LEFT JOIN session_tags t ON t.session_id = s.session_id
+		WHERE s.user_id = $1
+		GROUP BY s.session_id
+		ORDER BY s.created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []SessionData{}
+
+	for rows.Next() {
+		var data SessionData
+		err := rows.Scan(
+			&data.SessionId,
+			&data.Title,
+			&data.FolderId,
+			&data.Tags,
+			&data.PinnedAt,
+			&data.ArchivedAt,
+			&data.DeletedAt,
+			&data.CreatedAt,
+			&data.LastMessageAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+// messageBatchSize is the number of messages read per query while they are streamed
+const messageBatchSize = 500
+
+// StreamMessagesStorage calls fn for every question and answer of the user's sessions, session by session
+// in the order they were written. The messages are read in batches and each batch is decrypted after its
+// rows are closed, loading a data key needs a connection of its own
+func (s *Storage) StreamMessagesStorage(ctx context.Context, userId string, fn func(MessageData) error) error {
+	var after *MessageData
+	for {
+		dataList, err := s.messagesBatch(ctx, userId, after)
+		if err != nil {
+			return err
+		}
+
+		for _, data := range dataList {
+			data.Content, err = s.cipher.Decrypt(ctx, data.Content, data.ContentKeyId)
+			if err != nil {
+				return fmt.Errorf("error when decrypt message %s: %v", data.MessageId, err)
+			}
+			if err := fn(data); err != nil {
+				return err
+			}
+		}
+
+		if len(dataList) < messageBatchSize {
+			return nil
+		}
+		after = &dataList[len(dataList)-1]
+	}
+}
+
+// messagesBatch reads the messages of the user following after
+func (s *Storage) messagesBatch(ctx context.Context, userId string, after *MessageData) ([]MessageData, error) {
+	args := []any{userId, messageBatchSize}
+	condition := "TRUE"
+	if after != nil {
+		args = append(args, after.SessionId, after.CreatedAt, after.MessageId)
+		condition = "(session_id, created_at, message_id) > ($3::uuid, $4, $5::uuid)"
+	}
+
+	query := `
+		SELECT message_id, session_id, role, content, model_type, parent_message_id, is_active, feedback, created_at,
+			content_key_id
+		FROM (
+			SELECT u.message_id, u.session_id, 'user' AS role, u.content, NULL::text AS model_type,
+				u.parent_message_id, u.is_active, NULL::smallint AS feedback, u.created_at, u.content_key_id
+			FROM user_messages u
+			JOIN chat_sessions s ON s.session_id = u.session_id
+			WHERE s.user_id = $1
+			UNION ALL
+			SELECT m.message_id, m.session_id, 'model' AS role, m.content, m.model_type,
+				m.parent_message_id, m.is_active, m.feedback, m.created_at, m.content_key_id
+			FROM model_messages m
+			JOIN chat_sessions s ON s.session_id = m.session_id
+			WHERE s.user_id = $1
+		) messages
+		WHERE ` + condition + `
+		ORDER BY session_id, created_at ASC, message_id ASC
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := make([]MessageData, 0, messageBatchSize)
+	for rows.Next() {
+		var data MessageData
+		err := rows.Scan(
+			&data.MessageId,
+			&data.SessionId,
+			&data.Role,
+			&data.Content,
+			&data.ModelType,
+			&data.ParentMessageId,
+			&data.IsActive,
+			&data.Feedback,
+			&data.CreatedAt,
+			&data.ContentKeyId,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return dataList, nil
+}
+
+func (s *Storage) GetFeedbackStorage(ctx context.Context, userId string) ([]FeedbackData, error) {
+	query := `
+		SELECT feedback_id, message_id, rating, categories, severity, suggested_correction, detail, created_at
+		FROM message_feedback
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []FeedbackData{}
+
+	for rows.Next() {
+		var data FeedbackData
+		err := rows.Scan(
+			&data.FeedbackId,
+			&data.MessageId,
+			&data.Rating,
+			&data.Categories,
+			&data.Severity,
+			&data.SuggestedCorrection,
+			&data.Detail,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+// DeleteAccountStorage deletes children before their parents so it does not depend on ON DELETE CASCADE.
+// The audit log entries are kept for their action and time, the IP, user agent and before/after state are cleared
+func (s *Storage) DeleteAccountStorage(ctx context.Context, userId string) (DeletionSummary, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return DeletionSummary{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var summary DeletionSummary
+	var users int64
+	userSessions := `SELECT session_id FROM chat_sessions WHERE user_id = $1`
+
+	// the audit log is append-only, the setting lets this transaction clear the personal fields of the user's entries
+	if _, err := tx.Exec(ctx, `SELECT set_config('audit.anonymise', 'on', true)`); err != nil {
+		return DeletionSummary{}, err
+	}
+
+	steps := []struct {
+		query string
+		count *int64
+	}{
+		{`UPDATE audit_logs
+			SET ip = '', user_agent = '', before = NULL, after = NULL
+			WHERE actor_id = $1
+				OR (target_type = 'user' AND target_id = $1::text)
+				OR (target_type = 'session' AND target_id IN (SELECT session_id::text FROM chat_sessions WHERE user_id = $1))
+				OR (target_type = 'message' AND target_id IN (
+					SELECT message_id::text FROM model_messages WHERE session_id IN (` + userSessions + `)))`, &summary.AuditLogs},
+		{`DELETE FROM message_feedback
+			WHERE user_id = $1
+				OR message_id IN (SELECT message_id FROM model_messages WHERE session_id IN (` + userSessions + `))`, &summary.Feedback},
+		{`DELETE FROM user_messages WHERE user_id = $1 OR session_id IN (` + userSessions + `)`, &summary.UserMessages},
+		{`DELETE FROM model_messages WHERE user_id = $1 OR session_id IN (` + userSessions + `)`, &summary.ModelMessages},
+		{`DELETE FROM session_tags WHERE session_id IN (` + userSessions + `)`, nil},
+		{`DELETE FROM chat_sessions WHERE user_id = $1`, &summary.Sessions},
+		{`DELETE FROM session_folders WHERE user_id = $1`, &summary.Folders},
+		{`DELETE FROM moderation_flags WHERE user_id = $1`, &summary.ModerationFlags},
+		{`DELETE FROM refresh_tokens WHERE user_id = $1`, &summary.RefreshTokens},
+		{`DELETE FROM data_exports WHERE user_id = $1`, &summary.Exports},
+		{`DELETE FROM data_keys WHERE user_id = $1`, nil},
+		{`DELETE FROM users WHERE user_id = $1`, &users},
+	}
+
+	for _, step := range steps {
+		result, err := tx.Exec(ctx, step.query, userId)
+		if err != nil {
+			return DeletionSummary{}, err
+		}
+		if step.count != nil {
+			*step.count = result.RowsAffected()
+		}
+	}
+
+	if users == 0 {
+		return DeletionSummary{}, ErrAccountNotFound
+	}
+
+	return summary, tx.Commit(ctx)
+}
@@ -0,0 +1,149 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrExportNotFound  = errors.New("data export not found")
+	ErrExportNotReady  = errors.New("data export is not ready")
+)
+
+// Statuses of a data export
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+type AccountService interface {
+	// RequestExportService starts assembling the user's data archive, a running export is returned instead of starting another
+	RequestExportService(ctx context.Context, req ExportRequest) (ExportResponse, error)
+	GetExportService(ctx context.Context, req ExportRequest) (ExportResponse, error)
+	DownloadExportService(ctx context.Context, req ExportRequest) ([]byte, error)
+	DeleteAccountService(ctx context.Context, req DeleteAccountRequest) (DeletionSummary, error)
+	PurgeExpiredExportsService(ctx context.Context) (int64, error)
+}
+
+type AccountStorage interface {
+	CreateExportStorage(ctx context.Context, data ExportData) error
+	GetRunningExportStorage(ctx context.Context, userId string, startedAfter time.Time) (*ExportData, error)
+	GetExportStorage(ctx context.Context, userId, exportId string) (*ExportData, error)
+	GetExportArchiveStorage(ctx context.Context, userId, exportId string) ([]byte, error)
+	CompleteExportStorage(ctx context.Context, exportId string, archive []byte) error
+	FailExportStorage(ctx context.Context, exportId string, reason string) error
+	PurgeExportsStorage(ctx context.Context, now time.Time) (int64, error)
+
+	GetProfileStorage(ctx context.Context, userId string) (ProfileData, error)
+	GetFoldersStorage(ctx context.Context, userId string) ([]FolderData, error)
+	GetSessionsStorage(ctx context.Context, userId string) ([]SessionData, error)
+	StreamMessagesStorage(ctx context.Context, userId string, fn func(MessageData) error) error
+	GetFeedbackStorage(ctx context.Context, userId string) ([]FeedbackData, error)
+
+	// DeleteAccountStorage removes the user and every row written for them in one transaction
+	DeleteAccountStorage(ctx context.Context, userId string) (DeletionSummary, error)
+}
+
+type ExportRequest struct {
+	UserId   string `json:"userId" validate:"required"`
+	ExportId string `json:"exportId" validate:"omitempty,uuid"`
+}
+
+type DeleteAccountRequest struct {
+	UserId string `json:"userId" validate:"required"`
+}
+
+type ExportResponse struct {
+	ExportId    string     `json:"exportId"`
+	Status      string     `json:"status"`
+	SizeBytes   *int64     `json:"sizeBytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+}
+
+// DeletionSummary counts the rows removed for a deleted account
+type DeletionSummary struct {
+	Sessions        int64 `json:"sessions"`
+	UserMessages    int64 `json:"userMessages"`
+	ModelMessages   int64 `json:"modelMessages"`
+	Feedback        int64 `json:"feedback"`
+	Folders         int64 `json:"folders"`
+	ModerationFlags int64 `json:"moderationFlags"`
+	RefreshTokens   int64 `json:"refreshTokens"`
+	Exports         int64 `json:"exports"`
+	// AuditLogs counts the audit entries whose client and before/after state were cleared
+	AuditLogs int64 `json:"auditLogs"`
+}
+
+type ExportData struct {
+	ExportId    string     `db:"export_id"`
+	UserId      string     `db:"user_id"`
+	Status      string     `db:"status"`
+	SizeBytes   *int64     `db:"size_bytes"`
+	Error       *string    `db:"error"`
+	CreatedAt   time.Time  `db:"created_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+}
+
+// The files of the export archive
+
+type ProfileData struct {
+	UserId    string     `json:"userId" db:"user_id"`
+	Email     string     `json:"email" db:"email"`
+	Username  *string    `json:"username" db:"username"`
+	Picture   *string    `json:"picture" db:"picture"`
+	Role      string     `json:"role" db:"role"`
+	Plan      string     `json:"plan" db:"plan"`
+	OrgId     *string    `json:"orgId" db:"org_id"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type FolderData struct {
+	FolderId  string     `json:"folderId" db:"folder_id"`
+	Name      string     `json:"name" db:"name"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type SessionData struct {
+	SessionId     string     `json:"sessionId" db:"session_id"`
+	Title         *string    `json:"title" db:"title"`
+	FolderId      *string    `json:"folderId" db:"folder_id"`
+	Tags          []string   `json:"tags" db:"tags"`
+	PinnedAt      *time.Time `json:"pinnedAt" db:"pinned_at"`
+	ArchivedAt    *time.Time `json:"archivedAt" db:"archived_at"`
+	DeletedAt     *time.Time `json:"deletedAt" db:"deleted_at"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	LastMessageAt *time.Time `json:"lastMessageAt" db:"last_message_at"`
+}
+
+type MessageData struct {
+	MessageId       string    `json:"messageId" db:"message_id"`
+	SessionId       string    `json:"sessionId" db:"session_id"`
+	Role            string    `json:"role" db:"role"`
+	Content         string    `json:"content" db:"content"`
+	ModelType       *string   `json:"modelType,omitempty" db:"model_type"`
+	ParentMessageId *string   `json:"parentMessageId" db:"parent_message_id"`
+	IsActive        bool      `json:"isActive" db:"is_active"`
+	Feedback        *int      `json:"feedback,omitempty" db:"feedback"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+	ContentKeyId    *string   `json:"-" db:"content_key_id"`
+}
+
+type FeedbackData struct {
+	FeedbackId          string    `json:"feedbackId" db:"feedback_id"`
+	MessageId           string    `json:"messageId" db:"message_id"`
+	Rating              *int      `json:"rating" db:"rating"`
+	Categories          []string  `json:"categories" db:"categories"`
+	Severity            *string   `json:"severity" db:"severity"`
+	SuggestedCorrection *string   `json:"suggestedCorrection" db:"suggested_correction"`
+	Detail              *string   `json:"detail" db:"detail"`
+	CreatedAt           time.Time `json:"createdAt" db:"created_at"`
+}
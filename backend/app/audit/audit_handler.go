@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   AuditService
+	validator *validator.Validate
+}
+
+func NewHandler(service AuditService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetAuditLogsHandler lists audit log entries, newest first
+func (h *Handler) GetAuditLogsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req GetAuditLogsRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil || !validRange(req.Filter) {
+		logger.Error("invalid request query", "error", err)
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetAuditLogsService(ctx, req)
+	if err != nil {
+		logger.Error("error while get audit logs : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+// ExportAuditLogsHandler streams the matching entries as a JSONL or CSV download
+func (h *Handler) ExportAuditLogsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req ExportAuditLogsRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil || !validRange(req.Filter) {
+		logger.Error("invalid request query", "error", err)
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	if req.Format == "" {
+		req.Format = FormatJSONL
+	}
+
+	contentType := "application/x-ndjson"
+	if req.Format == FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	fileName := "audit-log-" + time.Now().Format("20060102-150405") + "." + req.Format
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+
+	ctx := c.Request.Context()
+	count, err := h.service.ExportAuditLogsService(ctx, req, c.Writer)
+	if err != nil {
+		// the body is already streaming, the download ends truncated
+		logger.Error("error while export audit logs : "+err.Error(), "records", count)
+		return
+	}
+
+	logger.Info("audit logs exported", "records", count)
+}
+
+func validRange(filter Filter) bool {
+	return filter.From.IsZero() || filter.To.IsZero() || !filter.To.Before(filter.From)
+}
@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+const defaultAuditLogsLimit = 50
+
+var csvHeader = []string{"audit_id", "actor_id", "action", "target_type", "target_id", "ip", "user_agent", "request_id", "before", "after", "created_at"}
+
+type Service struct {
+	cfg     *config.Audit
+	storage AuditStorage
+}
+
+func NewService(cfg *config.Audit, storage AuditStorage) *Service {
+	return &Service{
+		cfg:     cfg,
+		storage: storage,
+	}
+}
+
+func (s *Service) Record(ctx context.Context, event Event) {
+	logger := logging.FromContext(ctx)
+
+	data, err := s.toAuditLogData(ctx, event)
+	if err == nil {
+		// the audited change is already done, the entry is written even when the request is cancelled
+		err = s.storage.SaveAuditLogStorage(context.WithoutCancel(ctx), data)
+	}
+	if err != nil {
+		logger.Error("failed to save audit log", "action", event.Action, "targetType", event.TargetType, "targetId", event.TargetId, "error", err)
+	}
+}
+
+func (s *Service) toAuditLogData(ctx context.Context, event Event) (AuditLogData, error) {
+	before, err := marshalState(event.Before)
+	if err != nil {
+		return AuditLogData{}, fmt.Errorf("error when marshal before state: %v", err)
+	}
+	after, err := marshalState(event.After)
+	if err != nil {
+		return AuditLogData{}, fmt.Errorf("error when marshal after state: %v", err)
+	}
+
+	client := clientFromContext(ctx)
+	data := AuditLogData{
+		AuditId:    uuid.NewString(),
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetId:   event.TargetId,
+		IP:         client.ip,
+		UserAgent:  client.userAgent,
+		RequestId:  logging.RequestID(ctx),
+		Before:     before,
+		After:      after,
+		CreatedAt:  time.Now(),
+	}
+	if event.ActorId != "" {
+		data.ActorId = &event.ActorId
+	}
+	return data, nil
+}
+
+func marshalState(state any) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+func (s *Service) GetAuditLogsService(ctx context.Context, req GetAuditLogsRequest) ([]AuditLogResponse, error) {
+	if req.Limit == 0 {
+		req.Limit = defaultAuditLogsLimit
+	}
+
+	dataList, err := s.storage.GetAuditLogsStorage(ctx, req.Filter, req.Limit, req.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit logs in storage error : %w", err)
+	}
+
+	resp := make([]AuditLogResponse, 0, len(dataList))
+	for _, data := range dataList {
+		resp = append(resp, toAuditLogResponse(data))
+	}
+	return resp, nil
+}
+
+// ExportAuditLogsService writes the matching entries to w, oldest first, and returns how many were written.
+// The export itself is recorded once it is complete
+func (s *Service) ExportAuditLogsService(ctx context.Context, req ExportAuditLogsRequest, w io.Writer) (int, error) {
+	var write func(AuditLogResponse) error
+	var flush func() error
+
+	switch req.Format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return 0, fmt.Errorf("error when write csv header: %v", err)
+		}
+		write = func(entry AuditLogResponse) error {
+			return writer.Write(toCSVRecord(entry))
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		write = func(entry AuditLogResponse) error {
+			return encoder.Encode(entry)
+		}
+		flush = func() error { return nil }
+	}
+
+	count := 0
+	err := s.storage.StreamAuditLogsStorage(ctx, req.Filter, func(data AuditLogData) error {
+		if err := write(toAuditLogResponse(data)); err != nil {
+			return fmt.Errorf("error when encode audit log: %v", err)
+		}
+		count++
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return count, fmt.Errorf("failed to stream audit logs in storage error : %w", err)
+	}
+
+	s.Record(ctx, Event{
+		ActorId:    req.UserId,
+		Action:     ActionAdminAuditLogExport,
+		TargetType: TargetAuditLog,
+		After: map[string]any{
+			"filter":  req.Filter,
+			"format":  req.Format,
+			"records": count,
+		},
+	})
+	return count, nil
+}
+
+func (s *Service) PurgeExpiredAuditLogsService(ctx context.Context) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -s.cfg.RetentionDays)
+	purged, err := s.storage.PurgeAuditLogsStorage(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge audit logs in storage error : %w", err)
+	}
+	return purged, nil
+}
+
+func toAuditLogResponse(data AuditLogData) AuditLogResponse {
+	return AuditLogResponse{
+		AuditId:    data.AuditId,
+		ActorId:    data.ActorId,
+		Action:     data.Action,
+		TargetType: data.TargetType,
+		TargetId:   data.TargetId,
+		IP:         data.IP,
+		UserAgent:  data.UserAgent,
+		RequestId:  data.RequestId,
+		Before:     data.Before,
+		After:      data.After,
+		CreatedAt:  data.CreatedAt,
+	}
+}
+
+func toCSVRecord(entry AuditLogResponse) []string {
+	actorId := ""
+	if entry.ActorId != nil {
+		actorId = *entry.ActorId
+	}
+	return []string{
+		entry.AuditId,
+		actorId,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetId,
+		entry.IP,
+		entry.UserAgent,
+		entry.RequestId,
+		string(entry.Before),
+		string(entry.After),
+		entry.CreatedAt.Format(time.RFC3339),
+	}
+}
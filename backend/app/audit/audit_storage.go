@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) SaveAuditLogStorage(ctx context.Context, data AuditLogData) error {
+	query := `INSERT INTO audit_logs
+				(audit_id, actor_id, action, target_type, target_id, ip, user_agent, request_id, before, after, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := s.db.Exec(ctx, query,
+		data.AuditId,
+		data.ActorId,
+		data.Action,
+		data.TargetType,
+		data.TargetId,
+		data.IP,
+		data.UserAgent,
+		data.RequestId,
+		data.Before,
+		data.After,
+		data.CreatedAt,
+	)
+	return err
+}
+
+func (s *Storage) GetAuditLogsStorage(ctx context.Context, filter Filter, limit, offset int) ([]AuditLogData, error) {
+	conditions, args := filterConditions(filter)
+	args = append(args, limit, offset)
+
+	query := `
+		SELECT audit_id, actor_id, action, target_type, target_id, ip, user_agent, request_id, before, after, created_at
+		FROM audit_logs
+		WHERE ` + conditions + fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d;
+	`, len(args)-1, len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []AuditLogData{}
+
+	for rows.Next() {
+		data, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+// StreamAuditLogsStorage calls fn for every entry matching filter, oldest first, reading one row at a time
+func (s *Storage) StreamAuditLogsStorage(ctx context.Context, filter Filter, fn func(AuditLogData) error) error {
+	conditions, args := filterConditions(filter)
+
+	query := `
+		SELECT audit_id, actor_id, action, target_type, target_id, ip, user_agent, request_id, before, after, created_at
+		FROM audit_logs
+		WHERE ` + conditions + `
+		ORDER BY created_at ASC;
+	`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		data, err := scanAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// PurgeAuditLogsStorage deletes entries created before the cutoff, the append-only trigger
+// of audit_logs only lets a delete through inside a transaction flagged as a retention purge
+func (s *Storage) PurgeAuditLogsStorage(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT set_config('audit.retention_purge', 'on', true)`); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM audit_logs WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func filterConditions(filter Filter) (string, []any) {
+	conditions := "TRUE"
+	args := []any{}
+
+	if filter.ActorId != nil {
+		args = append(args, *filter.ActorId)
+		conditions += fmt.Sprintf(" AND actor_id = $%d", len(args))
+	}
+	if filter.Action != nil {
+		args = append(args, *filter.Action)
+		conditions += fmt.Sprintf(" AND action = $%d", len(args))
+	}
+	if filter.TargetType != nil {
+		args = append(args, *filter.TargetType)
+		conditions += fmt.Sprintf(" AND target_type = $%d", len(args))
+	}
+	if filter.TargetId != nil {
+		args = append(args, *filter.TargetId)
+		conditions += fmt.Sprintf(" AND target_id = $%d", len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		// the to date is inclusive
+		args = append(args, filter.To.AddDate(0, 0, 1))
+		conditions += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	return conditions, args
+}
+
+func scanAuditLog(rows pgx.Rows) (AuditLogData, error) {
+	var data AuditLogData
+	err := rows.Scan(
+		&data.AuditId,
+		&data.ActorId,
+		&data.Action,
+		&data.TargetType,
+		&data.TargetId,
+		&data.IP,
+		&data.UserAgent,
+		&data.RequestId,
+		&data.Before,
+		&data.After,
+		&data.CreatedAt,
+	)
+	return data, err
+}
@@ -0,0 +1,22 @@
+package audit
+
+import "context"
+
+type contextKey int
+
+const clientKey contextKey = iota
+
+type client struct {
+	ip        string
+	userAgent string
+}
+
+// WithClient returns ctx carrying the IP and user agent the audited request came from
+func WithClient(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientKey, client{ip: ip, userAgent: userAgent})
+}
+
+func clientFromContext(ctx context.Context) client {
+	c, _ := ctx.Value(clientKey).(client)
+	return c
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Actions written to the audit log
+const (
+	ActionLogin                = "auth.login"
+	ActionLogout               = "auth.logout"
+	ActionTokenRefresh         = "auth.token_refresh"
+	ActionTokenRefreshRejected = "auth.token_refresh_rejected"
+	ActionSessionDelete        = "session.delete"
+	ActionSessionRestore       = "session.restore"
+	ActionSessionPurge         = "session.purge"
+	ActionSessionRename        = "session.rename"
+	ActionSessionBulk          = "session.bulk"
+	ActionFeedbackUpdate       = "feedback.update"
+	ActionFeedbackPreferred    = "feedback.preferred"
+	ActionAccountExport        = "account.export"
+	ActionAccountDelete        = "account.delete"
+	ActionAdminDatasetExport   = "admin.dataset_export"
+	ActionAdminPIIPolicyUpdate = "admin.pii_policy_update"
+	ActionAdminAuditLogExport  = "admin.audit_log_export"
+	ActionAdminRetentionUpdate = "admin.retention_policy_update"
+	ActionAdminJobRun          = "admin.job_run"
+	ActionAdminOrgCreate       = "admin.org_create"
+	ActionAdminOrgMemberAdd    = "admin.org_member_add"
+	ActionAdminOrgMemberRemove = "admin.org_member_remove"
+)
+
+// Types of the entity an action was taken on
+const (
+	TargetUser     = "user"
+	TargetSession  = "session"
+	TargetMessage  = "message"
+	TargetOrg      = "org"
+	TargetDataset  = "dataset"
+	TargetAuditLog = "audit_log"
+	TargetJob      = "job"
+)
+
+// Export formats of the audit log
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+type AuditService interface {
+	// Record appends event to the audit log, a failure is logged and never fails the audited action
+	Record(ctx context.Context, event Event)
+	GetAuditLogsService(ctx context.Context, req GetAuditLogsRequest) ([]AuditLogResponse, error)
+	ExportAuditLogsService(ctx context.Context, req ExportAuditLogsRequest, w io.Writer) (int, error)
+	PurgeExpiredAuditLogsService(ctx context.Context) (int64, error)
+}
+
+type AuditStorage interface {
+	SaveAuditLogStorage(ctx context.Context, data AuditLogData) error
+	GetAuditLogsStorage(ctx context.Context, filter Filter, limit, offset int) ([]AuditLogData, error)
+	StreamAuditLogsStorage(ctx context.Context, filter Filter, fn func(AuditLogData) error) error
+	PurgeAuditLogsStorage(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Event is one audited action. ActorId is empty for actions taken by the system itself,
+// Before and After hold the changed values and are stored as JSON
+type Event struct {
+	ActorId    string
+	Action     string
+	TargetType string
+	TargetId   string
+	Before     any
+	After      any
+}
+
+type Filter struct {
+	ActorId    *string   `json:"actorId,omitempty" form:"actorId" validate:"omitempty,uuid"`
+	Action     *string   `json:"action,omitempty" form:"action" validate:"omitempty,max=100"`
+	TargetType *string   `json:"targetType,omitempty" form:"targetType" validate:"omitempty,max=50"`
+	TargetId   *string   `json:"targetId,omitempty" form:"targetId" validate:"omitempty,max=100"`
+	From       time.Time `json:"from,omitzero" form:"from" time_format:"2006-01-02"`
+	To         time.Time `json:"to,omitzero" form:"to" time_format:"2006-01-02"`
+}
+
+type GetAuditLogsRequest struct {
+	Filter
+	Limit  int `form:"limit" validate:"omitempty,min=1,max=200"`
+	Offset int `form:"offset" validate:"omitempty,min=0"`
+}
+
+type ExportAuditLogsRequest struct {
+	Filter
+	Format string `form:"format" validate:"omitempty,oneof=jsonl csv"`
+	// UserId is the admin exporting the log
+	UserId string `form:"-"`
+}
+
+type AuditLogResponse struct {
+	AuditId    string          `json:"auditId"`
+	ActorId    *string         `json:"actorId"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetId   string          `json:"targetId"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"userAgent"`
+	RequestId  string          `json:"requestId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+type AuditLogData struct {
+	AuditId    string          `db:"audit_id"`
+	ActorId    *string         `db:"actor_id"`
+	Action     string          `db:"action"`
+	TargetType string          `db:"target_type"`
+	TargetId   string          `db:"target_id"`
+	IP         string          `db:"ip"`
+	UserAgent  string          `db:"user_agent"`
+	RequestId  string          `db:"request_id"`
+	Before     json.RawMessage `db:"before"`
+	After      json.RawMessage `db:"after"`
+	CreatedAt  time.Time       `db:"created_at"`
+}
@@ -1,10 +1,10 @@
 package auth
 
 import (
-	"log/slog"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,7 +26,7 @@ func (h *Handler) GoogleLogin(c *gin.Context) {
 }
 
 func (h *Handler) GoogleCallback(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req GoogleCallbackRequest
 
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -70,7 +70,7 @@ func (h *Handler) GoogleCallback(c *gin.Context) {
 }
 
 func (h *Handler) RefreshTokenProcess(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -114,7 +114,7 @@ func (h *Handler) RefreshTokenProcess(c *gin.Context) {
 }
 
 func (h *Handler) Logout(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/audit"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -17,12 +18,14 @@ import (
 type authService struct {
 	cfg     *config.Config
 	storage AuthStorage
+	audit   audit.AuditService
 }
 
-func NewService(cfg *config.Config, storage AuthStorage) *authService {
+func NewService(cfg *config.Config, storage AuthStorage, auditService audit.AuditService) *authService {
 	return &authService{
 		cfg:     cfg,
 		storage: storage,
+		audit:   auditService,
 	}
 }
 
@@ -87,6 +90,16 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, req GoogleCallba
 		return nil, fmt.Errorf("failed to generate token: %v", err)
 	}
 
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    user.ID,
+		Action:     audit.ActionLogin,
+		TargetType: audit.TargetUser,
+		TargetId:   user.ID,
+		After: map[string]any{
+			"newUser": resp == nil,
+		},
+	})
+
 	response := LoginResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
@@ -165,6 +178,13 @@ func (s *authService) RefreshTokenService(ctx context.Context, refreshToken stri
 
 	exists, err := s.storage.ValidateRefreshToken(ctx, claims.UserID, refreshToken)
 	if err != nil || !exists {
+		// a signed token that is no longer stored has been used already or was revoked
+		s.audit.Record(ctx, audit.Event{
+			ActorId:    claims.UserID,
+			Action:     audit.ActionTokenRefreshRejected,
+			TargetType: audit.TargetUser,
+			TargetId:   claims.UserID,
+		})
 		return nil, fmt.Errorf("refresh token not found or invalid")
 	}
 
@@ -178,6 +198,13 @@ func (s *authService) RefreshTokenService(ctx context.Context, refreshToken stri
 		return nil, fmt.Errorf("failed to generate new tokens: %v", err)
 	}
 
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    claims.UserID,
+		Action:     audit.ActionTokenRefresh,
+		TargetType: audit.TargetUser,
+		TargetId:   claims.UserID,
+	})
+
 	response := RefreshTokenProcessResponse{
 		AccessToken:  newTokens.AccessToken,
 		RefreshToken: newTokens.RefreshToken,
@@ -213,5 +240,26 @@ func (s *authService) LogoutProcess(ctx context.Context, refreshToken string) er
 	if err != nil {
 		return fmt.Errorf("failed to delete old refresh token: %v", err)
 	}
+
+	// an expired or malformed token is still logged out, only without a known actor
+	event := audit.Event{
+		Action:     audit.ActionLogout,
+		TargetType: audit.TargetUser,
+	}
+	if claims, err := s.validateRefreshToken(refreshToken); err == nil {
+		event.ActorId = claims.UserID
+		event.TargetId = claims.UserID
+	}
+	s.audit.Record(ctx, event)
 	return nil
 }
+
+// PurgeExpiredRefreshTokensService deletes refresh tokens that can no longer be used, they are never removed otherwise
+// unless the user logs out
+func (s *authService) PurgeExpiredRefreshTokensService(ctx context.Context) (int64, error) {
+	purged, err := s.storage.DeleteExpiredRefreshTokens(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired refresh tokens: %v", err)
+	}
+	return purged, nil
+}
@@ -120,3 +120,14 @@ func (s *authStorage) DeleteRefreshTokens(ctx context.Context, refreshToken stri
 
 	return nil
 }
+
+func (s *authStorage) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM refresh_tokens WHERE expires_at <= $1`
+
+	cmdTag, err := s.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired refresh tokens: %v", err)
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
@@ -10,6 +10,7 @@ type AuthService interface {
 	HandleGoogleCallback(ctx context.Context, req GoogleCallbackRequest) (*LoginResponse, error)
 	RefreshTokenService(ctx context.Context, refreshToken string) (*RefreshTokenProcessResponse, error)
 	LogoutProcess(ctx context.Context, refreshToken string) error
+	PurgeExpiredRefreshTokensService(ctx context.Context) (int64, error)
 }
 
 type AuthStorage interface {
@@ -20,6 +21,7 @@ type AuthStorage interface {
 	StoreRefreshToken(ctx context.Context, userID, token string, expiredAt time.Time) error
 	ValidateRefreshToken(ctx context.Context, userID, token string) (bool, error)
 	DeleteRefreshTokens(ctx context.Context, refreshToken string) error
+	DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error)
 }
 
 type GoogleCallbackRequest struct {
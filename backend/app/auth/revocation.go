@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AccessTokenTTL is how long an access token is valid, a revocation only has to outlive it
+const AccessTokenTTL = 15 * time.Minute
+
+// Revocations rejects access tokens issued before a user's tokens were revoked. Refresh tokens
+// are revoked by deleting them, access tokens are only checked against Redis until they expire
+type Revocations struct {
+	redis *redis.Client
+}
+
+func NewRevocations(redisClient *redis.Client) *Revocations {
+	return &Revocations{redis: redisClient}
+}
+
+func revokedUserKey(userID string) string {
+	return fmt.Sprintf("auth:revoked:user:%s", userID)
+}
+
+// RevokeUser rejects every access token issued to the user up to now
+func (r *Revocations) RevokeUser(ctx context.Context, userID string) error {
+	return r.redis.Set(ctx, revokedUserKey(userID), time.Now().Unix(), AccessTokenTTL).Err()
+}
+
+// IsRevoked reports whether an access token of the user issued at issuedAt has been revoked
+func (r *Revocations) IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	revokedAt, err := r.redis.Get(ctx, revokedUserKey(userID)).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !issuedAt.After(time.Unix(revokedAt, 0)), nil
+}
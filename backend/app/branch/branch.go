@@ -0,0 +1,87 @@
+package branch
+
+import (
+	"sort"
+	"time"
+)
+
+// Node is a message in the conversation tree, user and model messages alternate
+// and siblings (same parent) are alternative questions or answers
+type Node struct {
+	MessageId       string
+	ParentMessageId *string
+	Role            string
+	IsActive        bool
+	CreatedAt       time.Time
+}
+
+type PathItem struct {
+	Node
+	SiblingCount int
+	SiblingIndex int
+}
+
+// ActivePath walks the tree from the root following the active sibling at every fork
+func ActivePath(nodes []Node) []PathItem {
+	children := map[string][]Node{}
+	for _, node := range nodes {
+		parent := ""
+		if node.ParentMessageId != nil {
+			parent = *node.ParentMessageId
+		}
+		children[parent] = append(children[parent], node)
+	}
+
+	path := []PathItem{}
+	parent := ""
+	seen := map[string]bool{}
+	for {
+		siblings := children[parent]
+		if len(siblings) == 0 {
+			return path
+		}
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].CreatedAt.Before(siblings[j].CreatedAt)
+		})
+
+		selected := len(siblings) - 1
+		for i := len(siblings) - 1; i >= 0; i-- {
+			if siblings[i].IsActive {
+				selected = i
+				break
+			}
+		}
+
+		node := siblings[selected]
+		if seen[node.MessageId] {
+			return path
+		}
+		seen[node.MessageId] = true
+
+		path = append(path, PathItem{
+			Node:         node,
+			SiblingCount: len(siblings),
+			SiblingIndex: selected + 1,
+		})
+		parent = node.MessageId
+	}
+}
+
+// Tip returns the last message of the active path, new questions are attached to it
+func Tip(nodes []Node) *Node {
+	path := ActivePath(nodes)
+	if len(path) == 0 {
+		return nil
+	}
+	return &path[len(path)-1].Node
+}
+
+// Find returns the node with messageId or nil
+func Find(nodes []Node, messageId string) *Node {
+	for i := range nodes {
+		if nodes[i].MessageId == messageId {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package branch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func node(id, parent string, active bool, minute int) Node {
+	n := Node{MessageId: id, IsActive: active, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	if parent != "" {
+		n.ParentMessageId = &parent
+	}
+	return n
+}
+
+// describe writes the path as id:index/count, so a test shows which sibling was picked at every step
+func describe(path []PathItem) string {
+	items := make([]string, len(path))
+	for i, item := range path {
+		items[i] = fmt.Sprintf("%s:%d/%d", item.MessageId, item.SiblingIndex, item.SiblingCount)
+	}
+	return strings.Join(items, " ")
+}
+
+func TestActivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []Node
+		want    string
+		wantTip string
+	}{
+		{
+			name:    "no messages",
+			nodes:   nil,
+			want:    "",
+			wantTip: "",
+		},
+		{
+			name: "linear chain",
+			nodes: []Node{
+				node("q1", "", true, 0),
+				node("a1", "q1", true, 1),
+				node("q2", "a1", true, 2),
+				node("a2", "q2", true, 3),
+			},
+			want:    "q1:1/1 a1:1/1 q2:1/1 a2:1/1",
+			wantTip: "a2",
+		},
+		{
+			name: "inactive sibling",
+			nodes: []Node{
+				node("q1", "", true, 0),
+				node("a1", "q1", true, 1),
+				node("a1b", "q1", false, 2),
+			},
+			want:    "q1:1/1 a1:1/2",
+			wantTip: "a1",
+		},
+		{
+			name: "fork with a later active branch",
+			nodes: []Node{
+				node("q1", "", true, 0),
+				node("a1", "q1", true, 1),
+				node("q2", "a1", false, 2),
+				node("a2", "q2", true, 3),
+				node("q2b", "a1", true, 4),
+				node("a2b", "q2b", true, 5),
+			},
+			want:    "q1:1/1 a1:1/1 q2b:2/2 a2b:1/1",
+			wantTip: "a2b",
+		},
+		{
+			name: "newest sibling when none is active",
+			nodes: []Node{
+				node("q1", "", true, 0),
+				node("a1c", "q1", false, 3),
+				node("a1", "q1", false, 1),
+				node("a1b", "q1", false, 2),
+			},
+			want:    "q1:1/1 a1c:3/3",
+			wantTip: "a1c",
+		},
+		{
+			name: "messages without a root",
+			nodes: []Node{
+				node("a1", "q1", true, 1),
+				node("q2", "a1", true, 2),
+			},
+			want:    "",
+			wantTip: "",
+		},
+		{
+			name: "message listed twice loops back",
+			nodes: []Node{
+				node("q1", "", true, 0),
+				node("a1", "q1", true, 1),
+				node("q1", "a1", true, 2),
+			},
+			want:    "q1:1/1 a1:1/1",
+			wantTip: "a1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describe(ActivePath(tt.nodes)); got != tt.want {
+				t.Fatalf("ActivePath() = %q, want %q", got, tt.want)
+			}
+
+			tip := Tip(tt.nodes)
+			if tt.wantTip == "" {
+				if tip != nil {
+					t.Fatalf("Tip() = %q, want nil", tip.MessageId)
+				}
+				return
+			}
+			if tip == nil || tip.MessageId != tt.wantTip {
+				t.Fatalf("Tip() = %v, want %q", tip, tt.wantTip)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package bulksession
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   BulkSessionService
+	validator *validator.Validate
+}
+
+func NewHandler(service BulkSessionService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+func (h *Handler) BulkSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req BulkSessionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserID = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.BulkSessionService(ctx, req)
+	if err != nil {
+		logger.Error("error while bulk session operation : " + err.Error())
+		switch {
+		case errors.Is(err, ErrInvalidTags):
+			c.JSON(http.StatusBadRequest, app.Response{
+				Code:    app.InvalidRequestErrorCode,
+				Message: app.InvalidRequestErrorMessage,
+			})
+		case errors.Is(err, ErrFolderNotFound):
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
@@ -0,0 +1,90 @@
+package bulksession
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/app/organise"
+)
+
+type Service struct {
+	storage BulkSessionStorage
+	audit   audit.AuditService
+}
+
+func NewService(storage BulkSessionStorage, auditService audit.AuditService) *Service {
+	return &Service{
+		storage: storage,
+		audit:   auditService,
+	}
+}
+
+func (s *Service) BulkSessionService(ctx context.Context, req BulkSessionRequest) (*BulkSessionResponse, error) {
+	req.SessionIDs = uniqueValues(req.SessionIDs)
+
+	if req.Action == ActionTag {
+		req.Tags = organise.NormalizeTags(req.Tags)
+		if len(req.Tags) == 0 {
+			return nil, ErrInvalidTags
+		}
+	}
+
+	if req.Action == ActionMove && req.FolderID != nil {
+		owned, err := s.storage.CheckFolderOwnerStorage(ctx, req.UserID, *req.FolderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check folder in storage error : %w", err)
+		}
+		if !owned {
+			return nil, ErrFolderNotFound
+		}
+	}
+
+	results, err := s.storage.BulkSessionStorage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run bulk %s in storage error : %w", req.Action, err)
+	}
+
+	resp := BulkSessionResponse{
+		Action:  req.Action,
+		Results: results,
+	}
+	changed := []string{}
+	for _, result := range results {
+		if result.Success {
+			resp.Succeeded++
+			changed = append(changed, result.SessionId)
+		} else {
+			resp.Failed++
+		}
+	}
+
+	if len(changed) > 0 {
+		s.audit.Record(ctx, audit.Event{
+			ActorId:    req.UserID,
+			Action:     audit.ActionSessionBulk,
+			TargetType: audit.TargetSession,
+			After: map[string]any{
+				"action":     req.Action,
+				"sessionIds": changed,
+				"folderId":   req.FolderID,
+				"tags":       req.Tags,
+			},
+		})
+	}
+
+	return &resp, nil
+}
+
+func uniqueValues(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,119 @@
+package bulksession
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/organise"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) CheckFolderOwnerStorage(ctx context.Context, userId, folderId string) (bool, error) {
+	return organise.FolderOwned(ctx, s.db, userId, folderId)
+}
+
+// BulkSessionStorage applies the action to every session in one transaction.
+// Sessions that do not exist or belong to another user are reported per item and do not abort the others,
+// any database error rolls back the whole batch
+func (s *Storage) BulkSessionStorage(ctx context.Context, req BulkSessionRequest) ([]BulkItemResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	results := []BulkItemResult{}
+	for _, sessionId := range req.SessionIDs {
+		var affected int64
+		switch req.Action {
+		case ActionDelete:
+			affected, err = execAffected(ctx, tx, `
+				UPDATE chat_sessions
+				SET deleted_at = $3
+				WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL`,
+				req.UserID, sessionId, now)
+		case ActionArchive:
+			affected, err = execAffected(ctx, tx, `
+				UPDATE chat_sessions
+				SET archived_at = $3
+				WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL AND archived_at IS NULL`,
+				req.UserID, sessionId, now)
+		case ActionUnarchive:
+			affected, err = execAffected(ctx, tx, `
+				UPDATE chat_sessions
+				SET archived_at = NULL
+				WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL AND archived_at IS NOT NULL`,
+				req.UserID, sessionId)
+		case ActionMove:
+			affected, err = execAffected(ctx, tx, `
+				UPDATE chat_sessions
+				SET folder_id = $3
+				WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL`,
+				req.UserID, sessionId, req.FolderID)
+		case ActionTag:
+			affected, err = tagSession(ctx, tx, req.UserID, sessionId, req.Tags, now)
+		default:
+			return nil, fmt.Errorf("unsupported bulk action: %s", req.Action)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error when %s session %s: %v", req.Action, sessionId, err)
+		}
+
+		result := BulkItemResult{
+			SessionId: sessionId,
+			Success:   affected > 0,
+		}
+		if !result.Success {
+			result.Error = ItemErrorNotFound
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error when commit transaction: %v", err)
+	}
+
+	return results, nil
+}
+
+func execAffected(ctx context.Context, tx pgx.Tx, query string, args ...any) (int64, error) {
+	cmdTag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// tagSession adds tags to a session owned by the user, it returns 0 when the session is not found
+func tagSession(ctx context.Context, tx pgx.Tx, userId, sessionId string, tags []string, now time.Time) (int64, error) {
+	owned, err := execAffected(ctx, tx, `
+		SELECT 1 FROM chat_sessions
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`,
+		userId, sessionId)
+	if err != nil || owned == 0 {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_tags (session_id, tag, created_at)
+		SELECT $1, UNNEST($2::text[]), $3
+		ON CONFLICT (session_id, tag) DO NOTHING`,
+		sessionId, tags, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return owned, nil
+}
@@ -0,0 +1,51 @@
+package bulksession
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ActionDelete    = "delete"
+	ActionArchive   = "archive"
+	ActionUnarchive = "unarchive"
+	ActionMove      = "move"
+	ActionTag       = "tag"
+
+	ItemErrorNotFound = "not_found"
+)
+
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrInvalidTags    = errors.New("tags are required for tag action")
+)
+
+type BulkSessionService interface {
+	BulkSessionService(ctx context.Context, req BulkSessionRequest) (*BulkSessionResponse, error)
+}
+
+type BulkSessionStorage interface {
+	CheckFolderOwnerStorage(ctx context.Context, userId, folderId string) (bool, error)
+	BulkSessionStorage(ctx context.Context, req BulkSessionRequest) ([]BulkItemResult, error)
+}
+
+type BulkSessionRequest struct {
+	UserID     string   `json:"userId" validate:"required"`
+	SessionIDs []string `json:"sessionIds" validate:"required,min=1,max=500,dive,uuid4"`
+	Action     string   `json:"action" validate:"required,oneof=delete archive unarchive move tag"`
+	FolderID   *string  `json:"folderId" validate:"omitempty,uuid4"`
+	Tags       []string `json:"tags" validate:"required_if=Action tag,max=20,dive,required,max=50"`
+}
+
+type BulkSessionResponse struct {
+	Action    string           `json:"action"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+type BulkItemResult struct {
+	SessionId string `json:"sessionId"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
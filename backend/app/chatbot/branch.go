@@ -0,0 +1,114 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/branch"
+	"github.com/google/uuid"
+)
+
+// branchPlan describes where the question and the new answer are attached in the conversation tree
+type branchPlan struct {
+	UserMessageId       string
+	UserParentMessageId *string
+	NewUserMessage      bool
+	Content             string
+	UserPromptTokens    int
+}
+
+// resolveBranch works out the plan for a normal question, an edited question or a regenerated answer
+func (s *MessageService) resolveBranch(ctx context.Context, req ChatbotProcessRequest) (*branchPlan, app.Response, error) {
+	if req.RegenerateMessageId != "" {
+		userMessage, err := s.storage.GetUserMessage(ctx, req.UserId, req.SessionId, req.RegenerateMessageId)
+		if err != nil {
+			return nil, app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			}, fmt.Errorf("error when get user message : %w", err)
+		}
+		if userMessage == nil {
+			return nil, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			}, fmt.Errorf("user message %s not found", req.RegenerateMessageId)
+		}
+		return &branchPlan{
+			UserMessageId: userMessage.MessageId,
+			Content:       userMessage.Content,
+		}, app.Response{}, nil
+	}
+
+	plan := branchPlan{
+		UserMessageId:  uuid.NewString(),
+		NewUserMessage: true,
+		Content:        req.Input.Messages.Content,
+	}
+
+	if req.EditMessageId != "" {
+		userMessage, err := s.storage.GetUserMessage(ctx, req.UserId, req.SessionId, req.EditMessageId)
+		if err != nil {
+			return nil, app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			}, fmt.Errorf("error when get user message : %w", err)
+		}
+		if userMessage == nil {
+			return nil, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			}, fmt.Errorf("user message %s not found", req.EditMessageId)
+		}
+		// the edited question is a sibling of the original one
+		plan.UserParentMessageId = userMessage.ParentMessageId
+		return &plan, app.Response{}, nil
+	}
+
+	nodes, err := s.storage.GetMessageNodes(ctx, req.SessionId)
+	if err != nil {
+		return nil, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, fmt.Errorf("error when get message nodes : %w", err)
+	}
+	if tip := branch.Tip(nodes); tip != nil {
+		if tip.Role == "model" {
+			plan.UserParentMessageId = &tip.MessageId
+		} else {
+			plan.UserParentMessageId = tip.ParentMessageId
+		}
+	}
+
+	return &plan, app.Response{}, nil
+}
+
+// saveBranch stores the question (unless it is regenerated) and the answer attached to it
+func (s *MessageService) saveBranch(ctx context.Context, req ChatbotProcessRequest, plan *branchPlan, modelMessageId string, modelDetail ModelMessageDetail) (app.Response, error) {
+	if plan.NewUserMessage {
+		err := s.storage.SaveUserMessage(ctx, req.UserId, req.SessionId, UserMessageDetail{
+			MessageId:            plan.UserMessageId,
+			ParentMessageId:      plan.UserParentMessageId,
+			Content:              plan.Content,
+			UserPromptTokens:     plan.UserPromptTokens,
+			ModelAnswerMessageId: modelMessageId,
+		})
+		if err != nil {
+			return app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			}, fmt.Errorf("error when save user message at session : %w", err)
+		}
+	}
+
+	modelDetail.ParentMessageId = &plan.UserMessageId
+	err := s.storage.SaveModelMessage(ctx, req.UserId, req.SessionId, modelMessageId, modelDetail)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, fmt.Errorf("error when save model message at session : %w", err)
+	}
+
+	return app.Response{}, nil
+}
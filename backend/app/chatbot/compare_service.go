@@ -0,0 +1,190 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/google/uuid"
+)
+
+// ChatbotCompareWithStream sends one question to several model types concurrently, every answer is streamed
+// under its own stream ID and stored as a sibling answer of the same user message
+func (s *MessageService) ChatbotCompareWithStream(ctx context.Context, req CompareProcessRequest, onChunk CompareStreamCallback) (app.Response, error) {
+	logger := logging.FromContext(ctx)
+
+	baseReq := ChatbotProcessRequest{
+		UserId:    req.UserId,
+		SessionId: req.SessionId,
+		Input:     req.Input,
+	}
+
+	plan, errResp, err := s.resolveBranch(ctx, baseReq)
+	if err != nil {
+		return errResp, err
+	}
+
+	errResp, err = s.moderatePrompt(ctx, baseReq, plan.Content, nil)
+	if err != nil {
+		return errResp, err
+	}
+
+	redaction, err := s.redactPrompt(ctx, req.UserId, plan)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, err
+	}
+	baseReq.Input.Messages.Content = redaction.Prompt
+
+	plan.UserPromptTokens, err = s.quotaService.CheckPromptLength(plan.Content)
+	if err != nil {
+		return app.Response{
+			Code:    app.UserPromptLengthExceededErrorCode,
+			Message: app.UserPromptLengthExceededErrorMessage,
+		}, fmt.Errorf("prompt length exceeded: %w", err)
+	}
+
+	quotaStatus, err := s.quotaService.CheckQuota(ctx, req.UserId)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, fmt.Errorf("failed to check quota: %w", err)
+	}
+
+	if quotaStatus.IsExceeded {
+		return app.Response{
+			Code:    app.QuotaExceededErrorCode,
+			Message: app.QuotaExceededErrorMessage,
+		}, fmt.Errorf("daily quota exceeded")
+	}
+
+	err = s.storage.UpdateLastMessageAt(ctx, req.UserId, req.SessionId)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, fmt.Errorf("error updating last message: %w", err)
+	}
+
+	type streamResult struct {
+		answer    CompareAnswer
+		detail    ModelMessageDetail
+		cancelled bool
+		err       error
+	}
+
+	results := make([]streamResult, len(req.ModelTypes))
+	var wg sync.WaitGroup
+	for i, modelType := range req.ModelTypes {
+		streamId := uuid.NewString()
+		results[i].answer = CompareAnswer{
+			StreamID:  streamId,
+			ModelType: modelType,
+		}
+
+		wg.Add(1)
+		go func(i int, modelType, streamId string) {
+			defer wg.Done()
+
+			streamReq := baseReq
+			streamReq.ModelType = modelType
+			var streamCallback StreamCallback
+			if onChunk != nil {
+				streamCallback = func(event StreamEvent) {
+					onChunk(streamId, modelType, event)
+				}
+			}
+			streamCallback, flush := redaction.Stream(streamCallback)
+			detail, cancelled, err := s.streamModelAnswer(ctx, streamReq, streamId, streamCallback)
+			flush()
+			if ctx.Err() != nil {
+				// every stream uses its own FastAPI session so each one has to be cancelled
+				go s.CancelModelRequest(streamId)
+			}
+			results[i].detail = detail
+			results[i].cancelled = cancelled
+			results[i].err = err
+		}(i, modelType, streamId)
+	}
+	wg.Wait()
+
+	if ctx.Err() == context.Canceled {
+		return app.Response{}, fmt.Errorf("cancelled")
+	}
+
+	compareGroupId := uuid.NewString()
+	resp := CompareMessageResponse{
+		UserMessageID:  plan.UserMessageId,
+		CompareGroupID: compareGroupId,
+		Answers:        []CompareAnswer{},
+	}
+
+	var totalUsedTokens, saved int
+	var streamErr error
+	var titleAnswer string
+	for _, result := range results {
+		answer := result.answer
+		if result.cancelled || result.err != nil {
+			logger.Error("compare stream failed", "modelType", answer.ModelType, "streamId", answer.StreamID, "error", result.err)
+			answer.Error = modelErrorResponse(result.err).Message
+			if streamErr == nil && result.err != nil {
+				streamErr = result.err
+			}
+			resp.Answers = append(resp.Answers, answer)
+			continue
+		}
+
+		if titleAnswer == "" {
+			titleAnswer = result.detail.Content
+		}
+		answerText, storedAnswer := redaction.Answer(result.detail.Content)
+		result.detail.Content = storedAnswer
+
+		modelMessageId := uuid.NewString()
+		result.detail.CompareGroupId = &compareGroupId
+		errResp, err := s.saveBranch(ctx, baseReq, plan, modelMessageId, result.detail)
+		if err != nil {
+			return errResp, err
+		}
+		// the question is stored once, the following answers only attach to it
+		plan.NewUserMessage = false
+		saved++
+
+		answer.ModelMessageID = modelMessageId
+		answer.Message = answerText
+		resp.Answers = append(resp.Answers, answer)
+		totalUsedTokens += result.detail.TotalUsedTokens
+	}
+
+	if saved == 0 {
+		if streamErr == nil {
+			streamErr = fmt.Errorf("every stream was cancelled")
+		}
+		return modelErrorResponse(streamErr), fmt.Errorf("all compare streams failed: %w", streamErr)
+	}
+
+	err = s.quotaService.ConsumeTokens(ctx, req.UserId, int64(totalUsedTokens))
+	if err != nil {
+		logger.Warn("failed to consume tokens", "error", err)
+	}
+
+	// the title is generated from the first saved answer, the title event belongs to no stream
+	var titleCallback StreamCallback
+	if onChunk != nil {
+		titleCallback = func(event StreamEvent) {
+			onChunk("", "", event)
+		}
+	}
+	go s.generateSessionTitle(ctx, baseReq, titleAnswer, titleCallback)
+
+	return app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	}, nil
+}
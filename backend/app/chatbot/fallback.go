@@ -0,0 +1,144 @@
+package chatbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/modelclient"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
+	"github.com/PatiharnKam/AiLaw/config"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// pipeline is one way of getting an answer: a model type through its streaming or its sync endpoint
+type pipeline struct {
+	ModelType string
+	Stream    bool
+}
+
+func (p pipeline) String() string {
+	if p.Stream {
+		return p.ModelType + ":stream"
+	}
+	return p.ModelType + ":sync"
+}
+
+// parseFallbackChains reads the configured chains, every step is written <model type>:<stream|sync>
+func parseFallbackChains(cfg *config.ModelFallback) map[string][]pipeline {
+	chains := map[string][]pipeline{}
+	if !cfg.Enabled {
+		return chains
+	}
+
+	for modelType, steps := range map[string][]string{"default": cfg.Default, "COT": cfg.COT} {
+		for _, step := range steps {
+			stepModelType, mode, _ := strings.Cut(strings.TrimSpace(step), ":")
+			if (stepModelType != "default" && stepModelType != "COT") || (mode != "stream" && mode != "sync") {
+				slog.Warn("ignoring invalid model fallback step", "modelType", modelType, "step", step)
+				continue
+			}
+			chains[modelType] = append(chains[modelType], pipeline{
+				ModelType: stepModelType,
+				Stream:    mode == "stream",
+			})
+		}
+	}
+	return chains
+}
+
+// fallbackChain returns the pipelines tried in order for modelType, it always starts with the requested one.
+// Sync callers only take the model types of the chain since they cannot forward a stream
+func (s *MessageService) fallbackChain(modelType string, stream bool) []pipeline {
+	chain := []pipeline{{ModelType: modelType, Stream: stream}}
+	for _, step := range s.fallbackChains[modelType] {
+		if !stream {
+			step.Stream = false
+		}
+		if !slices.Contains(chain, step) {
+			chain = append(chain, step)
+		}
+	}
+	return chain
+}
+
+// shouldFallback is true when the pipeline is down or too slow, other errors would happen on any pipeline
+func shouldFallback(err error) bool {
+	return errors.Is(err, modelclient.ErrUnavailable) || errors.Is(err, modelclient.ErrTimeout)
+}
+
+// generateAnswer answers req through the fallback chain of req.ModelType. The next pipeline is only tried
+// while nothing of the answer has been sent yet, the returned detail carries the model type actually used
+func (s *MessageService) generateAnswer(ctx context.Context, req ChatbotProcessRequest, streamId string, stream bool, onChunk StreamCallback) (ModelMessageDetail, bool, error) {
+	logger := logging.FromContext(ctx)
+
+	chain := s.fallbackChain(req.ModelType, stream)
+
+	var detail ModelMessageDetail
+	var cancelled bool
+	var err error
+	for i, step := range chain {
+		if i > 0 {
+			logger.Warn("model pipeline failed, falling back", "from", chain[i-1].String(), "to", step.String(), "sessionId", req.SessionId, "error", err)
+			if onChunk != nil {
+				message := fmt.Sprintf("%s model is unavailable, answering with the %s model", chain[i-1].ModelType, step.ModelType)
+				if chain[i-1].ModelType == step.ModelType {
+					message = fmt.Sprintf("%s model stream is unavailable, answering without streaming", step.ModelType)
+				}
+				onChunk(StreamEvent{
+					Type:      "status",
+					Message:   message,
+					ModelType: step.ModelType,
+				})
+			}
+		}
+
+		stepReq := req
+		stepReq.ModelType = step.ModelType
+		if step.Stream {
+			detail, cancelled, err = s.streamModelAnswer(ctx, stepReq, streamId, onChunk)
+		} else {
+			detail, err = s.syncModelAnswer(ctx, stepReq, onChunk)
+		}
+
+		if err == nil || cancelled || ctx.Err() != nil || detail.Content != "" || !shouldFallback(err) {
+			break
+		}
+	}
+	return detail, cancelled, err
+}
+
+// syncModelAnswer answers req through the non-streaming endpoint, the whole answer is sent to onChunk at once
+func (s *MessageService) syncModelAnswer(ctx context.Context, req ChatbotProcessRequest, onChunk StreamCallback) (ModelMessageDetail, error) {
+	detail := ModelMessageDetail{
+		ModelType: req.ModelType,
+	}
+
+	ctx, span := tracing.Start(ctx, "model.sync", attribute.String("model.type", req.ModelType))
+	resp, responseTime, err := s.callChatbot(ctx, req)
+	tracing.End(span, err)
+	if err != nil {
+		return detail, err
+	}
+
+	detail.Content = resp.Content
+	detail.TotalInputTokens = resp.TotalInputTokens
+	detail.TotalOutputTokens = resp.TotalOutputTokens
+	detail.FinalOutputTokens = resp.FinalOutputTokens
+	detail.TotalUsedTokens = resp.TotalUsedTokens
+	detail.ResponseTime = responseTime
+	if responseTime != nil {
+		metrics.ObserveModelAnswer(req.ModelType, "sync", *responseTime,
+			detail.TotalInputTokens, detail.TotalOutputTokens, detail.TotalUsedTokens)
+	}
+
+	if onChunk != nil {
+		onChunk(StreamEvent{Type: "content", Text: resp.Content})
+	}
+	return detail, nil
+}
@@ -1,10 +1,14 @@
 package chatbot
 
 import (
-	"log/slog"
+	"context"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/ratelimit"
+	"github.com/PatiharnKam/AiLaw/app/semaphore"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -13,19 +17,23 @@ import (
 type Handler struct {
 	service   Service
 	cfg       *config.Config
+	limiter   *ratelimit.Limiter
+	semaphore *semaphore.Semaphore
 	validator *validator.Validate
 }
 
-func NewHandler(service Service, cfg *config.Config) *Handler {
+func NewHandler(service Service, cfg *config.Config, limiter *ratelimit.Limiter, semaphore *semaphore.Semaphore) *Handler {
 	return &Handler{
 		service:   service,
 		cfg: cfg,
+		limiter:   limiter,
+		semaphore: semaphore,
 		validator: validator.New(),
 	}
 }
 
 func (h *Handler) CreateChatSessionHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req CreateChatSessionRequest
 
 	req.UserId = c.GetString("userId")
@@ -67,7 +75,7 @@ func (h *Handler) CreateChatSessionHandler(c *gin.Context) {
 }
 
 func (h *Handler) ChatbotProcessModelHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req ChatbotProcessRequest
 
 	req.UserId = c.GetString("userId")
@@ -90,7 +98,31 @@ func (h *Handler) ChatbotProcessModelHandler(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
+	if req.RegenerateMessageId == "" && req.Input.Messages.Content == "" {
+		logger.Error("invalid request body : content is required")
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := logging.With(c.Request.Context(), "sessionId", req.SessionId)
+	logger = logging.FromContext(ctx)
+	lease, err := h.semaphore.TryAcquire(ctx, "generation:user:"+req.UserId, h.cfg.Concurrency.MaxGenerationsPerUser, h.cfg.Concurrency.LeaseTTL)
+	if err != nil {
+		logger.Error("failed to acquire generation slot : " + err.Error())
+	} else if lease == nil {
+		logger.Warn("too many generations in progress", "userId", req.UserId)
+		c.JSON(http.StatusTooManyRequests, app.Response{
+			Code:    app.ConcurrencyLimitErrorCode,
+			Message: app.ConcurrencyLimitErrorMessage,
+		})
+		return
+	}
+	defer lease.Release(context.Background())
+	defer metrics.GenerationStarted("rest")()
+
 	resp, err := h.service.ChatbotProcess(ctx, req)
 	if err != nil {
 		logger.Error("error from service layer : " + err.Error())
@@ -103,6 +135,22 @@ func (h *Handler) ChatbotProcessModelHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, resp)
 			return
 
+		case app.PromptRejectedErrorCode:
+			c.JSON(http.StatusBadRequest, resp)
+			return
+
+		case app.NotFoundErrorCode:
+			c.JSON(http.StatusNotFound, resp)
+			return
+
+		case app.ModelBusyErrorCode, app.ModelUnavailableErrorCode:
+			c.JSON(http.StatusServiceUnavailable, resp)
+			return
+
+		case app.ModelTimeoutErrorCode:
+			c.JSON(http.StatusGatewayTimeout, resp)
+			return
+
 		default:
 			c.JSON(http.StatusInternalServerError, app.Response{
 				Code:    app.InternalServerErrorCode,
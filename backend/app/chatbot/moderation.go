@@ -0,0 +1,60 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/moderation"
+)
+
+const moderationWarningMessage = "คำถามนี้อาจไม่เหมาะสมหรืออยู่นอกขอบเขตด้านกฎหมาย ระบบจะพยายามตอบเท่าที่ทำได้"
+
+// moderatePrompt is the pre-flight stage run before quota checks and the model call,
+// a blocked prompt is rejected and a warned one is answered with a warning event sent to onChunk
+func (s *MessageService) moderatePrompt(ctx context.Context, req ChatbotProcessRequest, content string, onChunk StreamCallback) (app.Response, error) {
+	if s.moderationService == nil {
+		return app.Response{}, nil
+	}
+
+	result, err := s.moderationService.CheckPrompt(ctx, moderation.CheckRequest{
+		UserId:    req.UserId,
+		SessionId: req.SessionId,
+		Content:   content,
+	})
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, fmt.Errorf("error when moderate prompt : %w", err)
+	}
+
+	if result.Action == moderation.ActionAllow {
+		return app.Response{}, nil
+	}
+
+	rules := make([]string, 0, len(result.Violations))
+	for _, violation := range result.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	logging.FromContext(ctx).Warn("prompt matched moderation rules", "userId", req.UserId, "sessionId", req.SessionId, "action", result.Action, "rules", strings.Join(rules, ","))
+
+	switch result.Action {
+	case moderation.ActionBlock:
+		return app.Response{
+			Code:    app.PromptRejectedErrorCode,
+			Message: app.PromptRejectedErrorMessage,
+		}, fmt.Errorf("prompt blocked by moderation rules: %s", strings.Join(rules, ","))
+	case moderation.ActionWarn:
+		if onChunk != nil {
+			onChunk(StreamEvent{
+				Type:    "moderation_warning",
+				Message: moderationWarningMessage,
+			})
+		}
+	}
+
+	return app.Response{}, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/branch"
+	"github.com/PatiharnKam/AiLaw/app/pii"
 )
 
 type Service interface {
@@ -12,14 +14,21 @@ type Service interface {
 	ChatbotProcess(ctx context.Context, req ChatbotProcessRequest) (app.Response, error)
 	// ChatbotProcessWithStream(ctx context.Context, req ChatbotProcessRequest, onChunk StreamCallback) (*StreamingMessageResponse, error)
 	ChatbotProcessWithStream(ctx context.Context, req ChatbotProcessRequest, onChunk StreamCallback) (app.Response, error)
+	ChatbotCompareWithStream(ctx context.Context, req CompareProcessRequest, onChunk CompareStreamCallback) (app.Response, error)
 	CancelModelRequest(sessionID string)
 }
 
 type Storage interface {
 	CreateSession(ctx context.Context, req CreateChatSessionRequest) (*CreateChatSessionResponse, error)
 	UpdateLastMessageAt(ctx context.Context, userId string, sessionId string) error
-	SaveUserMessage(ctx context.Context, userId, sessionId, modelMessageId, userMessage string, userPromptTokens int) error
+	SaveUserMessage(ctx context.Context, userId, sessionId string, userDetail UserMessageDetail) error
 	SaveModelMessage(ctx context.Context, userId, sessionId, modelMessageId string, modelDetail ModelMessageDetail) error
+	GetMessageNodes(ctx context.Context, sessionId string) ([]branch.Node, error)
+	GetUserMessage(ctx context.Context, userId, sessionId, messageId string) (*UserMessageDetail, error)
+	ClaimTitleGeneration(ctx context.Context, userId, sessionId string) (bool, error)
+	UpdateGeneratedTitle(ctx context.Context, userId, sessionId, title string) (bool, error)
+	GetPIIPolicy(ctx context.Context, userId string) (*pii.Policy, error)
+	GetUserPlan(ctx context.Context, userId string) (string, error)
 }
 
 type CreateChatSessionRequest struct {
@@ -31,7 +40,17 @@ type CreateChatSessionResponse struct {
 	SessionId string `json:"sessionId"`
 }
 
+type UserMessageDetail struct {
+	MessageId            string  `json:"messageId"`
+	ParentMessageId      *string `json:"parentMessageId"`
+	Content              string  `json:"content"`
+	UserPromptTokens     int     `json:"userPromptTokens"`
+	ModelAnswerMessageId string  `json:"modelAnswerMessageId"`
+}
+
 type ModelMessageDetail struct {
+	ParentMessageId   *string `json:"parentMessageId"`
+	CompareGroupId    *string `json:"compareGroupId"`
 	ModelType         string  `json:"modelType"`
 	Content           string  `json:"message"`
 	Feedback          *string `json:"feedback"`
@@ -45,6 +64,7 @@ type ModelMessageDetail struct {
 type GetMessageResponse struct {
 	Message        string `json:"message"`
 	ModelMessageID string `json:"modelMessageID"`
+	UserMessageID  string `json:"userMessageID"`
 }
 
 type ChatbotProcessRequest struct {
@@ -52,6 +72,34 @@ type ChatbotProcessRequest struct {
 	SessionId string `json:"sessionId" binding:"required" validate:"required,uuid"`
 	ModelType string `json:"modelType" binding:"required"`
 	Input     Input  `json:"input"`
+
+	// RegenerateMessageId answers an existing user message again, EditMessageId sends Input as an edited version of it
+	RegenerateMessageId string `json:"regenerateMessageId,omitempty" validate:"omitempty,uuid"`
+	EditMessageId       string `json:"editMessageId,omitempty" validate:"omitempty,uuid,excluded_with=RegenerateMessageId"`
+
+	// GenerationId identifies the model stream so it can be cancelled on its own, the session ID is used when empty
+	GenerationId string `json:"-"`
+}
+
+type CompareProcessRequest struct {
+	UserId     string   `json:"userId" validate:"required,uuid"`
+	SessionId  string   `json:"sessionId" validate:"required,uuid"`
+	ModelTypes []string `json:"modelTypes" validate:"min=2,unique,dive,oneof=default COT"`
+	Input      Input    `json:"input"`
+}
+
+type CompareMessageResponse struct {
+	UserMessageID  string          `json:"userMessageId"`
+	CompareGroupID string          `json:"compareGroupId"`
+	Answers        []CompareAnswer `json:"answers"`
+}
+
+type CompareAnswer struct {
+	StreamID       string `json:"streamId"`
+	ModelType      string `json:"modelType"`
+	ModelMessageID string `json:"modelMessageId,omitempty"`
+	Message        string `json:"message,omitempty"`
+	Error          string `json:"error,omitempty"`
 }
 
 type Input struct {
@@ -60,7 +108,7 @@ type Input struct {
 
 type Messages struct {
 	Role    string `json:"role"`
-	Content string `json:"content" binding:"required"`
+	Content string `json:"content"`
 }
 
 type ChatbotRequest struct {
@@ -83,6 +131,10 @@ type Memory struct {
 	Agent    string `json:"agent"`
 	Sections string `json:"sections"`
 }
+
+type TitleResponse struct {
+	Title string `json:"title"`
+}
 //========================== Web Socker ========================================
 
 // WebSocket Message Types
@@ -91,6 +143,11 @@ type WSMessage struct {
 	SessionID string `json:"sessionId,omitempty"`
 	Content   string `json:"content,omitempty"`
 	ModelType string `json:"modelType,omitempty"` // "default" or "COT"
+	MessageID string `json:"messageId,omitempty"` // user message for "regenerate" and "edit"
+
+	ModelTypes []string `json:"modelTypes,omitempty"` // model types streamed side by side, turns a "chat" message into compare mode
+
+	GenerationID string `json:"generationId,omitempty"` // chosen by the client or assigned by the server, used by "cancel"
 }
 
 type WSResponse struct {
@@ -98,6 +155,14 @@ type WSResponse struct {
 	Content        string `json:"content,omitempty"`
 	SessionID      string `json:"sessionId,omitempty"`
 	ModelMessageID string `json:"modelMessageId,omitempty"`
+	UserMessageID  string `json:"userMessageId,omitempty"`
+	Title          string `json:"title,omitempty"`
+	GenerationID   string `json:"generationId,omitempty"`
+
+	// Compare specific fields
+	StreamID       string `json:"streamId,omitempty"`
+	ModelType      string `json:"modelType,omitempty"`
+	CompareGroupID string `json:"compareGroupId,omitempty"`
 
 	// COT specific fields
 	Steps       []string `json:"steps,omitempty"`
@@ -107,6 +172,9 @@ type WSResponse struct {
 	StepDesc    string   `json:"stepDescription,omitempty"`
 	Status      string   `json:"status,omitempty"`
 
+	// Model queue specific fields
+	QueuePosition int `json:"queuePosition,omitempty"`
+
 	// Error fields
 	Error *WSError `json:"error,omitempty"`
 }
@@ -119,6 +187,9 @@ type WSError struct {
 // StreamCallback is called for each chunk
 type StreamCallback func(chunk StreamEvent)
 
+// CompareStreamCallback is called for each chunk of every stream in compare mode
+type CompareStreamCallback func(streamId, modelType string, chunk StreamEvent)
+
 // StreamEvent represents different event types from SSE
 type StreamEvent struct {
 	Type string `json:"type"`
@@ -127,7 +198,9 @@ type StreamEvent struct {
 	Text string `json:"text,omitempty"`
 
 	// For status updates
-	Message string `json:"message,omitempty"`
+	Message       string `json:"message,omitempty"`
+	QueuePosition int    `json:"queuePosition,omitempty"`
+	ModelType     string `json:"modelType,omitempty"`
 
 	// For COT plan
 	Steps     []string `json:"steps,omitempty"`
@@ -147,10 +220,15 @@ type StreamEvent struct {
 
 	// For errors
 	Error string `json:"error,omitempty"`
+
+	// For generated session title
+	Title string `json:"title,omitempty"`
 }
 
 // StreamingMessageResponse for streaming completion
 type StreamingMessageResponse struct {
 	Message        string `json:"message"`
 	ModelMessageID string `json:"modelMessageId"`
+	UserMessageID  string `json:"userMessageId"`
+	ModelType      string `json:"modelType"`
 }
@@ -0,0 +1,73 @@
+package chatbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/modelclient"
+	"github.com/PatiharnKam/AiLaw/app/modelqueue"
+)
+
+// waitForModel takes a turn in the model queue with the priority of the user's plan,
+// while waiting the position in line is sent to onChunk as status events
+func (s *MessageService) waitForModel(ctx context.Context, userId string, onChunk StreamCallback) (func(), error) {
+	if s.queue == nil {
+		return func() {}, nil
+	}
+
+	var tier string
+	if userId != "" && s.storage != nil {
+		plan, err := s.storage.GetUserPlan(ctx, userId)
+		if err != nil {
+			// the user still gets an answer, only with the lowest priority
+			logging.FromContext(ctx).Warn("failed to get user plan", "userId", userId, "error", err)
+		}
+		tier = plan
+	}
+
+	var onPosition func(position int)
+	if onChunk != nil {
+		onPosition = func(position int) {
+			onChunk(StreamEvent{
+				Type:          "status",
+				Message:       fmt.Sprintf("waiting for the model, %d in queue", position),
+				QueuePosition: position,
+			})
+		}
+	}
+
+	release, err := s.queue.Acquire(ctx, userId, tier, onPosition)
+	if err != nil {
+		return nil, fmt.Errorf("error when waiting for model queue : %w", err)
+	}
+	return release, nil
+}
+
+// modelErrorResponse tells the user whether the model queue turned the call away, the model service
+// is down or it did not answer in time, any other failure is an internal error
+func modelErrorResponse(err error) app.Response {
+	switch {
+	case errors.Is(err, modelqueue.ErrQueueFull), errors.Is(err, modelqueue.ErrWaitTimeout):
+		return app.Response{
+			Code:    app.ModelBusyErrorCode,
+			Message: app.ModelBusyErrorMessage,
+		}
+	case errors.Is(err, modelclient.ErrUnavailable):
+		return app.Response{
+			Code:    app.ModelUnavailableErrorCode,
+			Message: app.ModelUnavailableErrorMessage,
+		}
+	case errors.Is(err, modelclient.ErrTimeout):
+		return app.Response{
+			Code:    app.ModelTimeoutErrorCode,
+			Message: app.ModelTimeoutErrorMessage,
+		}
+	}
+	return app.Response{
+		Code:    app.InternalServerErrorCode,
+		Message: app.InternalServerErrorMessage,
+	}
+}
@@ -0,0 +1,95 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/pii"
+)
+
+// redaction is the outcome of running a question through the PII pipeline
+type redaction struct {
+	policy pii.Policy
+	vault  *pii.Vault
+	// Prompt is the question sent to the model
+	Prompt string
+}
+
+// piiPolicy returns the policy of the user's organization, or the configured default
+func (s *MessageService) piiPolicy(ctx context.Context, userId string) (pii.Policy, error) {
+	policy, err := s.storage.GetPIIPolicy(ctx, userId)
+	if err != nil {
+		return pii.Policy{}, err
+	}
+	if policy != nil {
+		return *policy, nil
+	}
+	return pii.Policy{
+		Enabled:         s.cfg.PII.Enabled,
+		Entities:        s.cfg.PII.Entities,
+		RestoreResponse: s.cfg.PII.RestoreResponse,
+		StoreMode:       s.cfg.PII.StoreMode,
+	}, nil
+}
+
+// redactPrompt replaces personal data in the question before it leaves the backend,
+// plan.Content becomes the redacted question unless the policy stores originals
+func (s *MessageService) redactPrompt(ctx context.Context, userId string, plan *branchPlan) (*redaction, error) {
+	policy, err := s.piiPolicy(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("error when get pii policy : %w", err)
+	}
+
+	if !policy.Enabled {
+		return &redaction{policy: policy, vault: pii.NewVault(), Prompt: plan.Content}, nil
+	}
+
+	prompt, vault := pii.Redact(plan.Content, policy.Entities)
+	if vault.Len() > 0 {
+		logging.FromContext(ctx).Info("redacted personal data from prompt", "userId", userId, "count", vault.Len())
+	}
+	if policy.StoreMode != pii.StoreOriginal {
+		plan.Content = prompt
+	}
+
+	return &redaction{policy: policy, vault: vault, Prompt: prompt}, nil
+}
+
+// Answer returns the answer shown to the user and the answer stored in model_messages
+func (r *redaction) Answer(content string) (shown string, stored string) {
+	shown = content
+	if r.policy.RestoreResponse {
+		shown = r.vault.Restore(content)
+	}
+	stored = content
+	if r.policy.StoreMode == pii.StoreOriginal {
+		stored = shown
+	}
+	return shown, stored
+}
+
+// Stream wraps onChunk so placeholders in streamed content are restored when the policy asks for it,
+// flush has to be called once the stream ended to send the text held back
+func (r *redaction) Stream(onChunk StreamCallback) (wrapped StreamCallback, flush func()) {
+	if onChunk == nil || !r.policy.RestoreResponse || r.vault.Len() == 0 {
+		return onChunk, func() {}
+	}
+
+	restorer := pii.NewStreamRestorer(r.vault)
+	wrapped = func(event StreamEvent) {
+		if event.Type == "content" {
+			event.Text = restorer.Write(event.Text)
+			if event.Text == "" {
+				return
+			}
+		}
+		onChunk(event)
+	}
+	flush = func() {
+		if rest := restorer.Flush(); rest != "" {
+			onChunk(StreamEvent{Type: "content", Text: rest})
+		}
+	}
+	return wrapped, flush
+}
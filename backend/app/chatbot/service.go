@@ -6,29 +6,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/modelclient"
+	"github.com/PatiharnKam/AiLaw/app/modelqueue"
+	"github.com/PatiharnKam/AiLaw/app/moderation"
 	"github.com/PatiharnKam/AiLaw/app/quota"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/google/uuid"
 )
 
 type MessageService struct {
-	cfg          *config.Config
-	storage      Storage
-	quotaService quota.QuotaService
-	httpClient   *http.Client
+	cfg               *config.Config
+	storage           Storage
+	quotaService      quota.QuotaService
+	moderationService moderation.ModerationService
+	queue             *modelqueue.Queue
+	modelClient       *modelclient.Client
+	fallbackChains    map[string][]pipeline
 }
 
-func NewService(cfg *config.Config, storage Storage, quotaService quota.QuotaService) *MessageService {
+func NewService(cfg *config.Config, storage Storage, quotaService quota.QuotaService, moderationService moderation.ModerationService, queue *modelqueue.Queue) *MessageService {
 	return &MessageService{
-		cfg:          cfg,
-		storage:      storage,
-		quotaService: quotaService,
-		httpClient:   &http.Client{Timeout: 0},
+		cfg:               cfg,
+		storage:           storage,
+		quotaService:      quotaService,
+		moderationService: moderationService,
+		queue:             queue,
+		modelClient:       modelclient.New(&cfg.ModelClient),
+		fallbackChains:    parseFallbackChains(&cfg.ModelFallback),
 	}
 
 }
@@ -42,7 +51,26 @@ func (s *MessageService) CreateChatSessionService(ctx context.Context, req Creat
 }
 
 func (s *MessageService) ChatbotProcess(ctx context.Context, req ChatbotProcessRequest) (app.Response, error) {
-	userPromptTokens, err := s.quotaService.CheckPromptLength(req.Input.Messages.Content)
+	plan, errResp, err := s.resolveBranch(ctx, req)
+	if err != nil {
+		return errResp, err
+	}
+
+	errResp, err = s.moderatePrompt(ctx, req, plan.Content, nil)
+	if err != nil {
+		return errResp, err
+	}
+
+	redaction, err := s.redactPrompt(ctx, req.UserId, plan)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, err
+	}
+	req.Input.Messages.Content = redaction.Prompt
+
+	plan.UserPromptTokens, err = s.quotaService.CheckPromptLength(plan.Content)
 	if err != nil {
 		return app.Response{
 			Code:    app.UserPromptLengthExceededErrorCode,
@@ -73,56 +101,46 @@ func (s *MessageService) ChatbotProcess(ctx context.Context, req ChatbotProcessR
 		}, fmt.Errorf("error when update last message at session : %w", err)
 	}
 
-	resp, responseTime, err := s.callChatbot(ctx, req)
+	modelmessageDetail, _, err := s.generateAnswer(ctx, req, uuid.NewString(), false, nil)
 	if err != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("failed when call chatbot : %w", err)
+		return modelErrorResponse(err), fmt.Errorf("failed when call chatbot : %w", err)
 	}
 
+	modelAnswer := modelmessageDetail.Content
+	answer, storedAnswer := redaction.Answer(modelAnswer)
+	modelmessageDetail.Content = storedAnswer
+
 	modelMessageId := uuid.NewString()
-	err = s.storage.SaveUserMessage(ctx, req.UserId, req.SessionId, modelMessageId, req.Input.Messages.Content, userPromptTokens)
+	errResp, err = s.saveBranch(ctx, req, plan, modelMessageId, modelmessageDetail)
 	if err != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("error when save user message at session : %w", err)
+		return errResp, err
 	}
 
-	modelmessageDetail := ModelMessageDetail{
-		ModelType:         req.ModelType,
-		Content:           resp.Content,
-		TotalInputTokens:  resp.TotalInputTokens,
-		TotalOutputTokens: resp.TotalOutputTokens,
-		FinalOutputTokens: resp.FinalOutputTokens,
-		TotalUsedTokens:   resp.TotalUsedTokens,
-		ResponseTime:      responseTime,
-	}
-	err = s.storage.SaveModelMessage(ctx, req.UserId, req.SessionId, modelMessageId, modelmessageDetail)
+	err = s.quotaService.ConsumeTokens(ctx, req.UserId, int64(modelmessageDetail.TotalUsedTokens))
 	if err != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("error when save model message at session : %w", err)
+		logging.FromContext(ctx).Error("failed to consume tokens", "error", err)
 	}
 
-	err = s.quotaService.ConsumeTokens(ctx, req.UserId, int64(resp.TotalUsedTokens))
-	if err != nil {
-		slog.Error("failed to consume tokens", "error", err)
-	}
+	// the title model only sees the redacted conversation
+	go s.generateSessionTitle(ctx, req, modelAnswer, nil)
 
 	return app.Response{
 		Code:    app.SUCCESS_CODE,
 		Message: app.SUCCESS_MSG,
 		Data: GetMessageResponse{
-			Message:        modelmessageDetail.Content,
+			Message:        answer,
 			ModelMessageID: modelMessageId,
+			UserMessageID:  plan.UserMessageId,
 		},
 	}, nil
 }
 
 func (s *MessageService) callChatbot(ctx context.Context, req ChatbotProcessRequest) (*ChatbotResponse, *float64, error) {
+	release, err := s.waitForModel(ctx, req.UserId, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
 
 	data := ChatbotRequest{
 		Messages: []Messages{
@@ -151,7 +169,7 @@ func (s *MessageService) callChatbot(ctx context.Context, req ChatbotProcessRequ
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+s.cfg.Model.ModelAPIkey)
 
-	httpResp, err := s.httpClient.Do(httpReq)
+	httpResp, err := s.modelClient.Do(httpReq)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error whell calling API Model : %w", err)
 	}
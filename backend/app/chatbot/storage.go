@@ -2,22 +2,32 @@ package chatbot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/branch"
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/PatiharnKam/AiLaw/app/pii"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type storage struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	cipher encryption.Cipher
 }
 
-func NewStorage(db *pgxpool.Pool) *storage {
-	return &storage{db: db}
+func NewStorage(db *pgxpool.Pool, cipher encryption.Cipher) *storage {
+	return &storage{db: db, cipher: cipher}
 }
 
 func (s *storage) CreateSession(ctx context.Context, req CreateChatSessionRequest) (*CreateChatSessionResponse, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.CreateSession")
+	defer span.End()
+
 	query := `INSERT INTO chat_sessions
 				(session_id, user_id, title, created_at, last_message_at)
 				VALUES ($1, $2, $3, $4, $5)`
@@ -40,10 +50,13 @@ func (s *storage) CreateSession(ctx context.Context, req CreateChatSessionReques
 }
 
 func (s *storage) UpdateLastMessageAt(ctx context.Context, userId string, sessionId string) error {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.UpdateLastMessageAt")
+	defer span.End()
+
 	query := `
 		UPDATE chat_sessions
 		SET last_message_at = $3
-		WHERE user_id = $1 AND session_id = $2
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
 	`
 
 	now := time.Now()
@@ -60,36 +73,92 @@ func (s *storage) UpdateLastMessageAt(ctx context.Context, userId string, sessio
 	return nil
 }
 
-func (s *storage) SaveUserMessage(ctx context.Context, userId, sessionId, modelMessageId, userMessage string, userPromptTokens int) error {
+func (s *storage) SaveUserMessage(ctx context.Context, userId, sessionId string, userDetail UserMessageDetail) error {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.SaveUserMessage")
+	defer span.End()
+
+	content, keyId, err := s.cipher.Encrypt(ctx, userId, userDetail.Content)
+	if err != nil {
+		return fmt.Errorf("error when encrypt content: %v", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the new message becomes the active one among its siblings
+	_, err = tx.Exec(ctx, `
+		UPDATE user_messages
+		SET is_active = FALSE
+		WHERE session_id = $1 AND parent_message_id IS NOT DISTINCT FROM $2`,
+		sessionId, userDetail.ParentMessageId)
+	if err != nil {
+		return fmt.Errorf("error when deactivate siblings: %v", err)
+	}
+
 	query := `INSERT INTO user_messages 
-				(message_id, user_id, session_id ,content, created_at, user_prompt_tokens, model_answer_message_id)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := s.db.Exec(ctx, query,
-		uuid.NewString(),
+				(message_id, user_id, session_id ,content, created_at, user_prompt_tokens, model_answer_message_id,
+				parent_message_id, is_active, content_key_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE, $9)`
+	_, err = tx.Exec(ctx, query,
+		userDetail.MessageId,
 		userId,
 		sessionId,
-		userMessage,
+		content,
 		time.Now(),
-		userPromptTokens,
-		modelMessageId,
+		userDetail.UserPromptTokens,
+		userDetail.ModelAnswerMessageId,
+		userDetail.ParentMessageId,
+		keyId,
 	)
 	if err != nil {
 		return fmt.Errorf("error when insert data: %v", err)
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error when commit transaction: %v", err)
+	}
 	return nil
 }
 
 func (s *storage) SaveModelMessage(ctx context.Context, userId, sessionId, modelMessageId string, modelDetail ModelMessageDetail) error {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.SaveModelMessage")
+	defer span.End()
+
+	content, keyId, err := s.cipher.Encrypt(ctx, userId, modelDetail.Content)
+	if err != nil {
+		return fmt.Errorf("error when encrypt content: %v", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the new answer becomes the active one among its siblings
+	_, err = tx.Exec(ctx, `
+		UPDATE model_messages
+		SET is_active = FALSE
+		WHERE session_id = $1 AND parent_message_id IS NOT DISTINCT FROM $2`,
+		sessionId, modelDetail.ParentMessageId)
+	if err != nil {
+		return fmt.Errorf("error when deactivate siblings: %v", err)
+	}
+
 	query := `INSERT INTO model_messages 
 			(message_id, user_id, session_id, model_type ,content, created_at , feedback,
-			total_input_tokens, total_output_tokens, final_output_tokens, total_used_tokens, response_time)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-	_, err := s.db.Exec(ctx, query,
+			total_input_tokens, total_output_tokens, final_output_tokens, total_used_tokens, response_time,
+			parent_message_id, compare_group_id, is_active, content_key_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, TRUE, $15)`
+	_, err = tx.Exec(ctx, query,
 		modelMessageId,
 		userId,
 		sessionId,
 		modelDetail.ModelType,
-		modelDetail.Content,
+		content,
 		time.Now(),
 		modelDetail.Feedback,
 		modelDetail.TotalInputTokens,
@@ -97,9 +166,179 @@ func (s *storage) SaveModelMessage(ctx context.Context, userId, sessionId, model
 		modelDetail.FinalOutputTokens,
 		modelDetail.TotalUsedTokens,
 		modelDetail.ResponseTime,
+		modelDetail.ParentMessageId,
+		modelDetail.CompareGroupId,
+		keyId,
 	)
 	if err != nil {
 		return fmt.Errorf("error when insert data: %v", err)
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error when commit transaction: %v", err)
+	}
 	return nil
 }
+
+func (s *storage) GetMessageNodes(ctx context.Context, sessionId string) ([]branch.Node, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.GetMessageNodes")
+	defer span.End()
+
+	query := `
+		SELECT message_id, parent_message_id, 'user' AS role, is_active, created_at
+		FROM user_messages
+		WHERE session_id = $1
+
+		UNION ALL
+
+		SELECT message_id, parent_message_id, 'model' AS role, is_active, created_at
+		FROM model_messages
+		WHERE session_id = $1
+	`
+
+	rows, err := s.db.Query(ctx, query, sessionId)
+	if err != nil {
+		return nil, fmt.Errorf("error when query messages: %v", err)
+	}
+	defer rows.Close()
+
+	nodes := []branch.Node{}
+	for rows.Next() {
+		var node branch.Node
+		err := rows.Scan(&node.MessageId, &node.ParentMessageId, &node.Role, &node.IsActive, &node.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error when scan message: %v", err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error when read messages: %v", err)
+	}
+
+	return nodes, nil
+}
+
+func (s *storage) GetUserMessage(ctx context.Context, userId, sessionId, messageId string) (*UserMessageDetail, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.GetUserMessage")
+	defer span.End()
+
+	query := `
+		SELECT m.message_id, m.parent_message_id, m.content, m.user_prompt_tokens, m.model_answer_message_id,
+			m.content_key_id
+		FROM user_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE m.message_id = $1 AND m.session_id = $2 AND s.user_id = $3 AND s.deleted_at IS NULL
+	`
+
+	var detail UserMessageDetail
+	var keyId *string
+	err := s.db.QueryRow(ctx, query, messageId, sessionId, userId).Scan(
+		&detail.MessageId,
+		&detail.ParentMessageId,
+		&detail.Content,
+		&detail.UserPromptTokens,
+		&detail.ModelAnswerMessageId,
+		&keyId,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error when query user message: %v", err)
+	}
+
+	detail.Content, err = s.cipher.Decrypt(ctx, detail.Content, keyId)
+	if err != nil {
+		return nil, fmt.Errorf("error when decrypt user message: %v", err)
+	}
+
+	return &detail, nil
+}
+
+// ClaimTitleGeneration marks the title of the session as generated, it returns false when the title was already
+// generated or the user renamed the session, so only one turn of a session generates it
+func (s *storage) ClaimTitleGeneration(ctx context.Context, userId, sessionId string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.ClaimTitleGeneration")
+	defer span.End()
+
+	query := `
+		UPDATE chat_sessions
+		SET title_generated = TRUE
+		WHERE user_id = $1 AND session_id = $2 AND title_generated = FALSE AND title_edited = FALSE
+	`
+
+	cmdTag, err := s.db.Exec(ctx, query, userId, sessionId)
+	if err != nil {
+		return false, fmt.Errorf("error when claiming title generation: %v", err)
+	}
+
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// UpdateGeneratedTitle sets an auto-generated title, it returns false when the user already renamed the session
+func (s *storage) UpdateGeneratedTitle(ctx context.Context, userId, sessionId, title string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.UpdateGeneratedTitle")
+	defer span.End()
+
+	query := `
+		UPDATE chat_sessions
+		SET title = $3
+		WHERE user_id = $1 AND session_id = $2 AND title_edited = FALSE
+	`
+
+	cmdTag, err := s.db.Exec(ctx, query, userId, sessionId, title)
+	if err != nil {
+		return false, fmt.Errorf("error when updating title: %v", err)
+	}
+
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// GetPIIPolicy returns the redaction policy of the user's organization, nil when there is none
+func (s *storage) GetPIIPolicy(ctx context.Context, userId string) (*pii.Policy, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.GetPIIPolicy")
+	defer span.End()
+
+	query := `
+		SELECT p.enabled, p.entity_types, p.restore_response, p.store_mode
+		FROM users u
+		JOIN org_pii_policies p ON p.org_id = u.org_id
+		WHERE u.user_id = $1
+	`
+
+	var policy pii.Policy
+	err := s.db.QueryRow(ctx, query, userId).Scan(
+		&policy.Enabled,
+		&policy.Entities,
+		&policy.RestoreResponse,
+		&policy.StoreMode,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error when query pii policy: %v", err)
+	}
+
+	return &policy, nil
+}
+
+// GetUserPlan returns the plan tier of the user, the model queue serves higher tiers first
+func (s *storage) GetUserPlan(ctx context.Context, userId string) (string, error) {
+	ctx, span := tracing.Start(ctx, "chatbot.storage.GetUserPlan")
+	defer span.End()
+
+	query := `SELECT plan FROM users WHERE user_id = $1`
+
+	var plan string
+	err := s.db.QueryRow(ctx, query, userId).Scan(&plan)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error when query user plan: %v", err)
+	}
+
+	return plan, nil
+}
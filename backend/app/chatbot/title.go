@@ -0,0 +1,141 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+)
+
+const (
+	titleGenerationTimeout = 30 * time.Second
+	maxTitleRunes          = 40
+)
+
+// politeSuffixes are stripped from the end of a question before it is used as a fallback title
+var politeSuffixes = []string{"ครับ", "คะ", "ค่ะ", "ค่า", "จ้า", "นะ"}
+
+// generateSessionTitle runs after a model answer and generates the title once per session, the first turn
+// to claim it updates chat_sessions.title (unless the user already renamed it) and notifies the caller through onChunk with a "session_title" event
+func (s *MessageService) generateSessionTitle(ctx context.Context, req ChatbotProcessRequest, answer string, onChunk StreamCallback) {
+	logger := logging.FromContext(ctx)
+
+	// the title outlives the request, only its request ID and logger are kept
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), titleGenerationTimeout)
+	defer cancel()
+
+	claimed, err := s.storage.ClaimTitleGeneration(ctx, req.UserId, req.SessionId)
+	if err != nil {
+		logger.Error("failed to claim title generation", "sessionId", req.SessionId, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	question := req.Input.Messages.Content
+	title, err := s.callTitleModel(ctx, question, answer)
+	if err != nil {
+		logger.Warn("failed to generate title from model, using fallback", "sessionId", req.SessionId, "error", err)
+		title = fallbackTitle(question)
+	}
+	if title == "" {
+		return
+	}
+
+	updated, err := s.storage.UpdateGeneratedTitle(ctx, req.UserId, req.SessionId, title)
+	if err != nil {
+		logger.Error("failed to update session title", "sessionId", req.SessionId, "error", err)
+		return
+	}
+	if !updated {
+		// user renamed the session before the title was generated
+		return
+	}
+
+	if onChunk != nil {
+		onChunk(StreamEvent{
+			Type:  "session_title",
+			Title: title,
+		})
+	}
+}
+
+// callTitleModel asks the model service for a concise Thai title of the conversation
+func (s *MessageService) callTitleModel(ctx context.Context, question, answer string) (string, error) {
+	titleURL := s.cfg.Model.ModelTitleURL
+	if titleURL == "" {
+		return "", fmt.Errorf("model title url is not configured")
+	}
+
+	data := ChatbotRequest{
+		Messages: []Messages{
+			{Role: "user", Content: question},
+			{Role: "assistant", Content: answer},
+		},
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", titleURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+s.cfg.Model.ModelAPIkey)
+
+	httpResp, err := s.modelClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("error calling API: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed with status: %d", httpResp.StatusCode)
+	}
+
+	var response TitleResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	title := normalizeTitle(response.Title)
+	if title == "" {
+		return "", fmt.Errorf("model returned empty title")
+	}
+	return title, nil
+}
+
+// fallbackTitle builds a title from the first line of the user question
+func fallbackTitle(question string) string {
+	title := strings.TrimSpace(question)
+	if idx := strings.IndexAny(title, "\r\n"); idx >= 0 {
+		title = title[:idx]
+	}
+	title = strings.TrimRight(title, "?？!. ")
+	for _, suffix := range politeSuffixes {
+		title = strings.TrimSuffix(title, suffix)
+	}
+	title = strings.TrimRight(title, "?？!. ")
+	return normalizeTitle(title)
+}
+
+// normalizeTitle collapses whitespace, removes surrounding quotes and truncates to maxTitleRunes
+func normalizeTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	title = strings.Trim(title, "\"'“”")
+	if utf8.RuneCountInString(title) <= maxTitleRunes {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:maxTitleRunes])) + "…"
+}
@@ -3,16 +3,29 @@ package chatbot
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/ratelimit"
+	"github.com/PatiharnKam/AiLaw/app/semaphore"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const generationQueuePollInterval = 500 * time.Millisecond
+
 type Client struct {
 	conn    *websocket.Conn
 	userID  string
@@ -22,12 +35,26 @@ type Client struct {
 	mu      sync.Mutex
 	closed  bool
 
-	cancelMu      sync.Mutex
-	activeCancels map[string]context.CancelFunc // sessionID -> cancel function
+	connLease *semaphore.Lease
+	// requestID and logger come from the upgrade request, generations keep its request ID
+	requestID string
+	logger    *slog.Logger
+	// upgradeSpan is the span of the upgrade request, every message span links to it
+	upgradeSpan trace.SpanContext
+
+	generationMu sync.Mutex
+	generations  map[string]*generation // generationID -> running generation
+}
+
+// generation is a chat or compare answer being produced for the client
+type generation struct {
+	sessionID string
+	cancel    context.CancelFunc
+	lease     *semaphore.Lease
 }
 
 func (h *Handler) WebSocketHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 
 	userID := c.GetString("userId")
 	if userID == "" {
@@ -61,23 +88,38 @@ func (h *Handler) WebSocketHandler(c *gin.Context) {
 		return
 	}
 
+	connLease, err := h.semaphore.TryAcquire(c.Request.Context(), "ws_conn:user:"+userID, h.cfg.Concurrency.MaxConnectionsPerUser, h.cfg.Concurrency.LeaseTTL)
+	if err != nil {
+		// fail open, an unavailable Redis must not stop users from chatting
+		logger.Error("WebSocket: failed to acquire connection slot", "error", err.Error())
+	} else if connLease == nil {
+		logger.Warn("WebSocket: too many open connections")
+		rejectConnection(conn, "too many open connections, close another tab and try again")
+		return
+	}
+
 	client := &Client{
-		conn:          conn,
-		userID:        userID,
-		send:          make(chan []byte, 256),
-		done:          make(chan struct{}),
-		handler:       h,
-		activeCancels: make(map[string]context.CancelFunc),
+		conn:        conn,
+		userID:      userID,
+		send:        make(chan []byte, 256),
+		done:        make(chan struct{}),
+		handler:     h,
+		connLease:   connLease,
+		requestID:   logging.RequestID(c.Request.Context()),
+		logger:      logger,
+		upgradeSpan: trace.SpanContextFromContext(c.Request.Context()),
+		generations: make(map[string]*generation),
 	}
+	metrics.WebSocketConnected()
 
 	go client.writePump()
 	go client.readPump()
 
-	logger.Info("WebSocket connected", "userId", userID)
+	logger.Info("WebSocket connected")
 }
 
 func (c *Client) readPump() {
-	logger := slog.Default()
+	logger := c.logger
 	defer func() {
 		c.mu.Lock()
 		c.closed = true
@@ -86,7 +128,11 @@ func (c *Client) readPump() {
 
 		close(c.send)
 		c.conn.Close()
-		logger.Info("WebSocket disconnected", "userId", c.userID)
+		if err := c.connLease.Release(context.Background()); err != nil {
+			logger.Error("WebSocket: failed to release connection slot", "error", err.Error())
+		}
+		metrics.WebSocketDisconnected()
+		logger.Info("WebSocket disconnected")
 	}()
 
 	for {
@@ -105,8 +151,19 @@ func (c *Client) readPump() {
 		}
 
 		switch wsMsg.Type {
-		case "chat":
-			go c.handleChatMessage(wsMsg)
+		case "chat", "regenerate", "edit":
+			if !c.allowChatMessage() {
+				continue
+			}
+			if wsMsg.GenerationID == "" {
+				wsMsg.GenerationID = uuid.NewString()
+			}
+			if wsMsg.Type == "chat" && len(wsMsg.ModelTypes) > 0 {
+				// a chat message listing several model types runs in compare mode
+				go c.runGeneration(wsMsg, c.handleCompareMessage)
+				continue
+			}
+			go c.runGeneration(wsMsg, c.handleChatMessage)
 		case "cancel":
 			c.handleCancelMessage(wsMsg)
 		case "ping":
@@ -141,84 +198,230 @@ func (c *Client) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			c.refreshLeases()
 		}
 	}
 }
 
-func (c *Client) registerCancel(sessionID string, cancel context.CancelFunc) {
-	c.cancelMu.Lock()
-	defer c.cancelMu.Unlock()
-	c.activeCancels[sessionID] = cancel
-}
+// rejectConnection tells the client why the connection is refused and closes it
+func rejectConnection(conn *websocket.Conn, message string) {
+	defer conn.Close()
 
-func (c *Client) removeCancel(sessionID string) {
-	c.cancelMu.Lock()
-	defer c.cancelMu.Unlock()
-	delete(c.activeCancels, sessionID)
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	conn.WriteJSON(WSResponse{
+		Type: "error",
+		Error: &WSError{
+			Code:    app.ConcurrencyLimitErrorCode,
+			Message: message,
+		},
+	})
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, app.ConcurrencyLimitErrorMessage))
 }
 
-func (c *Client) handleCancelMessage(msg WSMessage) {
-	logger := slog.Default()
+// runGeneration registers the generation so "cancel" can stop it on its own, takes one of the user's
+// generation slots (waiting in line up to the queue timeout) and runs handle
+func (c *Client) runGeneration(msg WSMessage, handle func(ctx context.Context, msg WSMessage)) {
+	ctx := logging.WithRequestID(context.Background(), c.requestID)
+	ctx = logging.With(ctx, "userId", c.userID, "sessionId", msg.SessionID, "generationId", msg.GenerationID)
+	if msg.MessageID != "" {
+		ctx = logging.With(ctx, "messageId", msg.MessageID)
+	}
+	logger := logging.FromContext(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	if msg.SessionID == "" {
-		c.sendError("invalid_request", "sessionId is required for cancel")
+	// Monitor done channel
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// every message is a trace of its own, linked to the connection it came from
+	ctx, span := tracing.Start(ctx, "websocket."+msg.Type,
+		attribute.String("session.id", msg.SessionID),
+		attribute.String("generation.id", msg.GenerationID),
+		attribute.String("model.type", msg.ModelType),
+	)
+	span.AddLink(trace.Link{SpanContext: c.upgradeSpan})
+	defer span.End()
+
+	gen := &generation{
+		sessionID: msg.SessionID,
+		cancel:    cancel,
+	}
+	if !c.registerGeneration(msg.GenerationID, gen) {
+		c.sendGenerationError(msg, "invalid_request", "generationId "+msg.GenerationID+" is already in progress")
 		return
 	}
+	defer c.removeGeneration(msg.GenerationID)
 
-	c.cancelMu.Lock()
-	cancel, exists := c.activeCancels[msg.SessionID]
-	c.cancelMu.Unlock()
+	lease, ok := c.acquireGenerationSlot(ctx, msg)
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := lease.Release(context.Background()); err != nil {
+			logger.Error("failed to release generation slot", "error", err.Error())
+		}
+	}()
 
-	if exists {
-		cancel()
-		logger.Info("Chat cancelled by user", "userId", c.userID, "sessionId", msg.SessionID)
+	c.generationMu.Lock()
+	gen.lease = lease
+	c.generationMu.Unlock()
+
+	defer metrics.GenerationStarted("websocket")()
+	handle(ctx, msg)
+}
+
+// acquireGenerationSlot returns false when the generation must not run, the client has been told why
+func (c *Client) acquireGenerationSlot(ctx context.Context, msg WSMessage) (*semaphore.Lease, bool) {
+	logger := logging.FromContext(ctx)
+	cfg := c.handler.cfg.Concurrency
+	key := "generation:user:" + c.userID
+
+	lease, err := c.handler.semaphore.TryAcquire(ctx, key, cfg.MaxGenerationsPerUser, cfg.LeaseTTL)
+	if err != nil {
+		logger.Error("failed to acquire generation slot", "error", err.Error())
+		return nil, true
+	}
+	if lease != nil {
+		return lease, true
 	}
 
-	// Send cancel signal to FastAPI service
-	go c.handler.service.CancelModelRequest(msg.SessionID)
+	if cfg.QueueTimeout <= 0 {
+		c.sendGenerationError(msg, app.ConcurrencyLimitErrorCode, "too many answers in progress, wait for one to finish")
+		return nil, false
+	}
 
 	c.sendResponse(WSResponse{
-		Type:      "cancelled",
-		SessionID: msg.SessionID,
+		Type:         "queued",
+		SessionID:    msg.SessionID,
+		GenerationID: msg.GenerationID,
+		Status:       "waiting for another answer to finish",
 	})
+
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.QueueTimeout)
+	defer cancel()
+
+	lease, err = c.handler.semaphore.Acquire(waitCtx, key, cfg.MaxGenerationsPerUser, cfg.LeaseTTL, generationQueuePollInterval)
+	switch {
+	case err == nil:
+		return lease, true
+	case ctx.Err() != nil:
+		// cancelled or disconnected while waiting
+		return nil, false
+	case errors.Is(err, context.DeadlineExceeded):
+		c.sendGenerationError(msg, app.ConcurrencyLimitErrorCode, "too many answers in progress, try again later")
+		return nil, false
+	default:
+		logger.Error("failed to acquire generation slot", "error", err.Error())
+		return nil, true
+	}
 }
 
-func (c *Client) handleChatMessage(msg WSMessage) {
-	logger := slog.Default()
+func (c *Client) registerGeneration(generationID string, gen *generation) bool {
+	c.generationMu.Lock()
+	defer c.generationMu.Unlock()
+	if _, exists := c.generations[generationID]; exists {
+		return false
+	}
+	c.generations[generationID] = gen
+	return true
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer func() {
-		cancel()
-		c.removeCancel(msg.SessionID)
-	}()
+func (c *Client) removeGeneration(generationID string) {
+	c.generationMu.Lock()
+	defer c.generationMu.Unlock()
+	delete(c.generations, generationID)
+}
 
-	// Monitor done channel
-	go func() {
-		select {
-		case <-c.done:
-			cancel()
-		case <-ctx.Done():
+// refreshLeases keeps the connection and generation slots of a live client from expiring
+func (c *Client) refreshLeases() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	leases := []*semaphore.Lease{c.connLease}
+	c.generationMu.Lock()
+	for _, gen := range c.generations {
+		leases = append(leases, gen.lease)
+	}
+	c.generationMu.Unlock()
+
+	for _, lease := range leases {
+		if err := lease.Refresh(ctx); err != nil {
+			c.logger.Error("failed to refresh slot", "error", err.Error())
 		}
-	}()
+	}
+}
+
+// handleCancelMessage cancels one generation by generationId, or every generation of a session by sessionId
+func (c *Client) handleCancelMessage(msg WSMessage) {
+	logger := c.logger
 
-	if msg.SessionID == "" || msg.Content == "" {
-		c.sendError("invalid_request", "sessionId and content are required")
+	if msg.GenerationID == "" && msg.SessionID == "" {
+		c.sendError("invalid_request", "generationId or sessionId is required for cancel")
 		return
 	}
 
-	c.registerCancel(msg.SessionID, cancel)
+	var cancelled []WSResponse
+	c.generationMu.Lock()
+	for generationID, gen := range c.generations {
+		if generationID == msg.GenerationID || (msg.GenerationID == "" && gen.sessionID == msg.SessionID) {
+			gen.cancel()
+			cancelled = append(cancelled, WSResponse{
+				Type:         "cancelled",
+				SessionID:    gen.sessionID,
+				GenerationID: generationID,
+			})
+		}
+	}
+	c.generationMu.Unlock()
+
+	for _, resp := range cancelled {
+		logger.Info("Chat cancelled by user", "sessionId", resp.SessionID, "generationId", resp.GenerationID)
+		// Send cancel signal to FastAPI service
+		go c.handler.service.CancelModelRequest(resp.GenerationID)
+		c.sendResponse(resp)
+	}
+
+	if len(cancelled) == 0 {
+		c.sendResponse(WSResponse{
+			Type:         "cancelled",
+			SessionID:    msg.SessionID,
+			GenerationID: msg.GenerationID,
+		})
+	}
+}
+
+func (c *Client) handleChatMessage(ctx context.Context, msg WSMessage) {
+	logger := logging.FromContext(ctx)
+
+	if msg.SessionID == "" || (msg.Content == "" && msg.Type != "regenerate") {
+		c.sendGenerationError(msg, "invalid_request", "sessionId and content are required")
+		return
+	}
+	if msg.MessageID == "" && msg.Type != "chat" {
+		c.sendGenerationError(msg, "invalid_request", "messageId is required for "+msg.Type)
+		return
+	}
 
 	// Send acknowledgment
 	c.sendResponse(WSResponse{
-		Type:      "ack",
-		SessionID: msg.SessionID,
+		Type:         "ack",
+		SessionID:    msg.SessionID,
+		GenerationID: msg.GenerationID,
 	})
 
 	// Build request
 	req := ChatbotProcessRequest{
-		UserId:    c.userID,
-		SessionId: msg.SessionID,
-		ModelType: msg.ModelType,
+		UserId:       c.userID,
+		SessionId:    msg.SessionID,
+		ModelType:    msg.ModelType,
+		GenerationId: msg.GenerationID,
 		Input: Input{
 			Messages: Messages{
 				Role:    "user",
@@ -226,50 +429,32 @@ func (c *Client) handleChatMessage(msg WSMessage) {
 			},
 		},
 	}
+	switch msg.Type {
+	case "regenerate":
+		req.RegenerateMessageId = msg.MessageID
+	case "edit":
+		req.EditMessageId = msg.MessageID
+	}
 
 	// Stream callback
 	streamCallback := func(event StreamEvent) {
-		var wsResp WSResponse
-		wsResp.SessionID = msg.SessionID
-
-		switch event.Type {
-		case "guard_passed":
-			wsResp.Type = "guard_passed"
-		case "status":
-			wsResp.Type = "status"
-			wsResp.Status = event.Message
-		case "plan":
-			wsResp.Type = "plan"
-			wsResp.Steps = event.Steps
-			wsResp.Rationale = event.Rationale
-		case "cot_step":
-			wsResp.Type = "cot_step"
-			wsResp.CurrentStep = event.Step
-			wsResp.TotalSteps = event.Total
-			wsResp.StepDesc = event.Description
-		case "content":
-			wsResp.Type = "chunk"
-			wsResp.Content = event.Text
-		case "error":
-			wsResp.Type = "error"
-			wsResp.Error = &WSError{
-				Code:    "model_error",
-				Message: event.Error,
-			}
-		default:
+		wsResp, ok := toWSResponse(event)
+		if !ok {
 			return
 		}
+		wsResp.SessionID = msg.SessionID
+		wsResp.GenerationID = msg.GenerationID
 		c.sendResponse(wsResp)
 	}
 
 	resp, err := c.handler.service.ChatbotProcessWithStream(ctx, req, streamCallback)
 	if err != nil {
 		if ctx.Err() == context.Canceled || resp.Code == "cancelled" {
-			logger.Info("Chat process cancelled", "userId", c.userID, "sessionId", msg.SessionID)
+			logger.Info("Chat process cancelled")
 			return
 		}
 		logger.Error("Chat process error", "error", err.Error())
-		c.sendError(resp.Code, resp.Message)
+		c.sendGenerationError(msg, resp.Code, resp.Message)
 		return
 	}
 
@@ -278,11 +463,162 @@ func (c *Client) handleChatMessage(msg WSMessage) {
 	c.sendResponse(WSResponse{
 		Type:           "done",
 		SessionID:      msg.SessionID,
+		GenerationID:   msg.GenerationID,
 		ModelMessageID: respData.ModelMessageID,
+		UserMessageID:  respData.UserMessageID,
+		ModelType:      respData.ModelType,
 		Content:        respData.Message,
 	})
 }
 
+// handleCompareMessage streams the same question from several model types side by side
+func (c *Client) handleCompareMessage(ctx context.Context, msg WSMessage) {
+	logger := logging.FromContext(ctx)
+
+	req := CompareProcessRequest{
+		UserId:     c.userID,
+		SessionId:  msg.SessionID,
+		ModelTypes: msg.ModelTypes,
+		Input: Input{
+			Messages: Messages{
+				Role:    "user",
+				Content: msg.Content,
+			},
+		},
+	}
+
+	if err := c.handler.validator.Struct(req); err != nil || msg.Content == "" {
+		c.sendGenerationError(msg, "invalid_request", "sessionId, content and two distinct modelTypes are required")
+		return
+	}
+
+	c.sendResponse(WSResponse{
+		Type:         "ack",
+		SessionID:    msg.SessionID,
+		GenerationID: msg.GenerationID,
+	})
+
+	streamCallback := func(streamId, modelType string, event StreamEvent) {
+		wsResp, ok := toWSResponse(event)
+		if !ok {
+			return
+		}
+		wsResp.SessionID = msg.SessionID
+		wsResp.GenerationID = msg.GenerationID
+		wsResp.StreamID = streamId
+		wsResp.ModelType = modelType
+		c.sendResponse(wsResp)
+	}
+
+	resp, err := c.handler.service.ChatbotCompareWithStream(ctx, req, streamCallback)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			logger.Info("Compare process cancelled")
+			return
+		}
+		logger.Error("Compare process error", "error", err.Error())
+		c.sendGenerationError(msg, resp.Code, resp.Message)
+		return
+	}
+
+	respData := resp.Data.(CompareMessageResponse)
+	for _, answer := range respData.Answers {
+		wsResp := WSResponse{
+			Type:           "done",
+			SessionID:      msg.SessionID,
+			GenerationID:   msg.GenerationID,
+			StreamID:       answer.StreamID,
+			ModelType:      answer.ModelType,
+			ModelMessageID: answer.ModelMessageID,
+			UserMessageID:  respData.UserMessageID,
+			CompareGroupID: respData.CompareGroupID,
+			Content:        answer.Message,
+		}
+		if answer.Error != "" {
+			wsResp.Type = "error"
+			wsResp.Error = &WSError{
+				Code:    "model_error",
+				Message: answer.Error,
+			}
+		}
+		c.sendResponse(wsResp)
+	}
+
+	c.sendResponse(WSResponse{
+		Type:           "compare_done",
+		SessionID:      msg.SessionID,
+		GenerationID:   msg.GenerationID,
+		UserMessageID:  respData.UserMessageID,
+		CompareGroupID: respData.CompareGroupID,
+	})
+}
+
+// toWSResponse maps a model stream event to the WebSocket message sent to the client
+func toWSResponse(event StreamEvent) (WSResponse, bool) {
+	var wsResp WSResponse
+
+	switch event.Type {
+	case "guard_passed":
+		wsResp.Type = "guard_passed"
+	case "status":
+		wsResp.Type = "status"
+		wsResp.Status = event.Message
+		wsResp.QueuePosition = event.QueuePosition
+		wsResp.ModelType = event.ModelType
+	case "plan":
+		wsResp.Type = "plan"
+		wsResp.Steps = event.Steps
+		wsResp.Rationale = event.Rationale
+	case "cot_step":
+		wsResp.Type = "cot_step"
+		wsResp.CurrentStep = event.Step
+		wsResp.TotalSteps = event.Total
+		wsResp.StepDesc = event.Description
+	case "content":
+		wsResp.Type = "chunk"
+		wsResp.Content = event.Text
+	case "error":
+		wsResp.Type = "error"
+		wsResp.Error = &WSError{
+			Code:    "model_error",
+			Message: event.Error,
+		}
+	case "session_title":
+		wsResp.Type = "session_title"
+		wsResp.Title = event.Title
+	case "moderation_warning":
+		wsResp.Type = "warning"
+		wsResp.Status = event.Message
+	default:
+		return wsResp, false
+	}
+	return wsResp, true
+}
+
+// allowChatMessage throttles the messages that start a model answer, the client gets an error when it has to slow down
+func (c *Client) allowChatMessage() bool {
+	if c.handler.limiter == nil {
+		return true
+	}
+
+	rule := ratelimit.Rule{
+		Name:      "ws_chat",
+		PerMinute: c.handler.cfg.RateLimit.WSChatPerMinute,
+		Burst:     c.handler.cfg.RateLimit.WSChatBurst,
+	}
+	result, err := c.handler.limiter.Allow(context.Background(), rule, "user:"+c.userID)
+	if err != nil {
+		c.logger.Error("WebSocket rate limit check failed", "error", err)
+		return true
+	}
+	if !result.Allowed {
+		c.logger.Warn("WebSocket chat rate limit exceeded")
+		c.sendError(app.RateLimitExceededErrorCode, fmt.Sprintf("%s, retry in %d seconds", app.RateLimitExceededErrorMessage, int(math.Ceil(result.RetryAfter.Seconds()))))
+		return false
+	}
+	return true
+}
+
 func (c *Client) sendResponse(resp WSResponse) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -293,14 +629,14 @@ func (c *Client) sendResponse(resp WSResponse) {
 
 	data, err := json.Marshal(resp)
 	if err != nil {
-		slog.Error("Failed to marshal response", "error", err.Error())
+		c.logger.Error("Failed to marshal response", "error", err.Error())
 		return
 	}
 
 	select {
 	case c.send <- data:
 	default:
-		slog.Warn("Client send buffer full", "userId", c.userID)
+		c.logger.Warn("Client send buffer full")
 	}
 }
 
@@ -313,3 +649,16 @@ func (c *Client) sendError(code, message string) {
 		},
 	})
 }
+
+// sendGenerationError reports an error of one generation so the client can tell which stream failed
+func (c *Client) sendGenerationError(msg WSMessage, code, message string) {
+	c.sendResponse(WSResponse{
+		Type:         "error",
+		SessionID:    msg.SessionID,
+		GenerationID: msg.GenerationID,
+		Error: &WSError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
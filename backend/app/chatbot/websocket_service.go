@@ -13,13 +13,36 @@ import (
 	"time"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (s *MessageService) ChatbotProcessWithStream(ctx context.Context, req ChatbotProcessRequest, onChunk StreamCallback) (app.Response, error) {
-	logger := slog.Default()
+	logger := logging.FromContext(ctx)
+
+	plan, errResp, err := s.resolveBranch(ctx, req)
+	if err != nil {
+		return errResp, err
+	}
+
+	errResp, err = s.moderatePrompt(ctx, req, plan.Content, onChunk)
+	if err != nil {
+		return errResp, err
+	}
+
+	redaction, err := s.redactPrompt(ctx, req.UserId, plan)
+	if err != nil {
+		return app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		}, err
+	}
+	req.Input.Messages.Content = redaction.Prompt
 
-	userPromptTokens, err := s.quotaService.CheckPromptLength(req.Input.Messages.Content)
+	plan.UserPromptTokens, err = s.quotaService.CheckPromptLength(plan.Content)
 	if err != nil {
 		return app.Response{
 			Code:    app.UserPromptLengthExceededErrorCode,
@@ -50,6 +73,58 @@ func (s *MessageService) ChatbotProcessWithStream(ctx context.Context, req Chatb
 		}, fmt.Errorf("error updating last message: %w", err)
 	}
 
+	streamId := req.SessionId
+	if req.GenerationId != "" {
+		streamId = req.GenerationId
+	}
+
+	streamCallback, flush := redaction.Stream(onChunk)
+	modelMessageDetail, wasCancelled, err := s.generateAnswer(ctx, req, streamId, true, streamCallback)
+	flush()
+
+	if wasCancelled || ctx.Err() == context.Canceled {
+		return app.Response{}, fmt.Errorf("cancelled")
+	}
+
+	if err != nil {
+		return modelErrorResponse(err), fmt.Errorf("streaming error: %w", err)
+	}
+
+	modelAnswer := modelMessageDetail.Content
+	answer, storedAnswer := redaction.Answer(modelAnswer)
+	modelMessageDetail.Content = storedAnswer
+
+	modelMessageId := uuid.NewString()
+	errResp, err = s.saveBranch(ctx, req, plan, modelMessageId, modelMessageDetail)
+	if err != nil {
+		return errResp, err
+	}
+
+	err = s.quotaService.ConsumeTokens(ctx, req.UserId, int64(modelMessageDetail.TotalUsedTokens))
+	if err != nil {
+		logger.Warn("failed to consume tokens", "error", err)
+	}
+
+	// the title model only sees the redacted conversation
+	go s.generateSessionTitle(ctx, req, modelAnswer, onChunk)
+
+	return app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data: StreamingMessageResponse{
+			Message:        answer,
+			ModelMessageID: modelMessageId,
+			UserMessageID:  plan.UserMessageId,
+			ModelType:      modelMessageDetail.ModelType,
+		},
+	}, nil
+}
+
+// streamModelAnswer streams one answer for req.ModelType, forwarding progress events to onChunk.
+// streamId is sent to FastAPI as session_id so the stream can be cancelled on its own
+func (s *MessageService) streamModelAnswer(ctx context.Context, req ChatbotProcessRequest, streamId string, onChunk StreamCallback) (ModelMessageDetail, bool, error) {
+	logger := logging.FromContext(ctx)
+
 	modelURL := s.cfg.Model.ModelStreamURL
 	if req.ModelType == "COT" {
 		modelURL = s.cfg.Model.ModelCOTStreamURL
@@ -59,9 +134,21 @@ func (s *MessageService) ChatbotProcessWithStream(ctx context.Context, req Chatb
 		ModelType: req.ModelType,
 	}
 
+	ctx, span := tracing.Start(ctx, "model.stream",
+		attribute.String("model.type", req.ModelType),
+		attribute.String("model.stream_id", streamId),
+	)
+
+	release, err := s.waitForModel(ctx, req.UserId, onChunk)
+	if err != nil {
+		tracing.End(span, err)
+		return modelMessageDetail, false, err
+	}
+	defer release()
+
 	var modelErr error
 	var wasCancelled bool
-	responseTime, err := s.callFastAPIStream(ctx, req, modelURL, func(event StreamEvent) {
+	responseTime, err := s.callFastAPIStream(ctx, req, streamId, modelURL, func(event StreamEvent) {
 		switch event.Type {
 		case "content":
 			modelMessageDetail.Content += event.Text
@@ -82,7 +169,7 @@ func (s *MessageService) ChatbotProcessWithStream(ctx context.Context, req Chatb
 			}
 		case "cancelled":
 			wasCancelled = true
-			logger.Info("stream cancelled by FastAPI", "sessionId", req.SessionId)
+			logger.Info("stream cancelled by FastAPI", "sessionId", req.SessionId, "streamId", streamId)
 		case "error":
 			logger.Error("stream error", "error", event.Error)
 			modelErr = fmt.Errorf("model error: %s", event.Error)
@@ -90,53 +177,50 @@ func (s *MessageService) ChatbotProcessWithStream(ctx context.Context, req Chatb
 	})
 	modelMessageDetail.ResponseTime = responseTime
 
-	if wasCancelled || ctx.Err() == context.Canceled {
-		return app.Response{}, fmt.Errorf("cancelled")
+	if err == nil {
+		err = modelErr
 	}
-
-	if err != nil || modelErr != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("streaming error: %w", err)
+	if err == nil && !wasCancelled && responseTime != nil {
+		metrics.ObserveModelAnswer(req.ModelType, "stream", *responseTime,
+			modelMessageDetail.TotalInputTokens, modelMessageDetail.TotalOutputTokens, modelMessageDetail.TotalUsedTokens)
 	}
+	span.SetAttributes(
+		attribute.Bool("model.cancelled", wasCancelled),
+		attribute.Int("model.total_used_tokens", modelMessageDetail.TotalUsedTokens),
+	)
+	tracing.End(span, err)
+	return modelMessageDetail, wasCancelled, err
+}
 
-	modelMessageId := uuid.NewString()
-	err = s.storage.SaveUserMessage(ctx, req.UserId, req.SessionId, modelMessageId, req.Input.Messages.Content, userPromptTokens)
-	if err != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("error saving user message: %w", err)
+// GenerateAnswer streams one answer for question from modelType without touching storage or quota,
+// it is used by the offline evaluation command so answers go through the same request construction
+func (s *MessageService) GenerateAnswer(ctx context.Context, modelType, question string) (ModelMessageDetail, error) {
+	req := ChatbotProcessRequest{
+		ModelType: modelType,
+		Input: Input{
+			Messages: Messages{
+				Role:    "user",
+				Content: question,
+			},
+		},
 	}
 
-	err = s.storage.SaveModelMessage(ctx, req.UserId, req.SessionId, modelMessageId, modelMessageDetail)
+	streamId := uuid.NewString()
+	detail, cancelled, err := s.streamModelAnswer(ctx, req, streamId, nil)
 	if err != nil {
-		return app.Response{
-			Code:    app.InternalServerErrorCode,
-			Message: app.InternalServerErrorMessage,
-		}, fmt.Errorf("error saving model message: %w", err)
+		return detail, err
 	}
-
-	err = s.quotaService.ConsumeTokens(ctx, req.UserId, int64(modelMessageDetail.TotalUsedTokens))
-	if err != nil {
-		logger.Warn("failed to consume tokens", "error", err)
+	if cancelled {
+		return detail, fmt.Errorf("stream %s was cancelled", streamId)
 	}
-
-	return app.Response{
-		Code:    app.SUCCESS_CODE,
-		Message: app.SUCCESS_MSG,
-		Data: StreamingMessageResponse{
-			Message:        modelMessageDetail.Content,
-			ModelMessageID: modelMessageId,
-		},
-	}, nil
+	return detail, nil
 }
 
 // callFastAPIStream calls FastAPI SSE endpoint
 func (s *MessageService) callFastAPIStream(
 	ctx context.Context,
 	req ChatbotProcessRequest,
+	streamId string,
 	modelURL string,
 	onEvent func(StreamEvent),
 ) (*float64, error) {
@@ -148,7 +232,7 @@ func (s *MessageService) callFastAPIStream(
 				Content: req.Input.Messages.Content,
 			},
 		},
-		SessionID: req.SessionId,
+		SessionID: streamId,
 	}
 
 	jsonData, err := json.Marshal(data)
@@ -166,7 +250,7 @@ func (s *MessageService) callFastAPIStream(
 	httpReq.Header.Set("Authorization", "Bearer "+s.cfg.Model.ModelAPIkey)
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.modelClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("error calling API: %w", err)
 	}
@@ -179,6 +263,7 @@ func (s *MessageService) callFastAPIStream(
 
 	// Parse SSE stream
 	reader := bufio.NewReader(resp.Body)
+	firstChunk := true
 	for {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
@@ -210,10 +295,15 @@ func (s *MessageService) callFastAPIStream(
 
 			var event StreamEvent
 			if err := json.Unmarshal([]byte(data), &event); err != nil {
-				slog.Warn("failed to parse SSE event", "data", data, "error", err)
+				logging.FromContext(ctx).Warn("failed to parse SSE event", "data", data, "error", err)
 				continue
 			}
 
+			if event.Type == "content" && firstChunk {
+				firstChunk = false
+				metrics.ObserveTimeToFirstChunk(req.ModelType, time.Since(callStart))
+			}
+
 			onEvent(event)
 
 			if event.Type == "done" || event.Type == "error" || event.Type == "cancelled" {
@@ -248,7 +338,7 @@ func (s *MessageService) CancelModelRequest(sessionID string) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+s.cfg.Model.ModelAPIkey)
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.modelClient.Do(req)
 	if err != nil {
 		logger.Error("Failed to send cancel request to model", "error", err)
 		return
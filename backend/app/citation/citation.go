@@ -0,0 +1,31 @@
+package citation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sectionPattern matches law section references such as "มาตรา 420", "มาตรา ๖๕๔" or "มาตรา 1336/1"
+var sectionPattern = regexp.MustCompile(`มาตรา\s*([0-9๐-๙]+(?:/[0-9๐-๙]+)?)`)
+
+var thaiDigits = strings.NewReplacer(
+	"๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4",
+	"๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9",
+)
+
+// ExtractSections returns the distinct law sections cited in text, normalized to arabic digits, in order of appearance
+func ExtractSections(text string) []string {
+	sections := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range sectionPattern.FindAllStringSubmatch(text, -1) {
+		section := "มาตรา " + thaiDigits.Replace(match[1])
+		if seen[section] {
+			continue
+		}
+		seen[section] = true
+		sections = append(sections, section)
+	}
+
+	return sections
+}
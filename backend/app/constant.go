@@ -10,11 +10,31 @@ const (
 	UserPromptLengthExceededErrorCode = "10001"
 	QuotaExceededErrorCode            = "10002"
 	UnauthorizedErrorCode             = "10003"
+	NotFoundErrorCode                 = "10004"
+	ForbiddenErrorCode                = "10005"
+	PromptRejectedErrorCode           = "10006"
+	RateLimitExceededErrorCode        = "10007"
+	ConcurrencyLimitErrorCode         = "10008"
+	ModelBusyErrorCode                = "10009"
+	ModelUnavailableErrorCode         = "10010"
+	ModelTimeoutErrorCode             = "10011"
+	ExportNotReadyErrorCode           = "10012"
+	JobRunningErrorCode               = "10013"
 	InternalServerErrorCode           = "99999"
 
 	UserPromptLengthExceededErrorMessage = "user prompt length exceeded"
 	QuotaExceededErrorMessage            = "quota exceeded"
 	UnauthorizedErrorMessage             = "unauthorized access"
+	NotFoundErrorMessage                 = "resource not found"
+	ForbiddenErrorMessage                = "permission denied"
+	PromptRejectedErrorMessage           = "prompt rejected by content moderation"
+	RateLimitExceededErrorMessage        = "too many requests"
+	ConcurrencyLimitErrorMessage         = "too many concurrent requests"
+	ModelBusyErrorMessage                = "model service is busy, please try again later"
+	ModelUnavailableErrorMessage         = "model service is unavailable, please try again later"
+	ModelTimeoutErrorMessage             = "model service took too long to answer"
+	ExportNotReadyErrorMessage           = "data export is not ready yet"
+	JobRunningErrorMessage               = "job is already running"
 	InvalidRequestErrorMessage           = "invalid request"
 	InternalServerErrorMessage           = "internal server error"
 	ActionLogout                         = "logout"
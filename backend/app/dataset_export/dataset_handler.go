@@ -0,0 +1,91 @@
+package datasetexport
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// exportWriteTimeout bounds every write of the export, the deadline moves forward as records are written
+// so a large export is not cut by the server WriteTimeout while a client that stops reading still times out
+const exportWriteTimeout = time.Minute
+
+type Handler struct {
+	service   DatasetService
+	validator *validator.Validate
+}
+
+func NewHandler(service DatasetService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// ExportDatasetHandler streams the filtered answers as a JSONL download. The status is sent before the first
+// record, so the last line tells a complete download from a truncated one, see DatasetTrailer
+func (h *Handler) ExportDatasetHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req DatasetExportRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil || (!req.From.IsZero() && !req.To.IsZero() && req.To.Before(req.From)) {
+		logger.Error("invalid request query", "error", err)
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+
+	fileName := "dataset-" + time.Now().Format("20060102-150405") + ".jsonl"
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	var w io.Writer = c.Writer
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+		logger.Warn("cannot extend write deadline, the export is bound by the server write timeout", "error", err)
+	} else {
+		w = &deadlineWriter{w: c.Writer, controller: controller}
+	}
+
+	ctx := c.Request.Context()
+	count, err := h.service.ExportDatasetService(ctx, req, w)
+	if err != nil {
+		// the body is already streaming, the download ends without a complete trailer
+		logger.Error("error while export dataset : "+err.Error(), "records", count)
+		return
+	}
+
+	logger.Info("dataset exported", "userId", c.GetString("userId"), "records", count)
+}
+
+// deadlineWriter moves the write deadline forward before every write
+type deadlineWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
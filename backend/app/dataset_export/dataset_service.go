@@ -0,0 +1,111 @@
+package datasetexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/app/citation"
+	"github.com/PatiharnKam/AiLaw/app/pii"
+)
+
+type Service struct {
+	storage DatasetStorage
+	audit   audit.AuditService
+}
+
+func NewService(storage DatasetStorage, auditService audit.AuditService) *Service {
+	return &Service{
+		storage: storage,
+		audit:   auditService,
+	}
+}
+
+// ExportDatasetService writes the dataset to w as JSON lines followed by a DatasetTrailer and returns
+// the number of records written
+func (s *Service) ExportDatasetService(ctx context.Context, req DatasetExportRequest, w io.Writer) (int, error) {
+	scrub := req.ScrubPII == nil || *req.ScrubPII
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	count := 0
+	err := s.storage.StreamDatasetStorage(ctx, req, func(data DatasetRowData) error {
+		record := toDatasetRecord(data, scrub)
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("error when encode dataset record: %v", err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		// best effort, the writer may be what failed
+		encoder.Encode(DatasetTrailer{Complete: false, Records: count, Error: "export failed"})
+		return count, fmt.Errorf("failed to stream dataset in storage error : %w", err)
+	}
+	if err := encoder.Encode(DatasetTrailer{Complete: true, Records: count}); err != nil {
+		return count, fmt.Errorf("error when encode dataset trailer: %v", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminDatasetExport,
+		TargetType: audit.TargetDataset,
+		After: map[string]any{
+			"from":      req.From,
+			"to":        req.To,
+			"rating":    req.Rating,
+			"modelType": req.ModelType,
+			"scrubPii":  scrub,
+			"records":   count,
+		},
+	})
+	return count, nil
+}
+
+func toDatasetRecord(data DatasetRowData, scrub bool) DatasetRecord {
+	clean := func(text string) string {
+		if scrub {
+			return pii.Scrub(text)
+		}
+		return text
+	}
+	cleanPtr := func(text *string) *string {
+		if text == nil {
+			return nil
+		}
+		cleaned := clean(*text)
+		return &cleaned
+	}
+
+	record := DatasetRecord{
+		MessageId:           data.MessageId,
+		SessionId:           data.SessionId,
+		Answer:              clean(data.Answer),
+		ModelType:           data.ModelType,
+		Feedback:            data.Feedback,
+		FeedbackCategories:  data.FeedbackCategories,
+		FeedbackSeverity:    data.FeedbackSeverity,
+		FeedbackDetail:      cleanPtr(data.FeedbackDetail),
+		SuggestedCorrection: cleanPtr(data.SuggestedCorrection),
+		CitedSections:       citation.ExtractSections(data.Answer),
+		CreatedAt:           data.CreatedAt,
+	}
+	if data.Question != nil {
+		record.Question = clean(*data.Question)
+	}
+	if data.InputTokens != nil {
+		record.InputTokens = *data.InputTokens
+	}
+	if data.OutputTokens != nil {
+		record.OutputTokens = *data.OutputTokens
+	}
+	if data.TotalUsedTokens != nil {
+		record.TotalUsedTokens = *data.TotalUsedTokens
+	}
+	if record.FeedbackCategories == nil {
+		record.FeedbackCategories = []string{}
+	}
+	return record
+}
@@ -0,0 +1,160 @@
+package datasetexport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db     *pgxpool.Pool
+	cipher encryption.Cipher
+}
+
+func NewStorage(db *pgxpool.Pool, cipher encryption.Cipher) *Storage {
+	return &Storage{db: db, cipher: cipher}
+}
+
+// datasetBatchSize is the number of rows read per query while the dataset is streamed
+const datasetBatchSize = 500
+
+// StreamDatasetStorage calls fn for every answer matching req, the rows are read in batches so the export never
+// holds the whole dataset in memory. Each batch is decrypted after its rows are closed, loading a data key
+// needs a connection of its own
+func (s *Storage) StreamDatasetStorage(ctx context.Context, req DatasetExportRequest, fn func(DatasetRowData) error) error {
+	var after *DatasetRowData
+	for {
+		dataList, err := s.datasetBatch(ctx, req, after)
+		if err != nil {
+			return err
+		}
+
+		for _, data := range dataList {
+			if data.Question != nil {
+				question, err := s.cipher.Decrypt(ctx, *data.Question, data.QuestionKeyId)
+				if err != nil {
+					return fmt.Errorf("error when decrypt question of %s: %v", data.MessageId, err)
+				}
+				data.Question = &question
+			}
+			data.Answer, err = s.cipher.Decrypt(ctx, data.Answer, data.AnswerKeyId)
+			if err != nil {
+				return fmt.Errorf("error when decrypt answer %s: %v", data.MessageId, err)
+			}
+			if err := fn(data); err != nil {
+				return err
+			}
+		}
+
+		if len(dataList) < datasetBatchSize {
+			return nil
+		}
+		after = &dataList[len(dataList)-1]
+	}
+}
+
+// datasetBatch reads the rows following after, in the order of their creation
+func (s *Storage) datasetBatch(ctx context.Context, req DatasetExportRequest, after *DatasetRowData) ([]DatasetRowData, error) {
+	conditions := "s.deleted_at IS NULL"
+	args := []any{}
+
+	if !req.From.IsZero() {
+		args = append(args, req.From)
+		conditions += fmt.Sprintf(" AND m.created_at >= $%d", len(args))
+	}
+	if !req.To.IsZero() {
+		// the to date is inclusive
+		args = append(args, req.To.AddDate(0, 0, 1))
+		conditions += fmt.Sprintf(" AND m.created_at < $%d", len(args))
+	}
+	if req.Rating != nil {
+		if *req.Rating == 0 {
+			conditions += " AND m.feedback IS NULL"
+		} else {
+			args = append(args, *req.Rating)
+			conditions += fmt.Sprintf(" AND m.feedback = $%d", len(args))
+		}
+	}
+	if req.ModelType != nil {
+		args = append(args, *req.ModelType)
+		conditions += fmt.Sprintf(" AND m.model_type = $%d", len(args))
+	}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.MessageId)
+		conditions += fmt.Sprintf(" AND (m.created_at, m.message_id) > ($%d, $%d::uuid)", len(args)-1, len(args))
+	}
+	args = append(args, datasetBatchSize)
+
+	query := `
+		SELECT
+			m.message_id,
+			m.session_id,
+			u.content AS question,
+			m.content,
+			m.model_type,
+			m.total_input_tokens,
+			m.total_output_tokens,
+			m.total_used_tokens,
+			m.feedback,
+			m.feedback_categories,
+			m.feedback_severity,
+			m.feedback_detail,
+			f.suggested_correction,
+			m.created_at,
+			u.content_key_id AS question_key_id,
+			m.content_key_id
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		LEFT JOIN user_messages u ON u.message_id = m.parent_message_id
+		LEFT JOIN LATERAL (
+			SELECT suggested_correction
+			FROM message_feedback
+			WHERE message_id = m.message_id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) f ON TRUE
+		WHERE ` + conditions + `
+		ORDER BY m.created_at ASC, m.message_id ASC
+		LIMIT ` + fmt.Sprintf("$%d", len(args)) + `;
+	`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := make([]DatasetRowData, 0, datasetBatchSize)
+	for rows.Next() {
+		var data DatasetRowData
+		err := rows.Scan(
+			&data.MessageId,
+			&data.SessionId,
+			&data.Question,
+			&data.Answer,
+			&data.ModelType,
+			&data.InputTokens,
+			&data.OutputTokens,
+			&data.TotalUsedTokens,
+			&data.Feedback,
+			&data.FeedbackCategories,
+			&data.FeedbackSeverity,
+			&data.FeedbackDetail,
+			&data.SuggestedCorrection,
+			&data.CreatedAt,
+			&data.QuestionKeyId,
+			&data.AnswerKeyId,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return dataList, nil
+}
@@ -0,0 +1,70 @@
+package datasetexport
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type DatasetService interface {
+	ExportDatasetService(ctx context.Context, req DatasetExportRequest, w io.Writer) (int, error)
+}
+
+type DatasetStorage interface {
+	StreamDatasetStorage(ctx context.Context, req DatasetExportRequest, fn func(DatasetRowData) error) error
+}
+
+type DatasetExportRequest struct {
+	From      time.Time `form:"from" time_format:"2006-01-02"`
+	To        time.Time `form:"to" time_format:"2006-01-02"`
+	Rating    *int      `form:"rating" validate:"omitempty,oneof=1 -1 0"` // 0 exports unrated answers only
+	ModelType *string   `form:"modelType" validate:"omitempty,oneof=default COT"`
+	ScrubPII  *bool     `form:"scrubPii"`
+	// UserId is the admin exporting the dataset
+	UserId string `form:"-"`
+}
+
+// DatasetTrailer is the last line of an export, a download that does not end with a complete trailer was cut short
+type DatasetTrailer struct {
+	Complete bool   `json:"exportComplete"`
+	Records  int    `json:"records"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DatasetRecord is one JSONL line of the exported dataset
+type DatasetRecord struct {
+	MessageId           string    `json:"messageId"`
+	SessionId           string    `json:"sessionId"`
+	Question            string    `json:"question"`
+	Answer              string    `json:"answer"`
+	ModelType           *string   `json:"modelType"`
+	InputTokens         int       `json:"inputTokens"`
+	OutputTokens        int       `json:"outputTokens"`
+	TotalUsedTokens     int       `json:"totalUsedTokens"`
+	Feedback            *int      `json:"feedback"`
+	FeedbackCategories  []string  `json:"feedbackCategories"`
+	FeedbackSeverity    *string   `json:"feedbackSeverity"`
+	FeedbackDetail      *string   `json:"feedbackDetail"`
+	SuggestedCorrection *string   `json:"suggestedCorrection"`
+	CitedSections       []string  `json:"citedSections"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+type DatasetRowData struct {
+	MessageId           string    `db:"message_id"`
+	SessionId           string    `db:"session_id"`
+	Question            *string   `db:"question"`
+	Answer              string    `db:"content"`
+	ModelType           *string   `db:"model_type"`
+	InputTokens         *int      `db:"total_input_tokens"`
+	OutputTokens        *int      `db:"total_output_tokens"`
+	TotalUsedTokens     *int      `db:"total_used_tokens"`
+	Feedback            *int      `db:"feedback"`
+	FeedbackCategories  []string  `db:"feedback_categories"`
+	FeedbackSeverity    *string   `db:"feedback_severity"`
+	FeedbackDetail      *string   `db:"feedback_detail"`
+	SuggestedCorrection *string   `db:"suggested_correction"`
+	CreatedAt           time.Time `db:"created_at"`
+	QuestionKeyId       *string   `db:"question_key_id"`
+	AnswerKeyId         *string   `db:"content_key_id"`
+}
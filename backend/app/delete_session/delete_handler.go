@@ -1,10 +1,11 @@
 package deletechatsession
 
 import (
-	"log/slog"
+	"errors"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -22,7 +23,7 @@ func NewHandler(service DeleteChatSessionService) *Handler {
 }
 
 func (h *Handler) DeleteChatSessionHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req DeleteChatSessionRequest
 
 	req.UserID = c.GetString("userId")
@@ -53,3 +54,76 @@ func (h *Handler) DeleteChatSessionHandler(c *gin.Context) {
 		Message: app.SUCCESS_MSG,
 	})
 }
+
+func (h *Handler) GetTrashSessionsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req TrashSessionsRequest
+
+	req.UserID = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetTrashSessionsService(ctx, req)
+	if err != nil {
+		logger.Error("error while get trash sessions : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) RestoreChatSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req RestoreChatSessionRequest
+
+	req.UserID = c.GetString("userId")
+	req.SessionID = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.RestoreChatSessionService(ctx, req)
+	if err != nil {
+		logger.Error("error while restore session : " + err.Error())
+		if errors.Is(err, ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
@@ -3,15 +3,23 @@ package deletechatsession
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/config"
 )
 
 type Service struct {
+	cfg     *config.Trash
 	storage DeleteChatSessionStorage
+	audit   audit.AuditService
 }
 
-func NewService(storage DeleteChatSessionStorage) *Service {
+func NewService(cfg *config.Trash, storage DeleteChatSessionStorage, auditService audit.AuditService) *Service {
 	return &Service{
+		cfg:     cfg,
 		storage: storage,
+		audit:   auditService,
 	}
 }
 
@@ -21,5 +29,78 @@ func (s *Service) DeleteChatSessionService(ctx context.Context, req DeleteChatSe
 		return fmt.Errorf("failed to delete chat session in storage error : %w", err)
 	}
 
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserID,
+		Action:     audit.ActionSessionDelete,
+		TargetType: audit.TargetSession,
+		TargetId:   req.SessionID,
+	})
+
 	return nil
 }
+
+func (s *Service) GetTrashSessionsService(ctx context.Context, req TrashSessionsRequest) ([]TrashSessionResponse, error) {
+	resp, err := s.storage.GetTrashSessionsStorage(ctx, req.UserID, s.retentionCutoff())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash sessions in storage error : %w", err)
+	}
+
+	trashResp := []TrashSessionResponse{}
+	for _, data := range resp {
+		trashResp = append(trashResp, TrashSessionResponse{
+			SessionId:     data.SessionId,
+			Title:         data.Title,
+			CreatedAt:     data.CreatedAt,
+			LastMessageAt: data.LastMessageAt,
+			DeletedAt:     data.DeletedAt,
+			PurgeAt:       data.DeletedAt.Add(s.retention()),
+		})
+	}
+
+	return trashResp, nil
+}
+
+func (s *Service) RestoreChatSessionService(ctx context.Context, req RestoreChatSessionRequest) error {
+	err := s.storage.RestoreChatSessionStorage(ctx, req, s.retentionCutoff())
+	if err != nil {
+		return fmt.Errorf("failed to restore chat session in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserID,
+		Action:     audit.ActionSessionRestore,
+		TargetType: audit.TargetSession,
+		TargetId:   req.SessionID,
+	})
+
+	return nil
+}
+
+func (s *Service) PurgeExpiredSessionsService(ctx context.Context) (int64, error) {
+	purged, err := s.storage.PurgeDeletedSessionsStorage(ctx, s.retentionCutoff())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted sessions in storage error : %w", err)
+	}
+
+	if purged > 0 {
+		s.audit.Record(ctx, audit.Event{
+			Action:     audit.ActionSessionPurge,
+			TargetType: audit.TargetSession,
+			After: map[string]any{
+				"count":         purged,
+				"retentionDays": s.cfg.RetentionDays,
+			},
+		})
+	}
+
+	return purged, nil
+}
+
+func (s *Service) retention() time.Duration {
+	return time.Duration(s.cfg.RetentionDays) * 24 * time.Hour
+}
+
+// retentionCutoff is the oldest deleted_at that can still be listed or restored
+func (s *Service) retentionCutoff() time.Time {
+	return time.Now().Add(-s.retention())
+}
@@ -3,6 +3,7 @@ package deletechatsession
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -17,10 +18,11 @@ func NewStorage(db *pgxpool.Pool) *Storage {
 
 func (s *Storage) DeleteChatSessionStorage(ctx context.Context, req DeleteChatSessionRequest) error {
 	query := `
-		DELETE FROM chat_sessions
-		WHERE user_id = $1 AND session_id = $2;
+		UPDATE chat_sessions
+		SET deleted_at = $3
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL;
 	`
-	rows, err := s.db.Exec(ctx, query, req.UserID, req.SessionID)
+	rows, err := s.db.Exec(ctx, query, req.UserID, req.SessionID, time.Now())
 	if err != nil {
 		return err
 	}
@@ -31,3 +33,96 @@ func (s *Storage) DeleteChatSessionStorage(ctx context.Context, req DeleteChatSe
 
 	return nil
 }
+
+func (s *Storage) GetTrashSessionsStorage(ctx context.Context, userId string, deletedAfter time.Time) ([]TrashSessionData, error) {
+	query := `
+		SELECT
+			session_id,
+			title,
+			created_at,
+			last_message_at,
+			deleted_at
+		FROM chat_sessions
+		WHERE user_id = $1 AND deleted_at > $2
+		ORDER BY deleted_at DESC;
+	`
+
+	rows, err := s.db.Query(ctx, query, userId, deletedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []TrashSessionData{}
+
+	for rows.Next() {
+		var data TrashSessionData
+		err := rows.Scan(
+			&data.SessionId,
+			&data.Title,
+			&data.CreatedAt,
+			&data.LastMessageAt,
+			&data.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+func (s *Storage) RestoreChatSessionStorage(ctx context.Context, req RestoreChatSessionRequest, deletedAfter time.Time) error {
+	query := `
+		UPDATE chat_sessions
+		SET deleted_at = NULL
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at > $3;
+	`
+	rows, err := s.db.Exec(ctx, query, req.UserID, req.SessionID, deletedAfter)
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeletedSessionsStorage permanently removes sessions trashed before deletedBefore together with their messages
+func (s *Storage) PurgeDeletedSessionsStorage(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	expiredSessions := `SELECT session_id FROM chat_sessions WHERE deleted_at IS NOT NULL AND deleted_at <= $1`
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_messages WHERE session_id IN (`+expiredSessions+`)`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error when delete user messages: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM model_messages WHERE session_id IN (`+expiredSessions+`)`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error when delete model messages: %v", err)
+	}
+
+	rows, err := tx.Exec(ctx, `DELETE FROM chat_sessions WHERE deleted_at IS NOT NULL AND deleted_at <= $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error when delete sessions: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error when commit transaction: %v", err)
+	}
+
+	return rows.RowsAffected(), nil
+}
@@ -1,16 +1,54 @@
 package deletechatsession
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
 
 type DeleteChatSessionService interface {
 	DeleteChatSessionService(ctx context.Context, req DeleteChatSessionRequest) error
+	GetTrashSessionsService(ctx context.Context, req TrashSessionsRequest) ([]TrashSessionResponse, error)
+	RestoreChatSessionService(ctx context.Context, req RestoreChatSessionRequest) error
+	PurgeExpiredSessionsService(ctx context.Context) (int64, error)
 }
 
 type DeleteChatSessionStorage interface {
 	DeleteChatSessionStorage(ctx context.Context, req DeleteChatSessionRequest) error
+	GetTrashSessionsStorage(ctx context.Context, userId string, deletedAfter time.Time) ([]TrashSessionData, error)
+	RestoreChatSessionStorage(ctx context.Context, req RestoreChatSessionRequest, deletedAfter time.Time) error
+	PurgeDeletedSessionsStorage(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type DeleteChatSessionRequest struct {
 	UserID    string `json:"userId" validate:"required"`
 	SessionID string `json:"sessionID" validate:"required"`
 }
+
+type TrashSessionsRequest struct {
+	UserID string `json:"userId" validate:"required"`
+}
+
+type RestoreChatSessionRequest struct {
+	UserID    string `json:"userId" validate:"required"`
+	SessionID string `json:"sessionID" validate:"required"`
+}
+
+type TrashSessionResponse struct {
+	SessionId     string    `json:"sessionId"`
+	Title         string    `json:"title"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	DeletedAt     time.Time `json:"deletedAt"`
+	PurgeAt       time.Time `json:"purgeAt"`
+}
+
+type TrashSessionData struct {
+	SessionId     string    `db:"session_id"`
+	Title         string    `db:"title"`
+	CreatedAt     time.Time `db:"created_at"`
+	LastMessageAt time.Time `db:"last_message_at"`
+	DeletedAt     time.Time `db:"deleted_at"`
+}
@@ -0,0 +1,209 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) GetLatestDataKeyStorage(ctx context.Context, userId string) (*DataKeyData, error) {
+	query := `
+		SELECT key_id, user_id, version, wrapped_key, master_key_id, created_at
+		FROM data_keys
+		WHERE user_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	data, err := scanDataKey(s.db.QueryRow(ctx, query, userId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *Storage) GetDataKeyStorage(ctx context.Context, keyId string) (*DataKeyData, error) {
+	query := `
+		SELECT key_id, user_id, version, wrapped_key, master_key_id, created_at
+		FROM data_keys
+		WHERE key_id = $1
+	`
+
+	data, err := scanDataKey(s.db.QueryRow(ctx, query, keyId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDataKeyNotFound
+	}
+	return data, err
+}
+
+func (s *Storage) CreateDataKeyStorage(ctx context.Context, data DataKeyData) (bool, error) {
+	query := `INSERT INTO data_keys
+				(key_id, user_id, version, wrapped_key, master_key_id, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (user_id, version) DO NOTHING`
+
+	result, err := s.db.Exec(ctx, query,
+		data.KeyId,
+		data.UserId,
+		data.Version,
+		data.WrappedKey,
+		data.MasterKeyId,
+		data.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *Storage) ListDataKeysStorage(ctx context.Context, masterKeyId, afterKeyId string, limit int) ([]DataKeyData, error) {
+	query := `
+		SELECT key_id, user_id, version, wrapped_key, master_key_id, created_at
+		FROM data_keys
+		WHERE master_key_id <> $1 AND key_id > $2
+		ORDER BY key_id ASC
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(ctx, query, masterKeyId, afterKeyId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []DataKeyData{}
+
+	for rows.Next() {
+		data, err := scanDataKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, *data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+func (s *Storage) UpdateWrappedKeyStorage(ctx context.Context, keyId string, wrappedKey []byte, masterKeyId string) error {
+	query := `UPDATE data_keys SET wrapped_key = $2, master_key_id = $3 WHERE key_id = $1`
+
+	_, err := s.db.Exec(ctx, query, keyId, wrappedKey, masterKeyId)
+	return err
+}
+
+func (s *Storage) ListKeyOwnersStorage(ctx context.Context, afterUserId string, limit int) ([]string, error) {
+	query := `
+		SELECT DISTINCT user_id
+		FROM data_keys
+		WHERE user_id > $1
+		ORDER BY user_id ASC
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(ctx, query, afterUserId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIds := []string{}
+
+	for rows.Next() {
+		var userId string
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, userId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIds, nil
+}
+
+func scanDataKey(row pgx.Row) (*DataKeyData, error) {
+	var data DataKeyData
+	err := row.Scan(
+		&data.KeyId,
+		&data.UserId,
+		&data.Version,
+		&data.WrappedKey,
+		&data.MasterKeyId,
+		&data.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *Storage) ListMessagesStorage(ctx context.Context, table, afterMessageId string, limit int) ([]MessageData, error) {
+	if table != TableUserMessages && table != TableModelMessages {
+		return nil, fmt.Errorf("unknown message table %q", table)
+	}
+
+	query := `
+		SELECT message_id, user_id, content, content_key_id
+		FROM ` + table + `
+		WHERE message_id > $1
+		ORDER BY message_id ASC
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(ctx, query, afterMessageId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []MessageData{}
+
+	for rows.Next() {
+		var data MessageData
+		if err := rows.Scan(&data.MessageId, &data.UserId, &data.Content, &data.KeyId); err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+func (s *Storage) UpdateContentStorage(ctx context.Context, table string, data MessageData, content, keyId string) (bool, error) {
+	if table != TableUserMessages && table != TableModelMessages {
+		return false, fmt.Errorf("unknown message table %q", table)
+	}
+
+	query := `
+		UPDATE ` + table + `
+		SET content = $4, content_key_id = $5
+		WHERE message_id = $1 AND content = $2 AND content_key_id IS NOT DISTINCT FROM $3
+	`
+
+	result, err := s.db.Exec(ctx, query, data.MessageId, data.Content, data.KeyId, content, keyId)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
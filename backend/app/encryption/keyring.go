@@ -0,0 +1,340 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+// aad binds encrypted content to the data key it is encrypted with, the version leaves room for another format
+const aad = "message:v1:"
+
+const keySize = 32
+
+type cachedKey struct {
+	keyId   string
+	key     []byte
+	expires time.Time
+}
+
+// Keyring encrypts content with AES-GCM under per user data keys, which are stored wrapped by a master key
+type Keyring struct {
+	cfg     *config.Encryption
+	storage KeyStorage
+	masters map[string][]byte
+
+	mu sync.Mutex
+	// userKeys caches the latest data key of a user, dataKeys the unwrapped data keys by key ID
+	userKeys map[string]cachedKey
+	dataKeys map[string]cachedKey
+}
+
+func NewKeyring(cfg *config.Encryption, storage KeyStorage) (*Keyring, error) {
+	masters := make(map[string][]byte, len(cfg.MasterKeys))
+	for id, encoded := range cfg.MasterKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("error when decode master key %s: %v", id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key %s is %d bytes, want %d", id, len(key), keySize)
+		}
+		masters[id] = key
+	}
+
+	if cfg.Enabled {
+		if _, ok := masters[cfg.ActiveMasterKey]; !ok {
+			return nil, fmt.Errorf("active master key %q is not listed in the master keys", cfg.ActiveMasterKey)
+		}
+	}
+
+	return &Keyring{
+		cfg:      cfg,
+		storage:  storage,
+		masters:  masters,
+		userKeys: map[string]cachedKey{},
+		dataKeys: map[string]cachedKey{},
+	}, nil
+}
+
+func (k *Keyring) Enabled() bool {
+	return k.cfg.Enabled
+}
+
+// Encrypt returns plaintext as it is and no key ID while encryption is disabled
+func (k *Keyring) Encrypt(ctx context.Context, userId, plaintext string) (string, *string, error) {
+	if !k.cfg.Enabled {
+		return plaintext, nil, nil
+	}
+	content, keyId, err := k.encrypt(ctx, userId, plaintext)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, &keyId, nil
+}
+
+func (k *Keyring) encrypt(ctx context.Context, userId, plaintext string) (string, string, error) {
+	keyId, key, err := k.latestKey(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", fmt.Errorf("error when generate nonce: %v", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(aad+keyId))
+	return base64.StdEncoding.EncodeToString(sealed), keyId, nil
+}
+
+// Decrypt returns content as it is when keyId is nil, the content was stored in plaintext
+func (k *Keyring) Decrypt(ctx context.Context, content string, keyId *string) (string, error) {
+	if keyId == nil {
+		return content, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+	key, err := k.dataKey(ctx, *keyId)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedCiphertext
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(aad+*keyId))
+	if err != nil {
+		return "", fmt.Errorf("error when decrypt content with data key %s: %v", *keyId, err)
+	}
+	return string(plaintext), nil
+}
+
+// LatestKeyId returns the ID of the data key new content of the user is encrypted with
+func (k *Keyring) LatestKeyId(ctx context.Context, userId string) (string, error) {
+	keyId, _, err := k.latestKey(ctx, userId)
+	return keyId, err
+}
+
+func (k *Keyring) latestKey(ctx context.Context, userId string) (string, []byte, error) {
+	k.mu.Lock()
+	cached, ok := k.userKeys[userId]
+	k.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.keyId, cached.key, nil
+	}
+
+	data, err := k.storage.GetLatestDataKeyStorage(ctx, userId)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get data key in storage error : %w", err)
+	}
+	if data == nil {
+		data, err = k.createDataKey(ctx, userId, 1)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	key, err := k.unwrap(*data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	k.mu.Lock()
+	k.userKeys[userId] = cachedKey{keyId: data.KeyId, key: key, expires: time.Now().Add(k.cfg.KeyCacheTTL)}
+	k.mu.Unlock()
+	return data.KeyId, key, nil
+}
+
+func (k *Keyring) dataKey(ctx context.Context, keyId string) ([]byte, error) {
+	k.mu.Lock()
+	cached, ok := k.dataKeys[keyId]
+	k.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	data, err := k.storage.GetDataKeyStorage(ctx, keyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key %s in storage error : %w", keyId, err)
+	}
+	key, err := k.unwrap(*data)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.dataKeys[keyId] = cachedKey{keyId: keyId, key: key, expires: time.Now().Add(k.cfg.KeyCacheTTL)}
+	k.mu.Unlock()
+	return key, nil
+}
+
+// createDataKey stores a new data key of the given version, the key stored by a concurrent
+// call for the same version wins and is returned instead
+func (k *Keyring) createDataKey(ctx context.Context, userId string, version int) (*DataKeyData, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error when generate data key: %v", err)
+	}
+
+	data := DataKeyData{
+		KeyId:       uuid.NewString(),
+		UserId:      userId,
+		Version:     version,
+		MasterKeyId: k.cfg.ActiveMasterKey,
+		CreatedAt:   time.Now(),
+	}
+	wrapped, err := k.wrap(data, key)
+	if err != nil {
+		return nil, err
+	}
+	data.WrappedKey = wrapped
+
+	created, err := k.storage.CreateDataKeyStorage(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data key in storage error : %w", err)
+	}
+	if created {
+		return &data, nil
+	}
+
+	latest, err := k.storage.GetLatestDataKeyStorage(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key in storage error : %w", err)
+	}
+	if latest == nil {
+		return nil, ErrDataKeyNotFound
+	}
+	return latest, nil
+}
+
+// RotateUserKey gives the user a new data key for the content written from now on, content encrypted
+// with the previous keys stays readable until the migration re-encrypts it
+func (k *Keyring) RotateUserKey(ctx context.Context, userId string) (string, error) {
+	latest, err := k.storage.GetLatestDataKeyStorage(ctx, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to get data key in storage error : %w", err)
+	}
+	version := 1
+	if latest != nil {
+		version = latest.Version + 1
+	}
+
+	data, err := k.createDataKey(ctx, userId, version)
+	if err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	delete(k.userKeys, userId)
+	k.mu.Unlock()
+	return data.KeyId, nil
+}
+
+// RewrapKeys wraps every data key still wrapped by a replaced master key with the active master key,
+// the content is not touched
+func (k *Keyring) RewrapKeys(ctx context.Context, batchSize int) (int64, error) {
+	active := k.cfg.ActiveMasterKey
+	if _, ok := k.masters[active]; !ok {
+		return 0, fmt.Errorf("active master key %q: %w", active, ErrUnknownMasterKey)
+	}
+
+	var rewrapped int64
+	after := FirstId
+	for {
+		dataList, err := k.storage.ListDataKeysStorage(ctx, active, after, batchSize)
+		if err != nil {
+			return rewrapped, fmt.Errorf("failed to list data keys in storage error : %w", err)
+		}
+
+		for _, data := range dataList {
+			key, err := k.unwrap(data)
+			if err != nil {
+				return rewrapped, err
+			}
+			data.MasterKeyId = active
+			wrapped, err := k.wrap(data, key)
+			if err != nil {
+				return rewrapped, err
+			}
+			if err := k.storage.UpdateWrappedKeyStorage(ctx, data.KeyId, wrapped, active); err != nil {
+				return rewrapped, fmt.Errorf("failed to update data key in storage error : %w", err)
+			}
+			rewrapped++
+			after = data.KeyId
+		}
+
+		if len(dataList) < batchSize {
+			return rewrapped, nil
+		}
+	}
+}
+
+// wrap seals a data key with its master key, bound to the key and its user so a wrapped key cannot be moved to another row
+func (k *Keyring) wrap(data DataKeyData, key []byte) ([]byte, error) {
+	master, ok := k.masters[data.MasterKeyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %q: %w", data.MasterKeyId, ErrUnknownMasterKey)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error when generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, key, []byte(data.KeyId+":"+data.UserId)), nil
+}
+
+func (k *Keyring) unwrap(data DataKeyData) ([]byte, error) {
+	master, ok := k.masters[data.MasterKeyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %q of data key %s: %w", data.MasterKeyId, data.KeyId, ErrUnknownMasterKey)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.WrappedKey) < aead.NonceSize() {
+		return nil, ErrMalformedCiphertext
+	}
+	nonce, wrapped := data.WrappedKey[:aead.NonceSize()], data.WrappedKey[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, wrapped, []byte(data.KeyId+":"+data.UserId))
+	if err != nil {
+		return nil, fmt.Errorf("error when unwrap data key %s: %v", data.KeyId, err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error when create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error when create gcm: %v", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,344 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+const (
+	userA = "11111111-1111-1111-1111-111111111111"
+	userB = "22222222-2222-2222-2222-222222222222"
+)
+
+// fakeStorage keeps data keys and messages in memory
+type fakeStorage struct {
+	keys     []DataKeyData
+	messages map[string]MessageData
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{messages: map[string]MessageData{}}
+}
+
+func (f *fakeStorage) GetLatestDataKeyStorage(ctx context.Context, userId string) (*DataKeyData, error) {
+	var latest *DataKeyData
+	for i := range f.keys {
+		if f.keys[i].UserId == userId && (latest == nil || f.keys[i].Version > latest.Version) {
+			data := f.keys[i]
+			latest = &data
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeStorage) GetDataKeyStorage(ctx context.Context, keyId string) (*DataKeyData, error) {
+	for _, data := range f.keys {
+		if data.KeyId == keyId {
+			return &data, nil
+		}
+	}
+	return nil, ErrDataKeyNotFound
+}
+
+func (f *fakeStorage) CreateDataKeyStorage(ctx context.Context, data DataKeyData) (bool, error) {
+	for _, existing := range f.keys {
+		if existing.UserId == data.UserId && existing.Version == data.Version {
+			return false, nil
+		}
+	}
+	f.keys = append(f.keys, data)
+	return true, nil
+}
+
+func (f *fakeStorage) ListDataKeysStorage(ctx context.Context, masterKeyId, afterKeyId string, limit int) ([]DataKeyData, error) {
+	sort.Slice(f.keys, func(i, j int) bool { return f.keys[i].KeyId < f.keys[j].KeyId })
+	dataList := []DataKeyData{}
+	for _, data := range f.keys {
+		if data.MasterKeyId != masterKeyId && data.KeyId > afterKeyId && len(dataList) < limit {
+			dataList = append(dataList, data)
+		}
+	}
+	return dataList, nil
+}
+
+func (f *fakeStorage) UpdateWrappedKeyStorage(ctx context.Context, keyId string, wrappedKey []byte, masterKeyId string) error {
+	for i := range f.keys {
+		if f.keys[i].KeyId == keyId {
+			f.keys[i].WrappedKey = wrappedKey
+			f.keys[i].MasterKeyId = masterKeyId
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) ListKeyOwnersStorage(ctx context.Context, afterUserId string, limit int) ([]string, error) {
+	seen := map[string]bool{}
+	userIds := []string{}
+	for _, data := range f.keys {
+		if data.UserId > afterUserId && !seen[data.UserId] {
+			seen[data.UserId] = true
+			userIds = append(userIds, data.UserId)
+		}
+	}
+	sort.Strings(userIds)
+	if len(userIds) > limit {
+		userIds = userIds[:limit]
+	}
+	return userIds, nil
+}
+
+func (f *fakeStorage) ListMessagesStorage(ctx context.Context, table, afterMessageId string, limit int) ([]MessageData, error) {
+	ids := []string{}
+	for id := range f.messages {
+		if id > afterMessageId {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	dataList := []MessageData{}
+	for _, id := range ids {
+		if len(dataList) < limit {
+			dataList = append(dataList, f.messages[id])
+		}
+	}
+	return dataList, nil
+}
+
+func (f *fakeStorage) UpdateContentStorage(ctx context.Context, table string, data MessageData, content, keyId string) (bool, error) {
+	current := f.messages[data.MessageId]
+	if current.Content != data.Content || !sameKey(current.KeyId, data.KeyId) {
+		return false, nil
+	}
+	current.Content = content
+	current.KeyId = &keyId
+	f.messages[data.MessageId] = current
+	return true, nil
+}
+
+func sameKey(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func masterKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestKeyring(t *testing.T, storage KeyStorage, enabled bool, active string, masters map[string]string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(&config.Encryption{
+		Enabled:         enabled,
+		MasterKeys:      masters,
+		ActiveMasterKey: active,
+		KeyCacheTTL:     time.Minute,
+	}, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keyring := newTestKeyring(t, newFakeStorage(), true, "m1", map[string]string{"m1": masterKey(t)})
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"empty", ""},
+		{"thai", "ผู้เช่าต้องแจ้งล่วงหน้ากี่วันก่อนย้ายออก"},
+		{"looks encrypted", "enc:v1:x:AAAA"},
+		{"base64", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, keyId, err := keyring.Encrypt(ctx, userA, tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if keyId == nil {
+				t.Fatal("Encrypt() returned no key ID while encryption is enabled")
+			}
+			if tt.plaintext != "" && content == tt.plaintext {
+				t.Fatal("Encrypt() stored the plaintext")
+			}
+
+			got, err := keyring.Decrypt(ctx, content, keyId)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != tt.plaintext {
+				t.Fatalf("Decrypt() = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestKeyringPlaintext(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeStorage()
+	keyring := newTestKeyring(t, storage, false, "", nil)
+
+	// content a user typed is never mistaken for ciphertext, only a key ID marks content as encrypted
+	for _, plaintext := range []string{"hello", "enc:v1:x:AAAA", "enc:v1:" + userA + ":!!!"} {
+		content, keyId, err := keyring.Encrypt(ctx, userA, plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) error = %v", plaintext, err)
+		}
+		if content != plaintext || keyId != nil {
+			t.Fatalf("Encrypt(%q) = %q, %v, want the plaintext without key ID", plaintext, content, keyId)
+		}
+		got, err := keyring.Decrypt(ctx, content, keyId)
+		if err != nil || got != plaintext {
+			t.Fatalf("Decrypt(%q) = %q, %v", plaintext, got, err)
+		}
+	}
+	if len(storage.keys) != 0 {
+		t.Fatalf("created %d data keys while encryption is disabled", len(storage.keys))
+	}
+}
+
+func TestKeyringRejectsOtherKey(t *testing.T) {
+	ctx := context.Background()
+	keyring := newTestKeyring(t, newFakeStorage(), true, "m1", map[string]string{"m1": masterKey(t)})
+
+	content, _, err := keyring.Encrypt(ctx, userA, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKeyId, err := keyring.Encrypt(ctx, userB, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keyring.Decrypt(ctx, content, otherKeyId); err == nil {
+		t.Fatal("Decrypt() with the data key of another user succeeded")
+	}
+	if _, err := keyring.Decrypt(ctx, "not base64!", otherKeyId); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Fatalf("Decrypt() error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeStorage()
+	keyring := newTestKeyring(t, storage, true, "m1", map[string]string{"m1": masterKey(t)})
+
+	before, beforeKeyId, err := keyring.Encrypt(ctx, userA, "before rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedKeyId, err := keyring.RotateUserKey(ctx, userA)
+	if err != nil {
+		t.Fatalf("RotateUserKey() error = %v", err)
+	}
+	if rotatedKeyId == *beforeKeyId {
+		t.Fatal("RotateUserKey() kept the data key")
+	}
+
+	_, afterKeyId, err := keyring.Encrypt(ctx, userA, "after rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *afterKeyId != rotatedKeyId {
+		t.Fatalf("Encrypt() used data key %s, want the rotated %s", *afterKeyId, rotatedKeyId)
+	}
+
+	got, err := keyring.Decrypt(ctx, before, beforeKeyId)
+	if err != nil || got != "before rotation" {
+		t.Fatalf("Decrypt() of content from before the rotation = %q, %v", got, err)
+	}
+}
+
+func TestKeyringRewrap(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeStorage()
+	oldMaster, newMaster := masterKey(t), masterKey(t)
+	keyring := newTestKeyring(t, storage, true, "m1", map[string]string{"m1": oldMaster})
+
+	content, keyId, err := keyring.Encrypt(ctx, userA, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapping := newTestKeyring(t, storage, true, "m2", map[string]string{"m1": oldMaster, "m2": newMaster})
+	rewrapped, err := rewrapping.RewrapKeys(ctx, 1)
+	if err != nil || rewrapped != 1 {
+		t.Fatalf("RewrapKeys() = %d, %v, want 1", rewrapped, err)
+	}
+
+	// the replaced master key is no longer needed
+	onlyNew := newTestKeyring(t, storage, true, "m2", map[string]string{"m2": newMaster})
+	got, err := onlyNew.Decrypt(ctx, content, keyId)
+	if err != nil || got != "secret" {
+		t.Fatalf("Decrypt() after rewrap = %q, %v", got, err)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeStorage()
+	keyring := newTestKeyring(t, storage, true, "m1", map[string]string{"m1": masterKey(t)})
+	migrator := NewMigrator(keyring, storage, 2)
+
+	plaintexts := map[string]string{
+		"a1": "first question",
+		"b2": "enc:v1:x:AAAA",
+		"c3": "third question",
+	}
+	storage.messages["a1"] = MessageData{MessageId: "a1", UserId: userA, Content: plaintexts["a1"]}
+	storage.messages["b2"] = MessageData{MessageId: "b2", UserId: userB, Content: plaintexts["b2"]}
+	storage.messages["c3"] = MessageData{MessageId: "c3", UserId: userA, Content: plaintexts["c3"]}
+
+	result, err := migrator.EncryptMessages(ctx, TableUserMessages, false, false)
+	if err != nil {
+		t.Fatalf("EncryptMessages() error = %v", err)
+	}
+	if result != (MigrationResult{Scanned: 3, Encrypted: 3}) {
+		t.Fatalf("EncryptMessages() = %+v", result)
+	}
+
+	result, err = migrator.EncryptMessages(ctx, TableUserMessages, false, false)
+	if err != nil || result != (MigrationResult{Scanned: 3, Skipped: 3}) {
+		t.Fatalf("second EncryptMessages() = %+v, %v", result, err)
+	}
+
+	if _, err := migrator.RotateKeys(ctx, storage); err != nil {
+		t.Fatalf("RotateKeys() error = %v", err)
+	}
+	result, err = migrator.EncryptMessages(ctx, TableUserMessages, true, false)
+	if err != nil || result != (MigrationResult{Scanned: 3, Encrypted: 3}) {
+		t.Fatalf("EncryptMessages() after rotation = %+v, %v", result, err)
+	}
+
+	for id, data := range storage.messages {
+		latest, err := keyring.LatestKeyId(ctx, data.UserId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.KeyId == nil || *data.KeyId != latest {
+			t.Fatalf("message %s is not encrypted with the latest data key", id)
+		}
+		got, err := keyring.Decrypt(ctx, data.Content, data.KeyId)
+		if err != nil || got != plaintexts[id] {
+			t.Fatalf("Decrypt() of message %s = %q, %v, want %q", id, got, err, plaintexts[id])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+)
+
+// Migrator rewrites message content already in the database, it is run by cmd/encryption
+type Migrator struct {
+	keyring   *Keyring
+	storage   MessageStorage
+	batchSize int
+}
+
+func NewMigrator(keyring *Keyring, storage MessageStorage, batchSize int) *Migrator {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &Migrator{
+		keyring:   keyring,
+		storage:   storage,
+		batchSize: batchSize,
+	}
+}
+
+// EncryptMessages encrypts the plaintext content of table. With reencrypt, content encrypted with a data key
+// that is no longer its user's latest is also encrypted again, which finishes a data key rotation.
+// A message changed while it is migrated is counted as a conflict and left for the next run
+func (m *Migrator) EncryptMessages(ctx context.Context, table string, reencrypt bool, dryRun bool) (MigrationResult, error) {
+	var result MigrationResult
+	if !m.keyring.Enabled() {
+		return result, fmt.Errorf("encryption is disabled, new messages would still be stored in plaintext")
+	}
+
+	after := FirstId
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		dataList, err := m.storage.ListMessagesStorage(ctx, table, after, m.batchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to list %s in storage error : %w", table, err)
+		}
+
+		for _, data := range dataList {
+			after = data.MessageId
+			result.Scanned++
+
+			plaintext, migrate, err := m.plaintext(ctx, data, reencrypt)
+			if err != nil {
+				return result, fmt.Errorf("error when read message %s: %v", data.MessageId, err)
+			}
+			if !migrate {
+				result.Skipped++
+				continue
+			}
+			if dryRun {
+				result.Encrypted++
+				continue
+			}
+
+			encrypted, keyId, err := m.keyring.encrypt(ctx, data.UserId, plaintext)
+			if err != nil {
+				return result, fmt.Errorf("error when encrypt message %s: %v", data.MessageId, err)
+			}
+			updated, err := m.storage.UpdateContentStorage(ctx, table, data, encrypted, keyId)
+			if err != nil {
+				return result, fmt.Errorf("failed to update message %s in storage error : %w", data.MessageId, err)
+			}
+			if updated {
+				result.Encrypted++
+			} else {
+				result.Conflicts++
+			}
+		}
+
+		if len(dataList) < m.batchSize {
+			return result, nil
+		}
+	}
+}
+
+// plaintext returns the content of the message and whether it has to be encrypted
+func (m *Migrator) plaintext(ctx context.Context, data MessageData, reencrypt bool) (string, bool, error) {
+	if data.KeyId == nil {
+		return data.Content, true, nil
+	}
+	if !reencrypt {
+		return "", false, nil
+	}
+
+	latest, err := m.keyring.LatestKeyId(ctx, data.UserId)
+	if err != nil {
+		return "", false, err
+	}
+	if *data.KeyId == latest {
+		return "", false, nil
+	}
+
+	plaintext, err := m.keyring.Decrypt(ctx, data.Content, data.KeyId)
+	if err != nil {
+		return "", false, err
+	}
+	return plaintext, true, nil
+}
+
+// RotateKeys gives every user who has a data key a new one, run EncryptMessages with reencrypt
+// afterwards to move the existing content to the new keys
+func (m *Migrator) RotateKeys(ctx context.Context, storage KeyStorage) (int64, error) {
+	var rotated int64
+	after := FirstId
+	for {
+		userIds, err := storage.ListKeyOwnersStorage(ctx, after, m.batchSize)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to list key owners in storage error : %w", err)
+		}
+
+		for _, userId := range userIds {
+			if _, err := m.keyring.RotateUserKey(ctx, userId); err != nil {
+				return rotated, fmt.Errorf("error when rotate data key of user %s: %v", userId, err)
+			}
+			rotated++
+			after = userId
+		}
+
+		if len(userIds) < m.batchSize {
+			return rotated, nil
+		}
+	}
+}
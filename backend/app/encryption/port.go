@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMalformedCiphertext = errors.New("malformed ciphertext")
+	ErrDataKeyNotFound     = errors.New("data key not found")
+	ErrUnknownMasterKey    = errors.New("unknown master key")
+)
+
+// FirstId sorts before every UUID, listing after it starts from the beginning
+const FirstId = "00000000-0000-0000-0000-000000000000"
+
+// Tables whose content column holds message content
+const (
+	TableUserMessages  = "user_messages"
+	TableModelMessages = "model_messages"
+)
+
+// Cipher encrypts message content for storage. The data key ID returned by Encrypt is stored next to the content
+// in content_key_id and handed back to Decrypt, content without a key ID is plaintext and read back as it is
+type Cipher interface {
+	// Encrypt returns the content to store and the ID of the data key it is encrypted with, nil when it is stored in plaintext
+	Encrypt(ctx context.Context, userId, plaintext string) (string, *string, error)
+	Decrypt(ctx context.Context, content string, keyId *string) (string, error)
+}
+
+type KeyStorage interface {
+	// GetLatestDataKeyStorage returns the user's data key with the highest version, nil when the user has none
+	GetLatestDataKeyStorage(ctx context.Context, userId string) (*DataKeyData, error)
+	GetDataKeyStorage(ctx context.Context, keyId string) (*DataKeyData, error)
+	// CreateDataKeyStorage reports false when the user already has a data key of that version
+	CreateDataKeyStorage(ctx context.Context, data DataKeyData) (bool, error)
+	// ListDataKeysStorage returns up to limit data keys not wrapped by masterKeyId, ordered by key ID after afterKeyId
+	ListDataKeysStorage(ctx context.Context, masterKeyId, afterKeyId string, limit int) ([]DataKeyData, error)
+	UpdateWrappedKeyStorage(ctx context.Context, keyId string, wrappedKey []byte, masterKeyId string) error
+	ListKeyOwnersStorage(ctx context.Context, afterUserId string, limit int) ([]string, error)
+}
+
+type MessageStorage interface {
+	// ListMessagesStorage returns up to limit messages of table ordered by message ID after afterMessageId
+	ListMessagesStorage(ctx context.Context, table, afterMessageId string, limit int) ([]MessageData, error)
+	// UpdateContentStorage replaces the content of a message, it reports false when the content changed since it was read
+	UpdateContentStorage(ctx context.Context, table string, data MessageData, content, keyId string) (bool, error)
+}
+
+type DataKeyData struct {
+	KeyId       string    `db:"key_id"`
+	UserId      string    `db:"user_id"`
+	Version     int       `db:"version"`
+	WrappedKey  []byte    `db:"wrapped_key"`
+	MasterKeyId string    `db:"master_key_id"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type MessageData struct {
+	MessageId string  `db:"message_id"`
+	UserId    string  `db:"user_id"`
+	Content   string  `db:"content"`
+	KeyId     *string `db:"content_key_id"`
+}
+
+// MigrationResult counts the messages a migration looked at
+type MigrationResult struct {
+	Scanned   int64
+	Encrypted int64
+	Skipped   int64
+	Conflicts int64
+}
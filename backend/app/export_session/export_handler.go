@@ -0,0 +1,63 @@
+package exportsession
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   ExportService
+	validator *validator.Validate
+}
+
+func NewHandler(service ExportService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+func (h *Handler) ExportSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req ExportSessionRequest
+
+	req.UserID = c.GetString("userId")
+	req.SessionID = c.Param("sessionID")
+	req.Format = c.DefaultQuery("format", FormatPDF)
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	file, err := h.service.ExportSessionService(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			logger.Error("session not found for export", "sessionId", req.SessionID)
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
+		logger.Error("error while export session : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+file.FileName+`"`)
+	c.Data(http.StatusOK, file.ContentType, file.Content)
+}
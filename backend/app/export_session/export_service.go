@@ -0,0 +1,118 @@
+package exportsession
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/citation"
+	messageshistory "github.com/PatiharnKam/AiLaw/app/messages_history"
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+const disclaimer = "เอกสารนี้จัดทำโดยระบบปัญญาประดิษฐ์ AiLaw เพื่อใช้ประกอบการค้นคว้าเท่านั้น ไม่ถือเป็นคำปรึกษาทางกฎหมาย " +
+	"โปรดตรวจสอบตัวบทกฎหมายและปรึกษาผู้เชี่ยวชาญก่อนนำไปใช้ / This document was generated by AiLaw for research purposes only " +
+	"and does not constitute legal advice."
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// bangkok is fixed so exports do not depend on tzdata being installed in the container
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+type Service struct {
+	cfg            *config.Export
+	storage        ExportStorage
+	messageStorage messageshistory.MessageStorage
+}
+
+func NewService(cfg *config.Export, storage ExportStorage, messageStorage messageshistory.MessageStorage) *Service {
+	return &Service{
+		cfg:            cfg,
+		storage:        storage,
+		messageStorage: messageStorage,
+	}
+}
+
+func (s *Service) ExportSessionService(ctx context.Context, req ExportSessionRequest) (*ExportFile, error) {
+	session, err := s.storage.GetSessionStorage(ctx, req.UserID, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session in storage error : %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	messages, err := s.messageStorage.GetMessageHistoryStorage(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message history in storage error : %w", err)
+	}
+
+	doc := ExportDocument{
+		SessionId:  session.SessionId,
+		Title:      session.Title,
+		CreatedAt:  session.CreatedAt,
+		ExportedAt: time.Now(),
+		Messages:   []ExportMessage{},
+		Disclaimer: disclaimer,
+	}
+	for _, data := range messageshistory.ActiveBranch(messages) {
+		message := ExportMessage{
+			Role:      data.Role,
+			Content:   data.Content,
+			CreatedAt: data.CreatedAt,
+			ModelType: data.ModelType,
+		}
+		if data.Role == "model" {
+			message.CitedSections = citation.ExtractSections(data.Content)
+		}
+		doc.Messages = append(doc.Messages, message)
+	}
+
+	file := ExportFile{
+		FileName: fmt.Sprintf("ailaw-%s.%s", session.SessionId, req.Format),
+	}
+	switch req.Format {
+	case FormatPDF:
+		file.ContentType = "application/pdf"
+		file.Content, err = renderPDF(doc, s.cfg.PDFFontPath)
+	case FormatDOCX:
+		file.ContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		file.Content, err = renderDOCX(doc)
+	case FormatMarkdown:
+		file.ContentType = "text/markdown; charset=utf-8"
+		file.Content = renderMarkdown(doc)
+	case FormatJSON:
+		file.ContentType = "application/json; charset=utf-8"
+		file.Content, err = json.MarshalIndent(doc, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", req.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s export error : %w", req.Format, err)
+	}
+
+	return &file, nil
+}
+
+func roleLabel(message ExportMessage) string {
+	if message.Role == "user" {
+		return "ผู้ใช้ (User)"
+	}
+	if message.ModelType != nil && *message.ModelType != "" {
+		return fmt.Sprintf("AiLaw (%s)", *message.ModelType)
+	}
+	return "AiLaw"
+}
+
+func formatTime(t time.Time) string {
+	return t.In(bangkok).Format("02/01/2006 15:04")
+}
+
+func documentTitle(doc ExportDocument) string {
+	if doc.Title == "" {
+		return "AiLaw Chat"
+	}
+	return doc.Title
+}
@@ -0,0 +1,41 @@
+package exportsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) GetSessionStorage(ctx context.Context, userId, sessionId string) (*SessionData, error) {
+	query := `
+		SELECT session_id, title, created_at
+		FROM chat_sessions
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+	`
+
+	var data SessionData
+	err := s.db.QueryRow(ctx, query, userId, sessionId).Scan(
+		&data.SessionId,
+		&data.Title,
+		&data.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query session: %w", err)
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,189 @@
+package exportsession
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wrapText breaks text into lines no wider than maxWidth. fpdf's MultiCell only breaks lines at spaces,
+// but Thai is written without spaces between words, so inside Thai text a line may also break between
+// grapheme clusters. Without a dictionary the words are not known, so a break where a syllable starts or
+// ends is preferred over one between any two clusters. A cluster wider than a whole line is put on a line of its own
+func wrapText(text string, maxWidth float64, width func(string) float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		lines = append(lines, wrapParagraph(graphemeClusters(paragraph), maxWidth, width)...)
+	}
+	return lines
+}
+
+// How good a place between two clusters is to break a line at
+const (
+	noBreak = iota
+	clusterBreak
+	syllableBreak
+)
+
+func wrapParagraph(clusters []string, maxWidth float64, width func(string) float64) []string {
+	widths := make([]float64, len(clusters))
+	for i, cluster := range clusters {
+		widths[i] = width(cluster)
+	}
+
+	var lines []string
+	start, lastBreak, lastSyllableBreak := 0, -1, -1
+	lineWidth, syllableBreakWidth := 0.0, 0.0
+	for i := 0; i < len(clusters); i++ {
+		if i > start {
+			switch breakQuality(clusters, i) {
+			case syllableBreak:
+				lastBreak, lastSyllableBreak = i, i
+				syllableBreakWidth = lineWidth
+			case clusterBreak:
+				lastBreak = i
+			}
+		}
+		// spaces may run past the edge, they are dropped at the end of the line
+		if i > start && lineWidth+widths[i] > maxWidth && !isSpace(clusters[i]) {
+			end := lastBreak
+			// a line cut at a syllable is preferred unless it would leave the line less than a third full
+			if lastSyllableBreak > start && syllableBreakWidth >= maxWidth/3 {
+				end = lastSyllableBreak
+			}
+			if end <= start {
+				end = i
+			}
+			lines = append(lines, strings.TrimRightFunc(strings.Join(clusters[start:end], ""), unicode.IsSpace))
+
+			start = end
+			for start < len(clusters) && isSpace(clusters[start]) {
+				start++
+			}
+			lastBreak, lastSyllableBreak = -1, -1
+			lineWidth = 0
+			i = start - 1
+			continue
+		}
+		lineWidth += widths[i]
+	}
+	return append(lines, strings.TrimRightFunc(strings.Join(clusters[start:], ""), unicode.IsSpace))
+}
+
+// graphemeClusters splits text into base characters followed by their combining marks,
+// such as a Thai consonant with its above vowel and tone mark
+func graphemeClusters(text string) []string {
+	var clusters []string
+	for _, r := range text {
+		n := len(clusters)
+		if n > 0 && unicode.In(r, unicode.Mn, unicode.Me) && !isSpace(clusters[n-1]) {
+			clusters[n-1] += string(r)
+			continue
+		}
+		clusters = append(clusters, string(r))
+	}
+	return clusters
+}
+
+// breakQuality tells whether a line may break before clusters[i]
+func breakQuality(clusters []string, i int) int {
+	prev, next := clusters[i-1], clusters[i]
+	if isSpace(next) {
+		return noBreak
+	}
+	if isSpace(prev) {
+		return syllableBreak
+	}
+
+	p, n := baseRune(prev), baseRune(next)
+	if !isThaiLetter(p) || !isThaiLetter(n) {
+		return noBreak
+	}
+	switch {
+	// เ แ โ ใ ไ are written before the consonant they follow in speech
+	case isLeadingVowel(p):
+		return noBreak
+	// mai han-akat and mai taikhu always take a final consonant
+	case strings.ContainsAny(prev, "ั็"):
+		return noBreak
+	// the vowels เ-ีย and เ-ือ end with ย and อ
+	case strings.ContainsAny(prev, "ีื") && (n == 'ย' || n == 'อ'):
+		return noBreak
+	// ะ า ำ ๅ ๆ ฯ end the syllable before them
+	case strings.ContainsRune("ะาำๅๆฯ", n):
+		return noBreak
+	// a letter under thanthakhat is silent and stays with the syllable before it
+	case strings.ContainsRune(next, '์'):
+		return noBreak
+	// these never end a syllable, a consonant after them belongs to the same one as in หรือ or ของ
+	case strings.ContainsRune("หอฉผฝฌฮ", p) && !strings.ContainsAny(prev, "ัิีึืุู็่้๊๋") && isThaiConsonant(n):
+		return noBreak
+	// อ after a consonant without a vowel is the vowel of that consonant
+	case n == 'อ' && isThaiConsonant(p) && !strings.ContainsAny(prev, "ัิีึืุู็์"):
+		return noBreak
+	}
+
+	if endsSyllable(clusters, i-1) || startsSyllable(clusters, i) {
+		return syllableBreak
+	}
+	return clusterBreak
+}
+
+// endsSyllable reports whether no final consonant can follow clusters[j], after ะ and ำ, a silent letter
+// or a consonant written after ใ and ไ
+func endsSyllable(clusters []string, j int) bool {
+	if strings.ContainsRune("ะำๆฯ", baseRune(clusters[j])) || strings.ContainsRune(clusters[j], '์') {
+		return true
+	}
+	return j >= 1 && isThaiConsonant(baseRune(clusters[j])) && strings.ContainsRune("ใไ", baseRune(clusters[j-1]))
+}
+
+// startsSyllable reports whether clusters[i] is a leading vowel or a consonant with its own vowel,
+// that is not the second consonant of a cluster such as ปร or ทร
+func startsSyllable(clusters []string, i int) bool {
+	n := baseRune(clusters[i])
+	if isLeadingVowel(n) {
+		return true
+	}
+	hasVowel := strings.ContainsAny(clusters[i], "ัิีึืุู็") ||
+		i+1 < len(clusters) && strings.ContainsRune("ะาำ", baseRune(clusters[i+1]))
+	if !isThaiConsonant(n) || !hasVowel {
+		return false
+	}
+
+	// a consonant without a vowel before it is only a syllable of its own ending when it is a final consonant
+	if prev := clusters[i-1]; isThaiConsonant(baseRune(prev)) && !strings.ContainsAny(prev, "ัิีึืุู็") {
+		return i >= 2 && hasVowelBefore(clusters, i-1)
+	}
+	return true
+}
+
+// hasVowelBefore reports whether the consonant clusters[j] follows a vowel, which makes it a final consonant
+func hasVowelBefore(clusters []string, j int) bool {
+	prev := clusters[j-1]
+	if strings.ContainsAny(prev, "ัิีึืุู็") || baseRune(prev) == 'า' {
+		return true
+	}
+	return j >= 2 && isThaiConsonant(baseRune(prev)) && isLeadingVowel(baseRune(clusters[j-2]))
+}
+
+func baseRune(cluster string) rune {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return r
+}
+
+func isLeadingVowel(r rune) bool {
+	return r >= 'เ' && r <= 'ไ'
+}
+
+func isThaiConsonant(r rune) bool {
+	return r >= 'ก' && r <= 'ฮ'
+}
+
+func isThaiLetter(r rune) bool {
+	return r >= 'ก' && r <= '๎'
+}
+
+func isSpace(cluster string) bool {
+	return unicode.IsSpace(baseRune(cluster))
+}
@@ -0,0 +1,140 @@
+package exportsession
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// runeWidth gives every character a width of 1 and combining marks no width, like a Thai TrueType font
+func runeWidth(s string) float64 {
+	var width float64
+	for _, r := range s {
+		if !unicode.In(r, unicode.Mn, unicode.Me) {
+			width++
+		}
+	}
+	return width
+}
+
+const thaiSample = "ผู้ใดทำให้เสียหายทำลายทำให้เสื่อมค่าหรือทำให้ไร้ประโยชน์ซึ่งทรัพย์ของผู้อื่นหรือที่ผู้อื่นเป็นเจ้าของรวมอยู่ด้วยผู้นั้นกระทำความผิดฐานทำให้เสียทรัพย์"
+
+func TestWrapTextThai(t *testing.T) {
+	for _, maxWidth := range []float64{5, 8, 13, 20, 31} {
+		lines := wrapText(thaiSample, maxWidth, runeWidth)
+		if len(lines) < 2 {
+			t.Fatalf("maxWidth %v: got %d line, want the sample wrapped", maxWidth, len(lines))
+		}
+		if got := strings.Join(lines, ""); got != thaiSample {
+			t.Fatalf("maxWidth %v: lines join to %q, want the sample unchanged", maxWidth, got)
+		}
+
+		for i, line := range lines {
+			if width := runeWidth(line); width > maxWidth {
+				t.Errorf("maxWidth %v: line %q is %v wide", maxWidth, line, width)
+			}
+			if i == 0 {
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(line)
+			last, _ := utf8.DecodeLastRuneInString(lines[i-1])
+			if unicode.In(first, unicode.Mn, unicode.Me) {
+				t.Errorf("maxWidth %v: line %q starts with a combining mark", maxWidth, line)
+			}
+			if strings.ContainsRune("ะาำๅๆฯ", first) {
+				t.Errorf("maxWidth %v: line %q starts with a following vowel", maxWidth, line)
+			}
+			if last >= 'เ' && last <= 'ไ' {
+				t.Errorf("maxWidth %v: line %q ends with a leading vowel", maxWidth, lines[i-1])
+			}
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth float64
+		want     []string
+	}{
+		{
+			name:     "fits on one line",
+			text:     "มาตรา 358",
+			maxWidth: 20,
+			want:     []string{"มาตรา 358"},
+		},
+		{
+			name:     "latin words break at spaces",
+			text:     "the quick brown fox",
+			maxWidth: 10,
+			want:     []string{"the quick", "brown fox"},
+		},
+		{
+			name:     "thai breaks at a space",
+			text:     "ทำให้ เสียทรัพย์",
+			maxWidth: 8,
+			want:     []string{"ทำให้", "เสียทรัพย์"},
+		},
+		{
+			name:     "thai breaks at a syllable without spaces",
+			text:     "ทำให้เสียทรัพย์",
+			maxWidth: 8,
+			want:     []string{"ทำให้", "เสียทรัพย์"},
+		},
+		{
+			name:     "thai breaks after a silent letter",
+			text:     "ไร้ประโยชน์ซึ่งทรัพย์",
+			maxWidth: 10,
+			want:     []string{"ไร้ประโยชน์", "ซึ่งทรัพย์"},
+		},
+		{
+			name:     "thai keeps a leading ห with its consonant",
+			text:     "ค่าหรือทำให้",
+			maxWidth: 5,
+			want:     []string{"ค่า", "หรือทำ", "ให้"},
+		},
+		{
+			name:     "word longer than a line is broken between characters",
+			text:     "abcdefghij",
+			maxWidth: 4,
+			want:     []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:     "thai digits stay together",
+			text:     "มาตรา ๓๕๘",
+			maxWidth: 6,
+			want:     []string{"มาตรา", "๓๕๘"},
+		},
+		{
+			name:     "paragraphs and blank lines are kept",
+			text:     "ก\r\n\nข",
+			maxWidth: 10,
+			want:     []string{"ก", "", "ข"},
+		},
+		{
+			name:     "spaces at a break are dropped",
+			text:     "aaaa    bbbb",
+			maxWidth: 5,
+			want:     []string{"aaaa", "bbbb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.maxWidth, runeWidth)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("wrapText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphemeClusters(t *testing.T) {
+	got := graphemeClusters("ผู้ที่ ศัพท์")
+	want := []string{"ผู้", "ที่", " ", "ศั", "พ", "ท์"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("graphemeClusters() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package exportsession
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+)
+
+type ExportService interface {
+	ExportSessionService(ctx context.Context, req ExportSessionRequest) (*ExportFile, error)
+}
+
+type ExportStorage interface {
+	GetSessionStorage(ctx context.Context, userId, sessionId string) (*SessionData, error)
+}
+
+type ExportSessionRequest struct {
+	UserID    string `json:"userId" validate:"required"`
+	SessionID string `json:"sessionID" validate:"required,uuid4"`
+	Format    string `json:"format" validate:"required,oneof=pdf docx md json"`
+}
+
+type SessionData struct {
+	SessionId string    `db:"session_id"`
+	Title     string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type ExportFile struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+type ExportDocument struct {
+	SessionId  string          `json:"sessionId"`
+	Title      string          `json:"title"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Messages   []ExportMessage `json:"messages"`
+	Disclaimer string          `json:"disclaimer"`
+}
+
+type ExportMessage struct {
+	Role          string    `json:"role"`
+	Content       string    `json:"content"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ModelType     *string   `json:"modelType,omitempty"`
+	CitedSections []string  `json:"citedSections,omitempty"`
+}
@@ -0,0 +1,93 @@
+package exportsession
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const docxFont = "TH Sarabun New"
+
+// renderDOCX writes a minimal WordprocessingML package, only document.xml is needed for Word and LibreOffice to open it
+func renderDOCX(doc ExportDocument) ([]byte, error) {
+	var body strings.Builder
+
+	writeDOCXParagraph(&body, documentTitle(doc), 36, true, false)
+	writeDOCXParagraph(&body, "Session ID: "+doc.SessionId, 24, false, false)
+	writeDOCXParagraph(&body, "Created: "+formatTime(doc.CreatedAt), 24, false, false)
+	writeDOCXParagraph(&body, "Exported: "+formatTime(doc.ExportedAt), 24, false, false)
+
+	for _, message := range doc.Messages {
+		writeDOCXParagraph(&body, "", 24, false, false)
+		writeDOCXParagraph(&body, fmt.Sprintf("%s · %s", roleLabel(message), formatTime(message.CreatedAt)), 28, true, false)
+		for _, line := range strings.Split(strings.TrimSpace(message.Content), "\n") {
+			writeDOCXParagraph(&body, line, 28, false, false)
+		}
+		if len(message.CitedSections) > 0 {
+			writeDOCXParagraph(&body, "มาตราที่อ้างถึง: "+strings.Join(message.CitedSections, ", "), 28, false, true)
+		}
+	}
+
+	writeDOCXParagraph(&body, "", 24, false, false)
+	writeDOCXParagraph(&body, doc.Disclaimer, 20, false, true)
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body.String() +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440"/></w:sectPr></w:body></w:document>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/document.xml", document},
+	}
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", file.name, err)
+		}
+		if _, err := w.Write([]byte(file.content)); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing docx archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeDOCXParagraph appends a single-run paragraph, size is in half-points
+func writeDOCXParagraph(b *strings.Builder, text string, size int, bold, italic bool) {
+	b.WriteString("<w:p><w:r><w:rPr>")
+	fmt.Fprintf(b, `<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, docxFont)
+	if bold {
+		b.WriteString("<w:b/><w:bCs/>")
+	}
+	if italic {
+		b.WriteString("<w:i/><w:iCs/>")
+	}
+	fmt.Fprintf(b, `<w:sz w:val="%[1]d"/><w:szCs w:val="%[1]d"/>`, size)
+	b.WriteString(`</w:rPr><w:t xml:space="preserve">`)
+	xml.EscapeText(b, []byte(text))
+	b.WriteString("</w:t></w:r></w:p>")
+}
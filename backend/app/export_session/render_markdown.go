@@ -0,0 +1,30 @@
+package exportsession
+
+import (
+	"fmt"
+	"strings"
+)
+
+func renderMarkdown(doc ExportDocument) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", documentTitle(doc))
+	fmt.Fprintf(&b, "- Session ID: `%s`\n", doc.SessionId)
+	fmt.Fprintf(&b, "- Created: %s\n", formatTime(doc.CreatedAt))
+	fmt.Fprintf(&b, "- Exported: %s\n\n", formatTime(doc.ExportedAt))
+
+	for _, message := range doc.Messages {
+		b.WriteString("---\n\n")
+		fmt.Fprintf(&b, "### %s · %s\n\n", roleLabel(message), formatTime(message.CreatedAt))
+		b.WriteString(strings.TrimSpace(message.Content))
+		b.WriteString("\n\n")
+		if len(message.CitedSections) > 0 {
+			fmt.Fprintf(&b, "**มาตราที่อ้างถึง:** %s\n\n", strings.Join(message.CitedSections, ", "))
+		}
+	}
+
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "> %s\n", doc.Disclaimer)
+
+	return []byte(b.String())
+}
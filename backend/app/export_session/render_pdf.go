@@ -0,0 +1,89 @@
+package exportsession
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFontFamily   = "thai"
+	pdfFooterMargin = 30.0
+)
+
+// renderPDF lays out the conversation on A4 pages with the disclaimer repeated in every page footer.
+// Thai glyphs are not part of the PDF core fonts so a UTF-8 TrueType font has to be provided through config.
+// fpdf does no Thai shaping, the marks are drawn where the font places them by default
+func renderPDF(doc ExportDocument, fontPath string) ([]byte, error) {
+	fontBytes, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pdf font %q: %w", fontPath, err)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", fontBytes)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, pdfFooterMargin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfFooterMargin + 5)
+		pdf.SetFont(pdfFontFamily, "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		multiCell(pdf, 4, doc.Disclaimer, "T")
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont(pdfFontFamily, "", 18)
+	multiCell(pdf, 9, documentTitle(doc), "")
+
+	pdf.SetFont(pdfFontFamily, "", 10)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.MultiCell(0, 5, "Session ID: "+doc.SessionId, "", "L", false)
+	pdf.MultiCell(0, 5, "Created: "+formatTime(doc.CreatedAt)+"    Exported: "+formatTime(doc.ExportedAt), "", "L", false)
+	pdf.Ln(4)
+
+	for _, message := range doc.Messages {
+		pdf.SetFont(pdfFontFamily, "", 11)
+		if message.Role == "user" {
+			pdf.SetTextColor(30, 64, 175)
+		} else {
+			pdf.SetTextColor(4, 120, 87)
+		}
+		pdf.MultiCell(0, 6, fmt.Sprintf("%s · %s", roleLabel(message), formatTime(message.CreatedAt)), "B", "L", false)
+		pdf.Ln(1)
+
+		pdf.SetFont(pdfFontFamily, "", 12)
+		pdf.SetTextColor(0, 0, 0)
+		multiCell(pdf, 6.5, strings.TrimSpace(message.Content), "")
+
+		if len(message.CitedSections) > 0 {
+			pdf.SetFont(pdfFontFamily, "", 10)
+			pdf.SetTextColor(90, 90, 90)
+			multiCell(pdf, 5, "มาตราที่อ้างถึง: "+strings.Join(message.CitedSections, ", "), "")
+		}
+		pdf.Ln(4)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("error writing pdf: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// multiCell writes text across the width left on the page, with the lines broken by wrapText
+// since MultiCell alone would only break them at spaces
+func multiCell(pdf *fpdf.Fpdf, h float64, text, border string) {
+	pageWidth, _ := pdf.GetPageSize()
+	_, _, rightMargin, _ := pdf.GetMargins()
+	maxWidth := pageWidth - rightMargin - pdf.GetX() - 2*pdf.GetCellMargin()
+
+	lines := wrapText(text, maxWidth, pdf.GetStringWidth)
+	pdf.MultiCell(0, h, strings.Join(lines, "\n"), border, "L", false)
+}
@@ -1,10 +1,11 @@
 package feedback
 
 import (
-	"log/slog"
+	"errors"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -22,7 +23,7 @@ func NewHandler(service FeedbackService) *Handler {
 }
 
 func (h *Handler) FeedbackHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req FeedbackRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("invalid request body : " + err.Error())
@@ -32,6 +33,7 @@ func (h *Handler) FeedbackHandler(c *gin.Context) {
 		})
 		return
 	}
+	req.UserID = c.GetString("userId")
 	req.MessageID = c.Param("messageID")
 
 	if err := h.validator.Struct(req); err != nil {
@@ -47,6 +49,13 @@ func (h *Handler) FeedbackHandler(c *gin.Context) {
 	err := h.service.FeedbackService(ctx, req)
 	if err != nil {
 		logger.Error("error while update feedback : " + err.Error())
+		if errors.Is(err, ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, app.Response{
 			Code:    app.InternalServerErrorCode,
 			Message: app.InternalServerErrorMessage,
@@ -59,3 +68,84 @@ func (h *Handler) FeedbackHandler(c *gin.Context) {
 		Message: app.SUCCESS_MSG,
 	})
 }
+
+func (h *Handler) PreferredAnswerHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := PreferredAnswerRequest{
+		UserID:    c.GetString("userId"),
+		MessageID: c.Param("messageID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.PreferredAnswerService(ctx, req)
+	if err != nil {
+		logger.Error("error while mark preferred answer : " + err.Error())
+		if errors.Is(err, ErrCompareAnswerNotFound) {
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) FeedbackHistoryHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := FeedbackHistoryRequest{
+		UserID:    c.GetString("userId"),
+		MessageID: c.Param("messageID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.FeedbackHistoryService(ctx, req)
+	if err != nil {
+		logger.Error("error while get feedback history : " + err.Error())
+		if errors.Is(err, ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
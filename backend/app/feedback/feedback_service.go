@@ -3,22 +3,83 @@ package feedback
 import (
 	"context"
 	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
 )
 
 type Service struct {
 	storage FeedbackStorage
+	audit   audit.AuditService
 }
 
-func NewService(storage FeedbackStorage) *Service {
+func NewService(storage FeedbackStorage, auditService audit.AuditService) *Service {
 	return &Service{
 		storage: storage,
+		audit:   auditService,
 	}
 }
 
 func (s *Service) FeedbackService(ctx context.Context, req FeedbackRequest) error {
-	err := s.storage.FeedbackStorage(ctx, req)
+	previous, err := s.storage.FeedbackStorage(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to update message feedback error : %w", err)
 	}
+	metrics.FeedbackSubmitted(req.Feedback)
+
+	categories := req.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserID,
+		Action:     audit.ActionFeedbackUpdate,
+		TargetType: audit.TargetMessage,
+		TargetId:   req.MessageID,
+		Before:     previous,
+		After: FeedbackState{
+			Feedback:       req.Feedback,
+			FeedbackDetail: req.FeedbackDetail,
+			Categories:     categories,
+			Severity:       req.Severity,
+		},
+	})
 	return nil
 }
+
+func (s *Service) PreferredAnswerService(ctx context.Context, req PreferredAnswerRequest) error {
+	err := s.storage.PreferredAnswerStorage(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to mark preferred answer error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserID,
+		Action:     audit.ActionFeedbackPreferred,
+		TargetType: audit.TargetMessage,
+		TargetId:   req.MessageID,
+	})
+	return nil
+}
+
+func (s *Service) FeedbackHistoryService(ctx context.Context, req FeedbackHistoryRequest) ([]FeedbackHistoryResponse, error) {
+	dataList, err := s.storage.FeedbackHistoryStorage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback history in storage error : %w", err)
+	}
+
+	resp := make([]FeedbackHistoryResponse, 0, len(dataList))
+	for _, data := range dataList {
+		resp = append(resp, FeedbackHistoryResponse{
+			FeedbackId:          data.FeedbackId,
+			MessageId:           data.MessageId,
+			Feedback:            data.Rating,
+			Categories:          data.Categories,
+			Severity:            data.Severity,
+			SuggestedCorrection: data.SuggestedCorrection,
+			FeedbackDetail:      data.Detail,
+			CreatedAt:           data.CreatedAt,
+		})
+	}
+	return resp, nil
+}
@@ -2,34 +2,238 @@ package feedback
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Storage struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	cipher encryption.Cipher
 }
 
-func NewStorage(db *pgxpool.Pool) *Storage {
-	return &Storage{db: db}
+func NewStorage(db *pgxpool.Pool, cipher encryption.Cipher) *Storage {
+	return &Storage{db: db, cipher: cipher}
 }
 
-func (s *Storage) FeedbackStorage(ctx context.Context, req FeedbackRequest) error {
+// FeedbackStorage updates the latest feedback of an answer and appends the change to message_feedback
+func (s *Storage) FeedbackStorage(ctx context.Context, req FeedbackRequest) (FeedbackState, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return FeedbackState{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var previous FeedbackState
+	err = tx.QueryRow(ctx, `
+		SELECT m.feedback, m.feedback_detail, m.feedback_categories, m.feedback_severity
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE m.message_id = $1 AND s.user_id = $2 AND s.deleted_at IS NULL
+		FOR UPDATE OF m
+	`, req.MessageID, req.UserID).Scan(&previous.Feedback, &previous.FeedbackDetail, &previous.Categories, &previous.Severity)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FeedbackState{}, ErrMessageNotFound
+		}
+		return FeedbackState{}, err
+	}
+
+	categories := req.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+	now := time.Now()
+
 	query := `
-		UPDATE model_messages
+		UPDATE model_messages m
 		SET feedback = $1,
-			feedback_detail = $2
-		WHERE message_id = $3
+			feedback_detail = $2,
+			feedback_categories = $3,
+			feedback_severity = $4,
+			feedback_at = $5
+		FROM chat_sessions s
+		WHERE m.message_id = $6
+			AND s.session_id = m.session_id
+			AND s.user_id = $7
+			AND s.deleted_at IS NULL
 	`
-	rows, err := s.db.Exec(ctx, query, &req.Feedback, &req.FeedbackDetail, req.MessageID)
+	rows, err := tx.Exec(ctx, query, req.Feedback, req.FeedbackDetail, categories, req.Severity, now, req.MessageID, req.UserID)
 	if err != nil {
-		return err
+		return FeedbackState{}, err
 	}
 
 	if rows.RowsAffected() != 1 {
-		return fmt.Errorf("no rows were updated")
+		return FeedbackState{}, ErrMessageNotFound
+	}
+
+	err = insertFeedbackHistory(ctx, tx, FeedbackHistoryData{
+		FeedbackId:          uuid.NewString(),
+		MessageId:           req.MessageID,
+		Rating:              req.Feedback,
+		Categories:          categories,
+		Severity:            req.Severity,
+		SuggestedCorrection: req.SuggestedCorrection,
+		Detail:              req.FeedbackDetail,
+		CreatedAt:           now,
+	}, req.UserID)
+	if err != nil {
+		return FeedbackState{}, err
+	}
+
+	return previous, tx.Commit(ctx)
+}
+
+func insertFeedbackHistory(ctx context.Context, tx pgx.Tx, data FeedbackHistoryData, userId string) error {
+	query := `INSERT INTO message_feedback
+				(feedback_id, message_id, user_id, rating, categories, severity, suggested_correction, detail, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := tx.Exec(ctx, query,
+		data.FeedbackId,
+		data.MessageId,
+		userId,
+		data.Rating,
+		data.Categories,
+		data.Severity,
+		data.SuggestedCorrection,
+		data.Detail,
+		data.CreatedAt,
+	)
+	return err
+}
+
+func (s *Storage) FeedbackHistoryStorage(ctx context.Context, req FeedbackHistoryRequest) ([]FeedbackHistoryData, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM model_messages m
+			JOIN chat_sessions s ON s.session_id = m.session_id
+			WHERE m.message_id = $1 AND s.user_id = $2 AND s.deleted_at IS NULL
+		)
+	`, req.MessageID, req.UserID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	query := `
+		SELECT
+			feedback_id,
+			message_id,
+			rating,
+			categories,
+			severity,
+			suggested_correction,
+			detail,
+			created_at
+		FROM message_feedback
+		WHERE message_id = $1
+		ORDER BY created_at DESC;
+	`
+
+	rows, err := s.db.Query(ctx, query, req.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []FeedbackHistoryData{}
+
+	for rows.Next() {
+		var data FeedbackHistoryData
+		err := rows.Scan(
+			&data.FeedbackId,
+			&data.MessageId,
+			&data.Rating,
+			&data.Categories,
+			&data.Severity,
+			&data.SuggestedCorrection,
+			&data.Detail,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+// PreferredAnswerStorage marks one answer of a compare group as preferred, records it as positive feedback
+// and makes it the active branch of its user message
+func (s *Storage) PreferredAnswerStorage(ctx context.Context, req PreferredAnswerRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var compareGroupId string
+	var parentMessageId *string
+	query := `
+		SELECT m.compare_group_id, m.parent_message_id
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE m.message_id = $1
+			AND s.user_id = $2
+			AND s.deleted_at IS NULL
+			AND m.compare_group_id IS NOT NULL
+	`
+	err = tx.QueryRow(ctx, query, req.MessageID, req.UserID).Scan(&compareGroupId, &parentMessageId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCompareAnswerNotFound
+		}
+		return err
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(ctx, `
+		UPDATE model_messages
+		SET preferred = (message_id = $1),
+			feedback = CASE WHEN message_id = $1 THEN 1 ELSE feedback END,
+			feedback_at = CASE WHEN message_id = $1 THEN $3 ELSE feedback_at END
+		WHERE compare_group_id = $2
+	`, req.MessageID, compareGroupId, now)
+	if err != nil {
+		return err
+	}
+
+	rating := 1
+	err = insertFeedbackHistory(ctx, tx, FeedbackHistoryData{
+		FeedbackId: uuid.NewString(),
+		MessageId:  req.MessageID,
+		Rating:     &rating,
+		Categories: []string{},
+		CreatedAt:  now,
+	}, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	if parentMessageId != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE model_messages
+			SET is_active = (message_id = $1)
+			WHERE parent_message_id = $2
+		`, req.MessageID, *parentMessageId)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
@@ -1,17 +1,128 @@
 package feedback
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrCompareAnswerNotFound = errors.New("compare answer not found")
+	ErrMessageNotFound       = errors.New("message not found")
+)
 
 type FeedbackService interface {
 	FeedbackService(ctx context.Context, req FeedbackRequest) error
+	PreferredAnswerService(ctx context.Context, req PreferredAnswerRequest) error
+	FeedbackHistoryService(ctx context.Context, req FeedbackHistoryRequest) ([]FeedbackHistoryResponse, error)
+	ReviewQueueService(ctx context.Context, req ReviewQueueRequest) (ReviewQueueResponse, error)
 }
 
 type FeedbackStorage interface {
-	FeedbackStorage(ctx context.Context, req FeedbackRequest) error
+	// FeedbackStorage returns the feedback the answer had before the update
+	FeedbackStorage(ctx context.Context, req FeedbackRequest) (FeedbackState, error)
+	PreferredAnswerStorage(ctx context.Context, req PreferredAnswerRequest) error
+	FeedbackHistoryStorage(ctx context.Context, req FeedbackHistoryRequest) ([]FeedbackHistoryData, error)
+	ReviewQueueStorage(ctx context.Context, req ReviewQueueRequest) ([]ReviewItemData, int, error)
 }
 
+// Categories a user can attach to a rating
+const (
+	CategoryWrongLawSection      = "wrong_law_section"
+	CategoryOutdated             = "outdated"
+	CategoryHallucinatedCitation = "hallucinated_citation"
+	CategoryUnclear              = "unclear"
+	CategoryIncomplete           = "incomplete"
+	CategoryOther                = "other"
+)
+
 type FeedbackRequest struct {
-	MessageID      string  `json:"messageID" validate:"required,uuid4"`
-	Feedback       *int    `json:"feedback" validate:"omitempty,oneof=1 -1"`
-	FeedbackDetail *string `json:"feedbackDetail"`
+	UserID              string   `json:"userId" validate:"required"`
+	MessageID           string   `json:"messageID" validate:"required,uuid4"`
+	Feedback            *int     `json:"feedback" validate:"omitempty,oneof=1 -1"`
+	FeedbackDetail      *string  `json:"feedbackDetail" validate:"omitempty,max=2000"`
+	Categories          []string `json:"categories" validate:"omitempty,max=6,unique,dive,oneof=wrong_law_section outdated hallucinated_citation unclear incomplete other"`
+	Severity            *string  `json:"severity" validate:"omitempty,oneof=low medium high critical"`
+	SuggestedCorrection *string  `json:"suggestedCorrection" validate:"omitempty,max=5000"`
+}
+
+type PreferredAnswerRequest struct {
+	UserID    string `json:"userId" validate:"required"`
+	MessageID string `json:"messageID" validate:"required,uuid4"`
+}
+
+type FeedbackHistoryRequest struct {
+	UserID    string `json:"userId" validate:"required"`
+	MessageID string `json:"messageID" validate:"required,uuid4"`
+}
+
+type FeedbackHistoryResponse struct {
+	FeedbackId          string    `json:"feedbackId"`
+	MessageId           string    `json:"messageId"`
+	Feedback            *int      `json:"feedback"`
+	Categories          []string  `json:"categories"`
+	Severity            *string   `json:"severity"`
+	SuggestedCorrection *string   `json:"suggestedCorrection"`
+	FeedbackDetail      *string   `json:"feedbackDetail"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// FeedbackState is the latest feedback of an answer, kept in the audit log before and after a change
+type FeedbackState struct {
+	Feedback       *int     `json:"feedback"`
+	FeedbackDetail *string  `json:"feedbackDetail"`
+	Categories     []string `json:"categories"`
+	Severity       *string  `json:"severity"`
+}
+
+type FeedbackHistoryData struct {
+	FeedbackId          string    `db:"feedback_id"`
+	MessageId           string    `db:"message_id"`
+	Rating              *int      `db:"rating"`
+	Categories          []string  `db:"categories"`
+	Severity            *string   `db:"severity"`
+	SuggestedCorrection *string   `db:"suggested_correction"`
+	Detail              *string   `db:"detail"`
+	CreatedAt           time.Time `db:"created_at"`
+}
+
+type ReviewQueueRequest struct {
+	Category *string `form:"category" validate:"omitempty,oneof=wrong_law_section outdated hallucinated_citation unclear incomplete other"`
+	Severity *string `form:"severity" validate:"omitempty,oneof=low medium high critical"`
+	Limit    int     `form:"limit" validate:"omitempty,min=1,max=100"`
+	Offset   int     `form:"offset" validate:"omitempty,min=0"`
+}
+
+type ReviewQueueResponse struct {
+	Total int                  `json:"total"`
+	Items []ReviewItemResponse `json:"items"`
+}
+
+type ReviewItemResponse struct {
+	MessageId           string    `json:"messageId"`
+	SessionId           string    `json:"sessionId"`
+	ModelType           *string   `json:"modelType"`
+	Question            *string   `json:"question"`
+	Answer              string    `json:"answer"`
+	Citations           []string  `json:"citations"`
+	Categories          []string  `json:"categories"`
+	Severity            *string   `json:"severity"`
+	SuggestedCorrection *string   `json:"suggestedCorrection"`
+	FeedbackDetail      *string   `json:"feedbackDetail"`
+	FeedbackAt          time.Time `json:"feedbackAt"`
+}
+
+type ReviewItemData struct {
+	MessageId           string    `db:"message_id"`
+	SessionId           string    `db:"session_id"`
+	ModelType           *string   `db:"model_type"`
+	Question            *string   `db:"question"`
+	Answer              string    `db:"content"`
+	Categories          []string  `db:"feedback_categories"`
+	Severity            *string   `db:"feedback_severity"`
+	SuggestedCorrection *string   `db:"suggested_correction"`
+	FeedbackDetail      *string   `db:"feedback_detail"`
+	FeedbackAt          time.Time `db:"feedback_at"`
+	QuestionKeyId       *string   `db:"question_key_id"`
+	AnswerKeyId         *string   `db:"content_key_id"`
 }
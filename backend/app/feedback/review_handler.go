@@ -0,0 +1,50 @@
+package feedback
+
+import (
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewQueueHandler lists negatively rated answers for the legal QA team
+func (h *Handler) ReviewQueueHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req ReviewQueueRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.ReviewQueueService(ctx, req)
+	if err != nil {
+		logger.Error("error while get review queue : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
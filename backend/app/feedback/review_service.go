@@ -0,0 +1,42 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/citation"
+)
+
+const defaultReviewQueueLimit = 20
+
+func (s *Service) ReviewQueueService(ctx context.Context, req ReviewQueueRequest) (ReviewQueueResponse, error) {
+	if req.Limit == 0 {
+		req.Limit = defaultReviewQueueLimit
+	}
+
+	dataList, total, err := s.storage.ReviewQueueStorage(ctx, req)
+	if err != nil {
+		return ReviewQueueResponse{}, fmt.Errorf("failed to get review queue in storage error : %w", err)
+	}
+
+	resp := ReviewQueueResponse{
+		Total: total,
+		Items: make([]ReviewItemResponse, 0, len(dataList)),
+	}
+	for _, data := range dataList {
+		resp.Items = append(resp.Items, ReviewItemResponse{
+			MessageId:           data.MessageId,
+			SessionId:           data.SessionId,
+			ModelType:           data.ModelType,
+			Question:            data.Question,
+			Answer:              data.Answer,
+			Citations:           citation.ExtractSections(data.Answer),
+			Categories:          data.Categories,
+			Severity:            data.Severity,
+			SuggestedCorrection: data.SuggestedCorrection,
+			FeedbackDetail:      data.FeedbackDetail,
+			FeedbackAt:          data.FeedbackAt,
+		})
+	}
+	return resp, nil
+}
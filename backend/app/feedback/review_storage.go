@@ -0,0 +1,120 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReviewQueueStorage lists negatively rated answers, newest feedback first, together with the question they answer
+func (s *Storage) ReviewQueueStorage(ctx context.Context, req ReviewQueueRequest) ([]ReviewItemData, int, error) {
+	conditions := "m.feedback = -1 AND s.deleted_at IS NULL"
+	args := []any{}
+
+	if req.Category != nil {
+		args = append(args, *req.Category)
+		conditions += fmt.Sprintf(" AND $%d = ANY(m.feedback_categories)", len(args))
+	}
+	if req.Severity != nil {
+		args = append(args, *req.Severity)
+		conditions += fmt.Sprintf(" AND m.feedback_severity = $%d", len(args))
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*)
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE ` + conditions
+	if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, req.Limit, req.Offset)
+	query := fmt.Sprintf(`
+		SELECT
+			m.message_id,
+			m.session_id,
+			m.model_type,
+			u.content AS question,
+			m.content,
+			m.feedback_categories,
+			m.feedback_severity,
+			f.suggested_correction,
+			m.feedback_detail,
+			COALESCE(m.feedback_at, m.created_at) AS feedback_at,
+			u.content_key_id AS question_key_id,
+			m.content_key_id
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		LEFT JOIN user_messages u ON u.message_id = m.parent_message_id
+		LEFT JOIN LATERAL (
+			SELECT suggested_correction
+			FROM message_feedback
+			WHERE message_id = m.message_id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) f ON TRUE
+		WHERE %s
+		ORDER BY feedback_at DESC
+		LIMIT $%d OFFSET $%d;
+	`, conditions, len(args)-1, len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	dataList := []ReviewItemData{}
+
+	for rows.Next() {
+		var data ReviewItemData
+		err := rows.Scan(
+			&data.MessageId,
+			&data.SessionId,
+			&data.ModelType,
+			&data.Question,
+			&data.Answer,
+			&data.Categories,
+			&data.Severity,
+			&data.SuggestedCorrection,
+			&data.FeedbackDetail,
+			&data.FeedbackAt,
+			&data.QuestionKeyId,
+			&data.AnswerKeyId,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range dataList {
+		if err := s.decryptReviewItem(ctx, &dataList[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return dataList, total, nil
+}
+
+func (s *Storage) decryptReviewItem(ctx context.Context, data *ReviewItemData) error {
+	if data.Question != nil {
+		question, err := s.cipher.Decrypt(ctx, *data.Question, data.QuestionKeyId)
+		if err != nil {
+			return fmt.Errorf("error when decrypt question of %s: %v", data.MessageId, err)
+		}
+		data.Question = &question
+	}
+
+	answer, err := s.cipher.Decrypt(ctx, data.Answer, data.AnswerKeyId)
+	if err != nil {
+		return fmt.Errorf("error when decrypt answer %s: %v", data.MessageId, err)
+	}
+	data.Answer = answer
+	return nil
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service HealthService
+}
+
+func NewHandler(service HealthService) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// LivezHandler only tells the process is running, it never checks dependencies
+func (h *Handler) LivezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Liveness())
+}
+
+// ReadyzHandler answers 503 while a required dependency is down, a degraded replica stays ready
+func (h *Handler) ReadyzHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	report := h.service.Readiness(c.Request.Context())
+	if report.Status == StatusDown {
+		logger.Warn("readiness check failed", "checks", report.Checks)
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
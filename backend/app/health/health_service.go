@@ -0,0 +1,203 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type Service struct {
+	cfg        *config.Config
+	db         *pgxpool.Pool
+	redis      *redis.Client
+	httpClient *http.Client
+	build      BuildInfo
+	hostname   string
+	startedAt  time.Time
+}
+
+func NewService(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client, build BuildInfo) *Service {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = fmt.Sprintf("unknown host err: %s", err.Error())
+	}
+
+	return &Service{
+		cfg:        cfg,
+		db:         db,
+		redis:      redisClient,
+		httpClient: &http.Client{Timeout: cfg.Health.CheckTimeout},
+		build:      build,
+		hostname:   hostname,
+		startedAt:  time.Now(),
+	}
+}
+
+func (s *Service) Liveness() LivenessReport {
+	return LivenessReport{
+		Status:    StatusOK,
+		Hostname:  s.hostname,
+		Build:     s.build,
+		UptimeSec: time.Since(s.startedAt).Seconds(),
+	}
+}
+
+// Readiness checks every dependency concurrently, each check is bounded by the configured timeout
+func (s *Service) Readiness(ctx context.Context) ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Health.CheckTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) Check{
+		"postgres": s.checkPostgres,
+		"redis":    s.checkRedis,
+		"model":    s.checkModel,
+	}
+
+	report := ReadinessReport{
+		Status:   StatusOK,
+		Hostname: s.hostname,
+		Build:    s.build,
+		Checks:   make(map[string]Check, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) Check) {
+			defer wg.Done()
+			result := check(ctx)
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != CheckDown {
+			continue
+		}
+		if check.Required {
+			report.Status = StatusDown
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// checkPostgres pings the pool, the app cannot serve anything without it so it is always required
+func (s *Service) checkPostgres(ctx context.Context) Check {
+	check := Check{Required: true}
+	if s.db == nil {
+		check.Status = CheckDown
+		check.Error = "not connected"
+		return check
+	}
+
+	start := time.Now()
+	err := s.db.Ping(ctx)
+	check.LatencyMs = sinceMs(start)
+	check.setResult(err)
+
+	stat := s.db.Stat()
+	check.Details = map[string]any{
+		"totalConns":           stat.TotalConns(),
+		"idleConns":            stat.IdleConns(),
+		"acquiredConns":        stat.AcquiredConns(),
+		"constructingConns":    stat.ConstructingConns(),
+		"maxConns":             stat.MaxConns(),
+		"acquireCount":         stat.AcquireCount(),
+		"emptyAcquireCount":    stat.EmptyAcquireCount(),
+		"canceledAcquireCount": stat.CanceledAcquireCount(),
+	}
+	return check
+}
+
+// checkRedis pings Redis. While it is down quota either lets every request through or rejects them,
+// rate limits and concurrency limits are not enforced
+func (s *Service) checkRedis(ctx context.Context) Check {
+	check := Check{Required: s.cfg.Health.RequireRedis}
+
+	quotaMode := "fail_closed"
+	if s.cfg.Quota.FailOpen {
+		quotaMode = "fail_open"
+	}
+	check.Details = map[string]any{
+		"quotaWhenDown": quotaMode,
+	}
+
+	if s.redis == nil {
+		check.Status = CheckDown
+		check.Error = "not connected"
+		return check
+	}
+
+	start := time.Now()
+	err := s.redis.Ping(ctx).Err()
+	check.LatencyMs = sinceMs(start)
+	check.setResult(err)
+
+	stats := s.redis.PoolStats()
+	check.Details["totalConns"] = stats.TotalConns
+	check.Details["idleConns"] = stats.IdleConns
+	check.Details["timeouts"] = stats.Timeouts
+	return check
+}
+
+// checkModel calls the FastAPI /health endpoint
+func (s *Service) checkModel(ctx context.Context) Check {
+	check := Check{Required: s.cfg.Health.RequireModel}
+	healthURL := s.cfg.Model.ModelHealthURL
+	if healthURL == "" {
+		check.Status = CheckSkipped
+		check.Error = "model health url is not configured"
+		return check
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		check.setResult(fmt.Errorf("error creating request: %w", err))
+		return check
+	}
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	check.LatencyMs = sinceMs(start)
+	if err != nil {
+		check.setResult(err)
+		return check
+	}
+	defer resp.Body.Close()
+
+	check.Details = map[string]any{
+		"statusCode": resp.StatusCode,
+	}
+	if resp.StatusCode != http.StatusOK {
+		check.setResult(fmt.Errorf("model health returned status %d", resp.StatusCode))
+		return check
+	}
+	check.setResult(nil)
+	return check
+}
+
+func (c *Check) setResult(err error) {
+	if err != nil {
+		c.Status = CheckDown
+		c.Error = err.Error()
+		return
+	}
+	c.Status = CheckUp
+}
+
+func sinceMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,47 @@
+package health
+
+import "context"
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+
+	CheckUp      = "up"
+	CheckDown    = "down"
+	CheckSkipped = "skipped"
+)
+
+type HealthService interface {
+	Liveness() LivenessReport
+	Readiness(ctx context.Context) ReadinessReport
+}
+
+// BuildInfo is injected at build time with -ldflags "-X main.version=... -X main.commit=..."
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+type LivenessReport struct {
+	Status    string    `json:"status"`
+	Hostname  string    `json:"hostname"`
+	Build     BuildInfo `json:"build"`
+	UptimeSec float64   `json:"uptimeSec"`
+}
+
+// ReadinessReport is down when a required dependency is down, degraded when only optional ones are
+type ReadinessReport struct {
+	Status   string           `json:"status"`
+	Hostname string           `json:"hostname"`
+	Build    BuildInfo        `json:"build"`
+	Checks   map[string]Check `json:"checks"`
+}
+
+type Check struct {
+	Status    string         `json:"status"`
+	Required  bool           `json:"required"`
+	LatencyMs float64        `json:"latencyMs"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithRequestID returns ctx carrying the request ID, its logger adds the ID to every line
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return With(ctx, "requestId", requestID)
+}
+
+// RequestID returns the request ID carried by ctx, empty when there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// With returns ctx whose logger adds args, key-value pairs like slog.Logger.With, to every line
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger carried by ctx, the default logger when there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -1,10 +1,11 @@
 package messageshistory
 
 import (
-	"log/slog"
+	"errors"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -22,7 +23,7 @@ func NewHandler(service MessageService) *Handler {
 }
 
 func (h *Handler) GetMessageHistory(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req MessageHistoryRequest
 
 	req.SessionId = c.Param("sessionID")
@@ -32,7 +33,7 @@ func (h *Handler) GetMessageHistory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, app.Response{
 			Code:    app.InvalidRequestErrorCode,
 			Message: app.InvalidRequestErrorMessage,
-		}) 
+		})
 		return
 	}
 
@@ -53,3 +54,52 @@ func (h *Handler) GetMessageHistory(c *gin.Context) {
 		Data:    resp,
 	})
 }
+
+func (h *Handler) SwitchBranchHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req SwitchBranchRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.SessionId = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.SwitchBranchService(ctx, req)
+	if err != nil {
+		logger.Error("error while switch branch : " + err.Error())
+		if errors.Is(err, ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
@@ -1,6 +1,11 @@
 package messageshistory
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/branch"
+)
 
 type Service struct {
 	storage MessageStorage
@@ -19,16 +24,55 @@ func (s *Service) GetMessageHistoryService(ctx context.Context, req MessageHisto
 	}
 
 	messageResp := []MessageHistoryResponse{}
-	for _, data := range resp {
+	for _, data := range ActiveBranch(resp) {
 		messageResp = append(messageResp, MessageHistoryResponse{
-			SessionId: data.SessionId,
-			MessageId: data.MessageId,
-			Role:      data.Role,
-			Content:   data.Content,
-			CreatedAt: data.CreatedAt,
-			Feedback:  data.Feedback,
+			SessionId:       data.SessionId,
+			MessageId:       data.MessageId,
+			ParentMessageId: data.ParentMessageId,
+			Role:            data.Role,
+			Content:         data.Content,
+			CreatedAt:       data.CreatedAt,
+			Feedback:        data.Feedback,
+			ModelType:       data.ModelType,
+			CompareGroupId:  data.CompareGroupId,
+			SiblingCount:    data.SiblingCount,
+			SiblingIndex:    data.SiblingIndex,
 		})
 	}
 
 	return messageResp, nil
 }
+
+func (s *Service) SwitchBranchService(ctx context.Context, req SwitchBranchRequest) error {
+	err := s.storage.SwitchBranchStorage(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to switch branch in storage error : %w", err)
+	}
+	return nil
+}
+
+// ActiveBranch keeps only the messages on the active branch of the conversation, in conversation order
+func ActiveBranch(dataList []MessageHistoryData) []ActiveMessage {
+	nodes := make([]branch.Node, 0, len(dataList))
+	byId := make(map[string]MessageHistoryData, len(dataList))
+	for _, data := range dataList {
+		nodes = append(nodes, branch.Node{
+			MessageId:       data.MessageId,
+			ParentMessageId: data.ParentMessageId,
+			Role:            data.Role,
+			IsActive:        data.IsActive,
+			CreatedAt:       data.CreatedAt,
+		})
+		byId[data.MessageId] = data
+	}
+
+	active := []ActiveMessage{}
+	for _, item := range branch.ActivePath(nodes) {
+		active = append(active, ActiveMessage{
+			MessageHistoryData: byId[item.MessageId],
+			SiblingCount:       item.SiblingCount,
+			SiblingIndex:       item.SiblingIndex,
+		})
+	}
+	return active
+}
@@ -2,16 +2,21 @@ package messageshistory
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Storage struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	cipher encryption.Cipher
 }
 
-func NewStorage(db *pgxpool.Pool) *Storage {
-	return &Storage{db: db}
+func NewStorage(db *pgxpool.Pool, cipher encryption.Cipher) *Storage {
+	return &Storage{db: db, cipher: cipher}
 }
 
 func (s *Storage) GetMessageHistoryStorage(ctx context.Context, sessionId string) ([]MessageHistoryData, error) {
@@ -19,10 +24,15 @@ func (s *Storage) GetMessageHistoryStorage(ctx context.Context, sessionId string
 		SELECT 
 			session_id,
 			message_id,
+			parent_message_id,
 			'user' AS role,
 			content,
 			created_at,
-			NULL AS feedback
+			NULL AS feedback,
+			NULL AS model_type,
+			NULL::uuid AS compare_group_id,
+			is_active,
+			content_key_id
 		FROM user_messages
 		WHERE session_id = $1
 
@@ -31,10 +41,15 @@ func (s *Storage) GetMessageHistoryStorage(ctx context.Context, sessionId string
 		SELECT 
 			session_id,
 			message_id,
+			parent_message_id,
 			'model' AS role,
 			content,
 			created_at,
-			feedback
+			feedback,
+			model_type,
+			compare_group_id,
+			is_active,
+			content_key_id
 		FROM model_messages
 		WHERE session_id = $1
 
@@ -54,10 +69,15 @@ func (s *Storage) GetMessageHistoryStorage(ctx context.Context, sessionId string
 		err := rows.Scan(
 			&data.SessionId,
 			&data.MessageId,
+			&data.ParentMessageId,
 			&data.Role,
 			&data.Content,
 			&data.CreatedAt,
 			&data.Feedback,
+			&data.ModelType,
+			&data.CompareGroupId,
+			&data.IsActive,
+			&data.ContentKeyId,
 		)
 		if err != nil {
 			return nil, err
@@ -69,5 +89,61 @@ func (s *Storage) GetMessageHistoryStorage(ctx context.Context, sessionId string
 		return nil, err
 	}
 
+	// decrypted once the rows are read, loading a data key needs a connection of its own
+	for i := range dataList {
+		dataList[i].Content, err = s.cipher.Decrypt(ctx, dataList[i].Content, dataList[i].ContentKeyId)
+		if err != nil {
+			return nil, fmt.Errorf("error when decrypt message %s: %v", dataList[i].MessageId, err)
+		}
+	}
+
 	return dataList, nil
 }
+
+// SwitchBranchStorage makes messageId the active message among its siblings
+func (s *Storage) SwitchBranchStorage(ctx context.Context, req SwitchBranchRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT 'user_messages', m.parent_message_id
+		FROM user_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE m.message_id = $1 AND m.session_id = $2 AND s.user_id = $3 AND s.deleted_at IS NULL
+
+		UNION ALL
+
+		SELECT 'model_messages', m.parent_message_id
+		FROM model_messages m
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE m.message_id = $1 AND m.session_id = $2 AND s.user_id = $3 AND s.deleted_at IS NULL
+	`
+
+	var table string
+	var parentMessageId *string
+	err = tx.QueryRow(ctx, query, req.MessageId, req.SessionId, req.UserId).Scan(&table, &parentMessageId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMessageNotFound
+		}
+		return fmt.Errorf("error when query message: %v", err)
+	}
+
+	// table comes from the constant branches of the query above
+	update := `UPDATE ` + table + `
+		SET is_active = (message_id = $1)
+		WHERE session_id = $2 AND parent_message_id IS NOT DISTINCT FROM $3`
+	_, err = tx.Exec(ctx, update, req.MessageId, req.SessionId, parentMessageId)
+	if err != nil {
+		return fmt.Errorf("error when switch branch: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error when commit transaction: %v", err)
+	}
+
+	return nil
+}
@@ -2,34 +2,61 @@ package messageshistory
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var ErrMessageNotFound = errors.New("message not found")
+
 type MessageService interface {
 	GetMessageHistoryService(ctx context.Context, req MessageHistoryRequest) ([]MessageHistoryResponse, error)
+	SwitchBranchService(ctx context.Context, req SwitchBranchRequest) error
 }
 type MessageStorage interface {
 	GetMessageHistoryStorage(ctx context.Context, sessionId string) ([]MessageHistoryData, error)
+	SwitchBranchStorage(ctx context.Context, req SwitchBranchRequest) error
 }
 
 type MessageHistoryRequest struct {
 	SessionId string `json:"sessionId" validate:"required,uuid4"`
 }
 
+type SwitchBranchRequest struct {
+	UserId    string `json:"userId" validate:"required"`
+	SessionId string `json:"sessionId" validate:"required,uuid4"`
+	MessageId string `json:"messageId" validate:"required,uuid4"`
+}
+
 type MessageHistoryResponse struct {
-	SessionId string    `json:"sessionId"`
-	MessageId string    `json:"messageId"`
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	Feedback  *int   `json:"feedback"`
+	SessionId       string    `json:"sessionId"`
+	MessageId       string    `json:"messageId"`
+	ParentMessageId *string   `json:"parentMessageId"`
+	Role            string    `json:"role"`
+	Content         string    `json:"content"`
+	CreatedAt       time.Time `json:"createdAt"`
+	Feedback        *int      `json:"feedback"`
+	ModelType       *string   `json:"modelType,omitempty"`
+	CompareGroupId  *string   `json:"compareGroupId,omitempty"`
+	SiblingCount    int       `json:"siblingCount"`
+	SiblingIndex    int       `json:"siblingIndex"`
 }
 
 type MessageHistoryData struct {
-	SessionId string    `db:"session_id"`
-	MessageId string    `db:"message_id"`
-	Role      string    `db:"role"`
-	Content   string    `db:"content"`
-	CreatedAt time.Time `db:"created_at"`
-	Feedback  *int   `db:"feedback"`
+	SessionId       string    `db:"session_id"`
+	MessageId       string    `db:"message_id"`
+	ParentMessageId *string   `db:"parent_message_id"`
+	Role            string    `db:"role"`
+	Content         string    `db:"content"`
+	CreatedAt       time.Time `db:"created_at"`
+	Feedback        *int      `db:"feedback"`
+	ModelType       *string   `db:"model_type"`
+	CompareGroupId  *string   `db:"compare_group_id"`
+	IsActive        bool      `db:"is_active"`
+	ContentKeyId    *string   `db:"content_key_id"`
+}
+
+type ActiveMessage struct {
+	MessageHistoryData
+	SiblingCount int
+	SiblingIndex int
 }
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ailaw"
+
+// registry only holds the collectors of this package, the Go runtime and the process,
+// so nothing a dependency registers globally leaks into /metrics
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	webSocketConnections = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open WebSocket connections.",
+	})
+
+	generationsInFlight = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "generations_in_flight",
+		Help:      "Answers being generated, by transport.",
+	}, []string{"transport"})
+
+	modelTimeToFirstChunk = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "model_time_to_first_chunk_seconds",
+		Help:      "Time from calling the model stream until the first content chunk.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"model_type"})
+
+	modelResponseTime = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "model_response_seconds",
+		Help:      "Total time the model took to answer, by model type and stream or sync pipeline.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 90, 120, 180},
+	}, []string{"model_type", "mode"})
+
+	modelTokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_tokens_total",
+		Help:      "Tokens used by answered questions, kind is input, output or total.",
+	}, []string{"model_type", "kind"})
+
+	quotaRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_rejections_total",
+		Help:      "Questions rejected by quota, reason is daily_quota or prompt_length.",
+	}, []string{"reason"})
+
+	feedback = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feedback_total",
+		Help:      "Feedback submitted on answers, rating is positive, negative or cleared.",
+	}, []string{"rating"})
+
+	moderationChecks = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "moderation_checks_total",
+		Help:      "Prompts checked by moderation, by the action taken on the prompt.",
+	}, []string{"action"})
+
+	moderationViolations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "moderation_violations_total",
+		Help:      "Moderation rules a prompt violated, by rule and the action of the rule.",
+	}, []string{"rule", "action"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Register adds a collector, such as the Postgres pool statistics, to /metrics
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func WebSocketConnected() {
+	webSocketConnections.Inc()
+}
+
+func WebSocketDisconnected() {
+	webSocketConnections.Dec()
+}
+
+// GenerationStarted counts an answer in flight, the returned func marks it finished
+func GenerationStarted(transport string) func() {
+	gauge := generationsInFlight.WithLabelValues(transport)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+func ObserveTimeToFirstChunk(modelType string, duration time.Duration) {
+	modelTimeToFirstChunk.WithLabelValues(modelType).Observe(duration.Seconds())
+}
+
+func ObserveModelAnswer(modelType, mode string, responseTimeSec float64, inputTokens, outputTokens, totalTokens int) {
+	modelResponseTime.WithLabelValues(modelType, mode).Observe(responseTimeSec)
+	modelTokens.WithLabelValues(modelType, "input").Add(float64(inputTokens))
+	modelTokens.WithLabelValues(modelType, "output").Add(float64(outputTokens))
+	modelTokens.WithLabelValues(modelType, "total").Add(float64(totalTokens))
+}
+
+func QuotaRejected(reason string) {
+	quotaRejections.WithLabelValues(reason).Inc()
+}
+
+// FeedbackSubmitted counts a rating of 1 as positive, -1 as negative and no rating as cleared
+func FeedbackSubmitted(rating *int) {
+	label := "cleared"
+	if rating != nil {
+		switch *rating {
+		case 1:
+			label = "positive"
+		case -1:
+			label = "negative"
+		}
+	}
+	feedback.WithLabelValues(label).Inc()
+}
+
+// ModerationChecked counts a checked prompt by the action taken on it, allow when no rule matched
+func ModerationChecked(action string) {
+	moderationChecks.WithLabelValues(action).Inc()
+}
+
+func ModerationViolation(rule, action string) {
+	moderationViolations.WithLabelValues(rule, action).Inc()
+}
@@ -0,0 +1,226 @@
+package modelclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var (
+	// ErrUnavailable means the model service could not be reached or its circuit breaker is open
+	ErrUnavailable = errors.New("model service unavailable")
+	// ErrTimeout means the model service did not connect, answer or send the next event in time
+	ErrTimeout = errors.New("model service timed out")
+)
+
+// Client calls the model service. Failures that happen before the model service started working on a request
+// (connection errors, 502 and 503) are retried with backoff. A 504 or a timeout waiting for the first byte is not,
+// the sync endpoints only answer once the answer is generated and a retry would generate it again.
+// Every model URL has its own circuit breaker and a response body fails when no data arrives within the idle timeout
+type Client struct {
+	cfg  config.ModelClient
+	http *http.Client
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func New(cfg *config.ModelClient) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = cfg.FirstByteTimeout
+
+	return &Client{
+		cfg: *cfg,
+		// otelhttp adds a client span per attempt and the W3C traceparent header FastAPI continues the trace from
+		http:     &http.Client{Transport: otelhttp.NewTransport(transport)},
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Do sends req and returns the response of the first attempt the model service answered.
+// Only requests whose body can be replayed (GetBody is set) are retried.
+// The request ID of the request context is sent as X-Request-ID so model logs can be matched with ours
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	logger := logging.FromContext(ctx)
+	if requestID := logging.RequestID(ctx); requestID != "" && req.Header.Get("X-Request-ID") == "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	endpoint := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	b := c.breaker(endpoint)
+
+	attempts := 1
+	if req.GetBody != nil && c.cfg.MaxRetries > 0 {
+		attempts += c.cfg.MaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			logger.Warn("retrying model request", "url", endpoint, "attempt", attempt+1, "error", lastErr)
+			if err := c.backoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		if !b.allow() {
+			return nil, fmt.Errorf("%w: circuit breaker open for %s", ErrUnavailable, endpoint)
+		}
+
+		resp, retryable, err := c.attempt(req, attempt, b)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !retryable {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// attempt sends req once, a failed attempt is retryable when the model service cannot have started on it
+func (c *Client) attempt(req *http.Request, attempt int, b *breaker) (*http.Response, bool, error) {
+	attemptCtx, cancel := context.WithCancel(req.Context())
+	attemptReq := req.Clone(attemptCtx)
+	if attempt > 0 {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			b.release()
+			return nil, false, fmt.Errorf("error when replaying request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := c.http.Do(attemptReq)
+	if err != nil {
+		cancel()
+		if req.Context().Err() != nil {
+			b.release()
+			return nil, false, err
+		}
+		b.failure()
+
+		// only a request that never reached the model service is safe to send again
+		var opErr *net.OpError
+		dialed := errors.As(err, &opErr) && opErr.Op == "dial"
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, dialed, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return nil, dialed, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		resp.Body.Close()
+		cancel()
+		b.failure()
+		if resp.StatusCode == http.StatusGatewayTimeout {
+			return nil, false, fmt.Errorf("%w: status %d", ErrTimeout, resp.StatusCode)
+		}
+		return nil, true, fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	b.success()
+	resp.Body = newIdleTimeoutBody(resp.Body, c.cfg.IdleTimeout, cancel, b.failure)
+	return resp, false, nil
+}
+
+// backoff waits RetryBackoff doubled for every attempt already made, with up to half of it added as jitter
+func (c *Client) backoff(ctx context.Context, attempt int) error {
+	wait := c.cfg.RetryBackoff << (attempt - 1)
+	if wait > 0 {
+		wait += rand.N(wait/2 + 1)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) breaker(endpoint string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[endpoint]
+	if !ok {
+		b = newBreaker(c.cfg.BreakerFailures, c.cfg.BreakerCooldown)
+		c.breakers[endpoint] = b
+	}
+	return b
+}
+
+// idleTimeoutBody aborts the response when a read waits longer than timeout for data,
+// so a model worker that hangs in the middle of a stream does not hold the request forever
+type idleTimeoutBody struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+	cancel   context.CancelFunc
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc, onTimeout func()) io.ReadCloser {
+	b := &idleTimeoutBody{
+		body:    body,
+		timeout: timeout,
+		cancel:  cancel,
+	}
+	if timeout > 0 {
+		b.timer = time.AfterFunc(timeout, func() {
+			b.timedOut.Store(true)
+			onTimeout()
+			cancel()
+		})
+		b.timer.Stop()
+	}
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	if b.timer != nil {
+		b.timer.Reset(b.timeout)
+		defer b.timer.Stop()
+	}
+
+	n, err := b.body.Read(p)
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		err = fmt.Errorf("%w: no data for %s", ErrTimeout, b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
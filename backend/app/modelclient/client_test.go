@@ -0,0 +1,265 @@
+package modelclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+func testConfig() config.ModelClient {
+	return config.ModelClient{
+		ConnectTimeout:   time.Second,
+		FirstByteTimeout: time.Second,
+		IdleTimeout:      time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     time.Millisecond,
+	}
+}
+
+// statusServer answers with statuses in order, repeating the last one, and counts the calls it received
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newRequest(t *testing.T, ctx context.Context, url string) *http.Request {
+	t.Helper()
+	// a bytes.Reader body sets GetBody, so the request can be retried
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte(`{"q":"มาตรา 358"}`)))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	return req
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		noReplay   bool
+		wantCalls  int32
+		wantStatus int
+		wantErr    error
+	}{
+		{name: "success", statuses: []int{200}, wantCalls: 1, wantStatus: 200},
+		{name: "502 is retried", statuses: []int{502, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "503 is retried", statuses: []int{503, 503, 200}, wantCalls: 3, wantStatus: 200},
+		{name: "retries run out", statuses: []int{503}, wantCalls: 3, wantErr: ErrUnavailable},
+		{name: "504 is not retried", statuses: []int{504, 200}, wantCalls: 1, wantErr: ErrTimeout},
+		{name: "500 is returned as is", statuses: []int{500, 200}, wantCalls: 1, wantStatus: 500},
+		{name: "4xx is returned as is", statuses: []int{400, 200}, wantCalls: 1, wantStatus: 400},
+		{name: "body that cannot be replayed", statuses: []int{503, 200}, noReplay: true, wantCalls: 1, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusServer(t, tt.statuses...)
+			cfg := testConfig()
+			client := New(&cfg)
+
+			req := newRequest(t, context.Background(), server.URL)
+			if tt.noReplay {
+				req.GetBody = nil
+			}
+			resp, err := client.Do(req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Do() error = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Do() error = %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionRefused(t *testing.T) {
+	server, _ := statusServer(t, 200)
+	url := server.URL
+	server.Close()
+
+	cfg := testConfig()
+	client := New(&cfg)
+	_, err := client.Do(newRequest(t, context.Background(), url))
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Do() error = %v, want ErrUnavailable", err)
+	}
+
+	// every attempt dialed and failed
+	b := client.breaker(url)
+	if b.failures != cfg.MaxRetries+1 {
+		t.Fatalf("failures = %d, want %d", b.failures, cfg.MaxRetries+1)
+	}
+}
+
+func TestFirstByteTimeoutNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.FirstByteTimeout = 50 * time.Millisecond
+	client := New(&cfg)
+	_, err := client.Do(newRequest(t, context.Background(), server.URL))
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Do() error = %v, want ErrTimeout", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	server, calls := statusServer(t, 503, 503, 503, 200)
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.BreakerFailures = 2
+	cfg.BreakerCooldown = 50 * time.Millisecond
+	client := New(&cfg)
+
+	do := func() error {
+		resp, err := client.Do(newRequest(t, context.Background(), server.URL))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// the breaker opens after BreakerFailures failures in a row
+	for i := 0; i < cfg.BreakerFailures; i++ {
+		if err := do(); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("call %d error = %v, want ErrUnavailable", i+1, err)
+		}
+	}
+	if err := do(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Do() error = %v, want ErrUnavailable from the open breaker", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls = %d, want 2, the open breaker must not call the server", got)
+	}
+
+	// after the cooldown one probe is let through, its failure keeps the breaker open for another cooldown
+	time.Sleep(cfg.BreakerCooldown)
+	if err := do(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("probe error = %v, want ErrUnavailable", err)
+	}
+	if err := do(); !errors.Is(err, ErrUnavailable) || calls.Load() != 3 {
+		t.Fatalf("Do() error = %v, calls = %d, want the breaker open again after 3 calls", err, calls.Load())
+	}
+
+	// a successful probe closes it
+	time.Sleep(cfg.BreakerCooldown)
+	if err := do(); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if err := do(); err != nil {
+		t.Fatalf("Do() error = %v after the breaker closed", err)
+	}
+	if got := calls.Load(); got != 5 {
+		t.Fatalf("calls = %d, want 5", got)
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond)
+	b.failure()
+	if b.allow() {
+		t.Fatal("allow() = true while the breaker is open")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("allow() = false after the cooldown, want a probe")
+	}
+	for i := 0; i < 3; i++ {
+		if b.allow() {
+			t.Fatal("allow() = true while the probe is running")
+		}
+	}
+
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatal("allow() = false after the probe succeeded")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	if !b.allow() {
+		t.Fatal("allow() = false with the breaker disabled")
+	}
+}
+
+func TestCancelledProbeIsReleased(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the request context is only cancelled on a closed connection once the body has been read
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.BreakerFailures = 1
+	cfg.BreakerCooldown = 10 * time.Millisecond
+	client := New(&cfg)
+
+	b := client.breaker(server.URL)
+	b.failure()
+	time.Sleep(cfg.BreakerCooldown)
+
+	// the probe's caller gives up before the model service answers
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := client.Do(newRequest(t, ctx, server.URL))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do() error = %v, want context.Canceled", err)
+	}
+
+	// neither a success nor a failure, the next call probes again
+	if !b.allow() {
+		t.Fatal("allow() = false after the probe was cancelled, the breaker is stuck open")
+	}
+}
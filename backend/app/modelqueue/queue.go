@@ -0,0 +1,254 @@
+package modelqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+var (
+	ErrQueueFull   = errors.New("model queue is full")
+	ErrWaitTimeout = errors.New("timed out waiting in the model queue")
+)
+
+// Queue schedules the calls of one replica to the model service. At most maxInFlight calls run at once,
+// the others wait in line: higher plan tiers are served first and users of the same tier take turns,
+// so one user sending many questions cannot hold the line for everyone else
+type Queue struct {
+	mu          sync.Mutex
+	maxInFlight int
+	maxQueued   int
+	maxWait     time.Duration
+	priorities  map[string]int // plan tier -> level, level 0 is served first
+	levels      []*level
+	inFlight    int
+	queued      int
+}
+
+// level holds the users of one priority waiting in line, ring is the order they take turns in
+type level struct {
+	users map[string]*userLine
+	ring  []*userLine
+}
+
+type userLine struct {
+	userID  string
+	waiters []*waiter
+}
+
+type waiter struct {
+	level    *level
+	line     *userLine
+	ready    chan struct{}
+	position chan int
+	lastPos  int
+	granted  bool
+}
+
+// New returns a queue for cfg, tiers missing from cfg.Tiers are served last
+func New(cfg *config.ModelQueue) *Queue {
+	q := &Queue{
+		maxInFlight: cfg.MaxInFlight,
+		maxQueued:   cfg.MaxQueued,
+		maxWait:     cfg.MaxWait,
+		priorities:  make(map[string]int, len(cfg.Tiers)),
+	}
+	for i, tier := range cfg.Tiers {
+		q.priorities[tier] = i
+	}
+	for i := 0; i <= len(cfg.Tiers); i++ {
+		q.levels = append(q.levels, &level{users: make(map[string]*userLine)})
+	}
+	return q
+}
+
+// Acquire waits for a turn to call the model and returns the func that gives the turn back.
+// onPosition, when set, is called with the 1-based position in line every time it changes
+func (q *Queue) Acquire(ctx context.Context, userID, tier string, onPosition func(position int)) (func(), error) {
+	if q == nil || q.maxInFlight <= 0 {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	if q.inFlight < q.maxInFlight && q.queued == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	if q.maxQueued > 0 && q.queued >= q.maxQueued {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := q.enqueue(userID, tier)
+	q.updatePositions()
+	q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if q.maxWait > 0 {
+		timer := time.NewTimer(q.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-w.ready:
+			return q.releaseFunc(), nil
+		case position := <-w.position:
+			if onPosition != nil {
+				onPosition(position)
+			}
+		case <-timeout:
+			if q.abandon(w) {
+				return nil, ErrWaitTimeout
+			}
+			// the turn came at the same time as the timeout
+			return q.releaseFunc(), nil
+		case <-ctx.Done():
+			if !q.abandon(w) {
+				q.releaseFunc()()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *Queue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.inFlight--
+			q.dispatch()
+		})
+	}
+}
+
+func (q *Queue) enqueue(userID, tier string) *waiter {
+	priority, ok := q.priorities[tier]
+	if !ok {
+		priority = len(q.levels) - 1
+	}
+	lvl := q.levels[priority]
+
+	line, ok := lvl.users[userID]
+	if !ok {
+		line = &userLine{userID: userID}
+		lvl.users[userID] = line
+		lvl.ring = append(lvl.ring, line)
+	}
+
+	w := &waiter{
+		level:    lvl,
+		line:     line,
+		ready:    make(chan struct{}),
+		position: make(chan int, 1),
+	}
+	line.waiters = append(line.waiters, w)
+	q.queued++
+	return w
+}
+
+// dispatch hands free turns to the waiters next in line
+func (q *Queue) dispatch() {
+	dispatched := false
+	for q.inFlight < q.maxInFlight && q.queued > 0 {
+		w := q.next()
+		w.granted = true
+		close(w.ready)
+		q.inFlight++
+		dispatched = true
+	}
+	if dispatched {
+		q.updatePositions()
+	}
+}
+
+// next takes the first waiter of the user whose turn it is in the highest non-empty level
+func (q *Queue) next() *waiter {
+	for _, lvl := range q.levels {
+		if len(lvl.ring) == 0 {
+			continue
+		}
+
+		line := lvl.ring[0]
+		w := line.waiters[0]
+		line.waiters = line.waiters[1:]
+		lvl.ring = lvl.ring[1:]
+		if len(line.waiters) > 0 {
+			lvl.ring = append(lvl.ring, line)
+		} else {
+			delete(lvl.users, line.userID)
+		}
+		q.queued--
+		return w
+	}
+	return nil
+}
+
+// abandon takes w out of line, it returns false when w was already given a turn
+func (q *Queue) abandon(w *waiter) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if w.granted {
+		return false
+	}
+
+	line := w.line
+	for i, other := range line.waiters {
+		if other == w {
+			line.waiters = append(line.waiters[:i], line.waiters[i+1:]...)
+			break
+		}
+	}
+	if len(line.waiters) == 0 {
+		lvl := w.level
+		delete(lvl.users, line.userID)
+		for i, other := range lvl.ring {
+			if other == line {
+				lvl.ring = append(lvl.ring[:i], lvl.ring[i+1:]...)
+				break
+			}
+		}
+	}
+	q.queued--
+	q.updatePositions()
+	return true
+}
+
+// updatePositions walks the line in the order it will be served and tells every waiter whose position changed
+func (q *Queue) updatePositions() {
+	position := 0
+	for _, lvl := range q.levels {
+		for round := 0; ; round++ {
+			served := false
+			for _, line := range lvl.ring {
+				if round >= len(line.waiters) {
+					continue
+				}
+				position++
+				served = true
+
+				w := line.waiters[round]
+				if w.lastPos == position {
+					continue
+				}
+				w.lastPos = position
+				// only the latest position matters to the waiter
+				select {
+				case <-w.position:
+				default:
+				}
+				w.position <- position
+			}
+			if !served {
+				break
+			}
+		}
+	}
+}
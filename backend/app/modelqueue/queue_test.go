@@ -0,0 +1,350 @@
+package modelqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+func newQueue(maxInFlight, maxQueued int, maxWait time.Duration) *Queue {
+	return New(&config.ModelQueue{
+		MaxInFlight: maxInFlight,
+		MaxQueued:   maxQueued,
+		MaxWait:     maxWait,
+		Tiers:       []string{"enterprise", "pro", "free"},
+	})
+}
+
+// waitQueued waits until n callers are waiting in line, so the test controls the order they joined it in
+func waitQueued(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		queued := q.queued
+		q.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func assertIdle(t *testing.T, q *Queue) {
+	t.Helper()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight != 0 || q.queued != 0 {
+		t.Fatalf("inFlight = %d, queued = %d, want both 0", q.inFlight, q.queued)
+	}
+	for i, lvl := range q.levels {
+		if len(lvl.ring) != 0 || len(lvl.users) != 0 {
+			t.Fatalf("level %d still holds %d users", i, len(lvl.ring))
+		}
+	}
+}
+
+type caller struct {
+	name   string
+	userID string
+	tier   string
+}
+
+// serveOrder holds the only turn while callers join the line one by one, then gives it back and
+// returns the order the callers were served in
+func serveOrder(t *testing.T, callers []caller) []string {
+	t.Helper()
+	q := newQueue(1, 0, 0)
+	release, err := q.Acquire(context.Background(), "holder", "enterprise", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	order := make(chan string, len(callers))
+	var wg sync.WaitGroup
+	for i, c := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := q.Acquire(context.Background(), c.userID, c.tier, nil)
+			if err != nil {
+				t.Errorf("Acquire(%s) error = %v", c.name, err)
+				return
+			}
+			order <- c.name
+			release()
+		}()
+		waitQueued(t, q, i+1)
+	}
+
+	release()
+	wg.Wait()
+	close(order)
+
+	var got []string
+	for name := range order {
+		got = append(got, name)
+	}
+	assertIdle(t, q)
+	return got
+}
+
+func TestTierOrder(t *testing.T) {
+	got := serveOrder(t, []caller{
+		{"free", "u1", "free"},
+		{"unknown", "u2", "trial"},
+		{"pro", "u3", "pro"},
+		{"enterprise", "u4", "enterprise"},
+	})
+
+	want := []string{"enterprise", "pro", "free", "unknown"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("served %v, want %v", got, want)
+	}
+}
+
+func TestRoundRobinBetweenUsers(t *testing.T) {
+	got := serveOrder(t, []caller{
+		{"a1", "a", "free"},
+		{"a2", "a", "free"},
+		{"a3", "a", "free"},
+		{"b1", "b", "free"},
+		{"b2", "b", "free"},
+		{"c1", "c", "free"},
+	})
+
+	want := []string{"a1", "b1", "c1", "a2", "b2", "a3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("served %v, want %v", got, want)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := newQueue(1, 1, 0)
+	release, err := q.Acquire(context.Background(), "holder", "free", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		release, err := q.Acquire(context.Background(), "u1", "free", nil)
+		if err == nil {
+			release()
+		}
+		waited <- err
+	}()
+	waitQueued(t, q, 1)
+
+	if _, err := q.Acquire(context.Background(), "u2", "free", nil); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Acquire() error = %v, want ErrQueueFull", err)
+	}
+
+	release()
+	if err := <-waited; err != nil {
+		t.Fatalf("queued Acquire() error = %v", err)
+	}
+	assertIdle(t, q)
+}
+
+func TestWaitTimeout(t *testing.T) {
+	q := newQueue(1, 0, 20*time.Millisecond)
+	release, err := q.Acquire(context.Background(), "holder", "free", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	if _, err := q.Acquire(context.Background(), "u1", "free", nil); !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("Acquire() error = %v, want ErrWaitTimeout", err)
+	}
+
+	release()
+	assertIdle(t, q)
+}
+
+func TestCancelWhileWaiting(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	release, err := q.Acquire(context.Background(), "holder", "free", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(ctx, "u1", "free", nil)
+		waited <- err
+	}()
+	waitQueued(t, q, 1)
+
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire() error = %v, want context.Canceled", err)
+	}
+
+	release()
+	assertIdle(t, q)
+}
+
+func TestPositions(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	release, err := q.Acquire(context.Background(), "holder", "free", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	positions := make(chan int, 10)
+	waited := make(chan error, 1)
+	go func() {
+		release, err := q.Acquire(context.Background(), "u1", "free", func(position int) {
+			positions <- position
+		})
+		if err == nil {
+			release()
+		}
+		waited <- err
+	}()
+	waitQueued(t, q, 1)
+	if got := <-positions; got != 1 {
+		t.Fatalf("position = %d, want 1", got)
+	}
+
+	// a caller of a higher tier moves in front
+	enterpriseCtx, cancelEnterprise := context.WithCancel(context.Background())
+	enterpriseWaited := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(enterpriseCtx, "u2", "enterprise", nil)
+		enterpriseWaited <- err
+	}()
+	waitQueued(t, q, 2)
+	if got := <-positions; got != 2 {
+		t.Fatalf("position = %d, want 2", got)
+	}
+
+	// and leaving the line moves the caller back to the front
+	cancelEnterprise()
+	if err := <-enterpriseWaited; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire() error = %v, want context.Canceled", err)
+	}
+	if got := <-positions; got != 1 {
+		t.Fatalf("position = %d, want 1", got)
+	}
+
+	release()
+	if err := <-waited; err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	assertIdle(t, q)
+}
+
+func TestUpdatePositionsKeepsLatest(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	q.inFlight = 1
+
+	q.mu.Lock()
+	w := q.enqueue("u1", "free")
+	q.updatePositions()
+	q.enqueue("u2", "enterprise")
+	q.updatePositions()
+	q.mu.Unlock()
+
+	// the waiter did not read position 1 before it changed, only the latest one is left
+	if got := <-w.position; got != 2 {
+		t.Fatalf("position = %d, want 2", got)
+	}
+	select {
+	case position := <-w.position:
+		t.Fatalf("unexpected position %d", position)
+	default:
+	}
+}
+
+func TestGrantBeforeAbandon(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	q.inFlight = 1
+
+	q.mu.Lock()
+	w := q.enqueue("u1", "free")
+	q.mu.Unlock()
+
+	// the turn is handed over just before the caller gives up
+	q.releaseFunc()()
+	if !w.granted {
+		t.Fatal("waiter was not given the free turn")
+	}
+	if q.abandon(w) {
+		t.Fatal("abandon() = true for a waiter that was already given a turn")
+	}
+
+	// the caller gives the turn it did not use back
+	q.releaseFunc()()
+	assertIdle(t, q)
+}
+
+func TestAbandonBeforeGrant(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	q.inFlight = 1
+
+	q.mu.Lock()
+	first := q.enqueue("u1", "free")
+	second := q.enqueue("u2", "free")
+	q.updatePositions()
+	q.mu.Unlock()
+	<-first.position
+	<-second.position
+
+	if !q.abandon(first) {
+		t.Fatal("abandon() = false for a waiter still in line")
+	}
+	if got := <-second.position; got != 1 {
+		t.Fatalf("position = %d, want 1", got)
+	}
+
+	// the turn skips the caller who left
+	q.releaseFunc()()
+	if first.granted || !second.granted {
+		t.Fatalf("granted first = %v, second = %v, want only the second", first.granted, second.granted)
+	}
+
+	q.releaseFunc()()
+	assertIdle(t, q)
+}
+
+// TestCancelRace lets callers give up at random while turns are handed out, every turn has to come back
+func TestCancelRace(t *testing.T) {
+	q := newQueue(2, 0, 0)
+	tiers := []string{"enterprise", "pro", "free"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(rand.Intn(200))*time.Microsecond)
+			defer cancel()
+
+			release, err := q.Acquire(ctx, fmt.Sprintf("u%d", i%7), tiers[i%len(tiers)], func(int) {})
+			if err != nil {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Acquire() error = %v", err)
+				}
+				return
+			}
+			time.Sleep(time.Duration(rand.Intn(50)) * time.Microsecond)
+			release()
+			// releasing twice must not free a second turn
+			release()
+		}()
+	}
+	wg.Wait()
+	assertIdle(t, q)
+}
@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   ModerationService
+	validator *validator.Validate
+}
+
+func NewHandler(service ModerationService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetFlagsHandler lists flagged and blocked prompts for review
+func (h *Handler) GetFlagsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req GetFlagsRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetFlagsService(ctx, req)
+	if err != nil {
+		logger.Error("error while get moderation flags : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
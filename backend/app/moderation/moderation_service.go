@@ -0,0 +1,137 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/pii"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultFlagsLimit = 20
+	flagExcerptRunes  = 500
+)
+
+var actionSeverity = map[string]int{
+	ActionAllow: 0,
+	ActionWarn:  1,
+	ActionFlag:  2,
+	ActionBlock: 3,
+}
+
+type Service struct {
+	enabled bool
+	rules   []Rule
+	storage ModerationStorage
+}
+
+// NewService builds the rules from cfg, rules whose action is allow are left out
+func NewService(cfg *config.Moderation, storage ModerationStorage) (*Service, error) {
+	s := &Service{
+		enabled: cfg.Enabled,
+		storage: storage,
+	}
+
+	for _, action := range []string{cfg.KeywordAction, cfg.PatternAction, cfg.JailbreakAction, cfg.LengthAction, cfg.EntropyAction} {
+		if _, ok := actionSeverity[action]; !ok {
+			return nil, fmt.Errorf("unknown moderation action %q", action)
+		}
+	}
+
+	patternRule, err := NewRegexRule("pattern", cfg.PatternAction, "matched a blocked pattern", cfg.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []Rule{
+		NewLengthRule(cfg.LengthAction, cfg.MaxPromptRunes),
+		NewJailbreakRule(cfg.JailbreakAction),
+		NewKeywordRule("keyword", cfg.KeywordAction, cfg.Keywords),
+		patternRule,
+		NewEntropyRule(cfg.EntropyAction, cfg.EntropyThreshold, cfg.EntropyMinRunes),
+	}
+	actions := []string{cfg.LengthAction, cfg.JailbreakAction, cfg.KeywordAction, cfg.PatternAction, cfg.EntropyAction}
+	for i, rule := range rules {
+		if actions[i] != ActionAllow {
+			s.rules = append(s.rules, rule)
+		}
+	}
+
+	return s, nil
+}
+
+// AddRule registers an extra rule, it has to be called before the service is used
+func (s *Service) AddRule(rule Rule) {
+	s.rules = append(s.rules, rule)
+}
+
+func (s *Service) CheckPrompt(ctx context.Context, req CheckRequest) (Result, error) {
+	result := Result{
+		Action:     ActionAllow,
+		Violations: []Violation{},
+	}
+	if !s.enabled {
+		return result, nil
+	}
+
+	for _, rule := range s.rules {
+		violation := rule.Check(req.Content)
+		if violation == nil {
+			continue
+		}
+		result.Violations = append(result.Violations, *violation)
+		if actionSeverity[violation.Action] > actionSeverity[result.Action] {
+			result.Action = violation.Action
+		}
+	}
+	metrics.ModerationChecked(result.Action)
+	for _, violation := range result.Violations {
+		metrics.ModerationViolation(violation.Rule, violation.Action)
+	}
+
+	if result.Action == ActionFlag || result.Action == ActionBlock {
+		// the prompt is checked before it is redacted, personal data is scrubbed from what the flag keeps
+		violations := make([]Violation, 0, len(result.Violations))
+		for _, violation := range result.Violations {
+			violation.Match = pii.Scrub(violation.Match)
+			violations = append(violations, violation)
+		}
+		err := s.storage.SaveFlagStorage(ctx, FlagData{
+			FlagId:     uuid.NewString(),
+			UserId:     req.UserId,
+			SessionId:  req.SessionId,
+			Action:     result.Action,
+			Violations: violations,
+			Excerpt:    excerpt(pii.Scrub(req.Content), flagExcerptRunes),
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			// the decision stands even when it cannot be recorded
+			logging.FromContext(ctx).Error("failed to save moderation flag", "userId", req.UserId, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetFlagsService(ctx context.Context, req GetFlagsRequest) ([]FlagResponse, error) {
+	if req.Limit == 0 {
+		req.Limit = defaultFlagsLimit
+	}
+
+	dataList, err := s.storage.GetFlagsStorage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation flags in storage error : %w", err)
+	}
+
+	resp := make([]FlagResponse, 0, len(dataList))
+	for _, data := range dataList {
+		resp = append(resp, FlagResponse(data))
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) SaveFlagStorage(ctx context.Context, data FlagData) error {
+	violations, err := json.Marshal(data.Violations)
+	if err != nil {
+		return fmt.Errorf("error when marshal violations: %v", err)
+	}
+
+	query := `INSERT INTO moderation_flags
+				(flag_id, user_id, session_id, action, violations, excerpt, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = s.db.Exec(ctx, query,
+		data.FlagId,
+		data.UserId,
+		data.SessionId,
+		data.Action,
+		violations,
+		data.Excerpt,
+		data.CreatedAt,
+	)
+	return err
+}
+
+func (s *Storage) GetFlagsStorage(ctx context.Context, req GetFlagsRequest) ([]FlagData, error) {
+	query := `
+		SELECT flag_id, user_id, session_id, action, violations, excerpt, created_at
+		FROM moderation_flags
+		WHERE ($1::text IS NULL OR action = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := s.db.Query(ctx, query, req.Action, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []FlagData{}
+
+	for rows.Next() {
+		var data FlagData
+		var violations []byte
+		err := rows.Scan(
+			&data.FlagId,
+			&data.UserId,
+			&data.SessionId,
+			&data.Action,
+			&violations,
+			&data.Excerpt,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(violations, &data.Violations); err != nil {
+			return nil, fmt.Errorf("error when unmarshal violations: %v", err)
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
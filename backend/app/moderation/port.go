@@ -0,0 +1,76 @@
+package moderation
+
+import (
+	"context"
+	"time"
+)
+
+// Actions taken when a rule matches, ordered from the mildest
+const (
+	ActionAllow = "allow"
+	ActionWarn  = "warn"
+	ActionFlag  = "flag"
+	ActionBlock = "block"
+)
+
+type ModerationService interface {
+	// CheckPrompt runs every rule against a prompt and records the outcome
+	CheckPrompt(ctx context.Context, req CheckRequest) (Result, error)
+	GetFlagsService(ctx context.Context, req GetFlagsRequest) ([]FlagResponse, error)
+}
+
+type ModerationStorage interface {
+	SaveFlagStorage(ctx context.Context, data FlagData) error
+	GetFlagsStorage(ctx context.Context, req GetFlagsRequest) ([]FlagData, error)
+}
+
+// Rule inspects a prompt, it returns nil when the prompt does not match
+type Rule interface {
+	Name() string
+	Check(text string) *Violation
+}
+
+type Violation struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	// Match is the part of the prompt that triggered the rule
+	Match string `json:"match,omitempty"`
+}
+
+type CheckRequest struct {
+	UserId    string
+	SessionId string
+	Content   string
+}
+
+type Result struct {
+	Action     string      `json:"action"`
+	Violations []Violation `json:"violations"`
+}
+
+type GetFlagsRequest struct {
+	Action *string `form:"action" validate:"omitempty,oneof=warn flag block"`
+	Limit  int     `form:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int     `form:"offset" validate:"omitempty,min=0"`
+}
+
+type FlagResponse struct {
+	FlagId     string      `json:"flagId"`
+	UserId     string      `json:"userId"`
+	SessionId  string      `json:"sessionId"`
+	Action     string      `json:"action"`
+	Violations []Violation `json:"violations"`
+	Excerpt    string      `json:"excerpt"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+type FlagData struct {
+	FlagId     string      `db:"flag_id"`
+	UserId     string      `db:"user_id"`
+	SessionId  string      `db:"session_id"`
+	Action     string      `db:"action"`
+	Violations []Violation `db:"violations"`
+	Excerpt    string      `db:"excerpt"`
+	CreatedAt  time.Time   `db:"created_at"`
+}
@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// jailbreakPatterns are known prompt-injection phrasings in English and Thai
+var jailbreakPatterns = []string{
+	`(?i)ignore\s+(all\s+)?(the\s+)?(previous|prior|above)\s+(instructions|prompts?|rules)`,
+	`(?i)disregard\s+(all\s+)?(your|the)\s+(instructions|rules|guidelines)`,
+	`(?i)(reveal|show|print|repeat)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions)`,
+	`(?i)you\s+are\s+now\s+(DAN|in\s+developer\s+mode|jailbroken)`,
+	`(?i)\bDAN\s+mode\b|\bdeveloper\s+mode\s+enabled\b`,
+	`(?i)pretend\s+(that\s+)?you\s+(have\s+no|are\s+not\s+bound\s+by)\s+(rules|restrictions|guidelines)`,
+	`(ลืม|เพิกเฉย|ไม่ต้องสนใจ)(คำสั่ง|กฎ)(ทั้งหมด)?(ก่อนหน้า|ข้างต้น)?`,
+	`(แสดง|บอก)(system\s*prompt|คำสั่งระบบ)`,
+}
+
+// KeywordRule matches any of a list of words, case insensitive
+type KeywordRule struct {
+	name     string
+	action   string
+	keywords []string
+}
+
+func NewKeywordRule(name, action string, keywords []string) *KeywordRule {
+	lowered := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			lowered = append(lowered, keyword)
+		}
+	}
+	return &KeywordRule{name: name, action: action, keywords: lowered}
+}
+
+func (r *KeywordRule) Name() string { return r.name }
+
+func (r *KeywordRule) Check(text string) *Violation {
+	lowered := strings.ToLower(text)
+	for _, keyword := range r.keywords {
+		if strings.Contains(lowered, keyword) {
+			return &Violation{Rule: r.name, Action: r.action, Reason: "blocked keyword", Match: keyword}
+		}
+	}
+	return nil
+}
+
+// RegexRule matches any of a list of regular expressions
+type RegexRule struct {
+	name     string
+	action   string
+	reason   string
+	patterns []*regexp.Regexp
+}
+
+func NewRegexRule(name, action, reason string, patterns []string) (*RegexRule, error) {
+	rule := &RegexRule{name: name, action: action, reason: reason}
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for rule %s: %v", pattern, name, err)
+		}
+		rule.patterns = append(rule.patterns, compiled)
+	}
+	return rule, nil
+}
+
+// NewJailbreakRule matches the built-in prompt-injection patterns
+func NewJailbreakRule(action string) *RegexRule {
+	rule, err := NewRegexRule("jailbreak", action, "prompt injection attempt", jailbreakPatterns)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func (r *RegexRule) Name() string { return r.name }
+
+func (r *RegexRule) Check(text string) *Violation {
+	for _, pattern := range r.patterns {
+		if match := pattern.FindString(text); match != "" {
+			return &Violation{Rule: r.name, Action: r.action, Reason: r.reason, Match: match}
+		}
+	}
+	return nil
+}
+
+// LengthRule matches prompts longer than maxRunes characters
+type LengthRule struct {
+	action   string
+	maxRunes int
+}
+
+func NewLengthRule(action string, maxRunes int) *LengthRule {
+	return &LengthRule{action: action, maxRunes: maxRunes}
+}
+
+func (r *LengthRule) Name() string { return "length" }
+
+func (r *LengthRule) Check(text string) *Violation {
+	if r.maxRunes <= 0 {
+		return nil
+	}
+	if length := utf8.RuneCountInString(text); length > r.maxRunes {
+		return &Violation{Rule: r.Name(), Action: r.action, Reason: fmt.Sprintf("prompt has %d characters, limit is %d", length, r.maxRunes)}
+	}
+	return nil
+}
+
+// EntropyRule matches long ASCII tokens with high entropy such as base64 encoded payloads,
+// and prompts made of one character repeated over and over. Thai text is not split by spaces
+// so only ASCII tokens are measured
+type EntropyRule struct {
+	action    string
+	threshold float64
+	minRunes  int
+}
+
+func NewEntropyRule(action string, threshold float64, minRunes int) *EntropyRule {
+	return &EntropyRule{action: action, threshold: threshold, minRunes: minRunes}
+}
+
+func (r *EntropyRule) Name() string { return "entropy" }
+
+func (r *EntropyRule) Check(text string) *Violation {
+	for _, word := range strings.Fields(text) {
+		if utf8.RuneCountInString(word) < r.minRunes || !isASCII(word) {
+			continue
+		}
+		if entropy := shannonEntropy(word); r.threshold > 0 && entropy > r.threshold {
+			return &Violation{Rule: r.Name(), Action: r.action, Reason: fmt.Sprintf("high entropy token (%.2f bits)", entropy), Match: excerpt(word, 40)}
+		}
+	}
+
+	if length := utf8.RuneCountInString(text); length >= r.minRunes && shannonEntropy(text) < 1 {
+		return &Violation{Rule: r.Name(), Action: r.action, Reason: "repetitive prompt"}
+	}
+	return nil
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// shannonEntropy returns the entropy of text in bits per character
+func shannonEntropy(text string) float64 {
+	counts := map[rune]int{}
+	total := 0
+	for _, r := range text {
+		counts[r]++
+		total++
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func excerpt(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes]) + "…"
+}
@@ -0,0 +1,280 @@
+package orgpolicy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   PolicyService
+	validator *validator.Validate
+}
+
+func NewHandler(service PolicyService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+func (h *Handler) GetPIIPolicyHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := GetPIIPolicyRequest{
+		OrgId: c.Param("orgID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetPIIPolicyService(ctx, req)
+	if err != nil {
+		logger.Error("error while get pii policy : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) UpdatePIIPolicyHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req UpdatePIIPolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+	req.OrgId = c.Param("orgID")
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.UpdatePIIPolicyService(ctx, req)
+	if err != nil {
+		logger.Error("error while update pii policy : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("pii policy updated", "orgId", req.OrgId, "by", c.GetString("userId"))
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) GetRetentionPolicyHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := GetRetentionPolicyRequest{
+		OrgId: c.Param("orgID"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetRetentionPolicyService(ctx, req)
+	if err != nil {
+		logger.Error("error while get retention policy : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) UpdateRetentionPolicyHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req UpdateRetentionPolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+	req.OrgId = c.Param("orgID")
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.UpdateRetentionPolicyService(ctx, req)
+	if err != nil {
+		logger.Error("error while update retention policy : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("retention policy updated", "orgId", req.OrgId, "by", c.GetString("userId"))
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) CreateOrganizationHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req CreateOrganizationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := h.service.CreateOrganizationService(ctx, req)
+	if err != nil {
+		logger.Error("error while create organization : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("organization created", "orgId", resp.OrgId, "by", req.UserId)
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) AddMemberHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := MemberRequest{
+		OrgId:        c.Param("orgID"),
+		MemberUserId: c.Param("userID"),
+		UserId:       c.GetString("userId"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.AddMemberService(ctx, req)
+	if err != nil {
+		logger.Error("error while add organization member : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("organization member added", "orgId", req.OrgId, "userId", req.MemberUserId, "by", req.UserId)
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) RemoveMemberHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := MemberRequest{
+		OrgId:        c.Param("orgID"),
+		MemberUserId: c.Param("userID"),
+		UserId:       c.GetString("userId"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.RemoveMemberService(ctx, req)
+	if err != nil {
+		logger.Error("error while remove organization member : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("organization member removed", "orgId", req.OrgId, "userId", req.MemberUserId, "by", req.UserId)
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func writeServiceError(c *gin.Context, err error) {
+	if errors.Is(err, ErrOrganizationNotFound) || errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, app.Response{
+			Code:    app.NotFoundErrorCode,
+			Message: app.NotFoundErrorMessage,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, app.Response{
+		Code:    app.InternalServerErrorCode,
+		Message: app.InternalServerErrorMessage,
+	})
+}
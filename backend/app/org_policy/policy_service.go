@@ -0,0 +1,213 @@
+package orgpolicy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/app/pii"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	cfg     *config.PII
+	storage PolicyStorage
+	audit   audit.AuditService
+}
+
+func NewService(cfg *config.PII, storage PolicyStorage, auditService audit.AuditService) *Service {
+	return &Service{
+		cfg:     cfg,
+		storage: storage,
+		audit:   auditService,
+	}
+}
+
+func (s *Service) GetPIIPolicyService(ctx context.Context, req GetPIIPolicyRequest) (PIIPolicyResponse, error) {
+	data, err := s.storage.GetPIIPolicyStorage(ctx, req.OrgId)
+	if err != nil {
+		return PIIPolicyResponse{}, fmt.Errorf("failed to get pii policy in storage error : %w", err)
+	}
+
+	if data == nil {
+		return PIIPolicyResponse{
+			OrgId:     req.OrgId,
+			IsDefault: true,
+			Policy: pii.Policy{
+				Enabled:         s.cfg.Enabled,
+				Entities:        s.cfg.Entities,
+				RestoreResponse: s.cfg.RestoreResponse,
+				StoreMode:       s.cfg.StoreMode,
+			},
+		}, nil
+	}
+
+	return PIIPolicyResponse{
+		OrgId:     data.OrgId,
+		Policy:    data.Policy,
+		UpdatedAt: &data.UpdatedAt,
+	}, nil
+}
+
+func (s *Service) UpdatePIIPolicyService(ctx context.Context, req UpdatePIIPolicyRequest) (PIIPolicyResponse, error) {
+	// the policy in effect before the change, the default one when the organization had none
+	previous, err := s.GetPIIPolicyService(ctx, GetPIIPolicyRequest{OrgId: req.OrgId})
+	if err != nil {
+		return PIIPolicyResponse{}, err
+	}
+
+	entities := req.Entities
+	if entities == nil {
+		entities = []string{}
+	}
+
+	data := PIIPolicyData{
+		OrgId: req.OrgId,
+		Policy: pii.Policy{
+			Enabled:         req.Enabled,
+			Entities:        entities,
+			RestoreResponse: req.RestoreResponse,
+			StoreMode:       req.StoreMode,
+		},
+		UpdatedAt: time.Now(),
+	}
+
+	err = s.storage.UpsertPIIPolicyStorage(ctx, data)
+	if err != nil {
+		return PIIPolicyResponse{}, fmt.Errorf("failed to update pii policy in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminPIIPolicyUpdate,
+		TargetType: audit.TargetOrg,
+		TargetId:   req.OrgId,
+		Before:     previous.Policy,
+		After:      data.Policy,
+	})
+
+	return PIIPolicyResponse{
+		OrgId:     data.OrgId,
+		Policy:    data.Policy,
+		UpdatedAt: &data.UpdatedAt,
+	}, nil
+}
+
+func (s *Service) GetRetentionPolicyService(ctx context.Context, req GetRetentionPolicyRequest) (RetentionPolicyResponse, error) {
+	data, err := s.storage.GetRetentionPolicyStorage(ctx, req.OrgId)
+	if err != nil {
+		return RetentionPolicyResponse{}, fmt.Errorf("failed to get retention policy in storage error : %w", err)
+	}
+
+	// without a policy the retention of each user's plan applies
+	if data == nil {
+		return RetentionPolicyResponse{
+			OrgId:     req.OrgId,
+			IsDefault: true,
+		}, nil
+	}
+
+	return RetentionPolicyResponse{
+		OrgId:                data.OrgId,
+		MessageRetentionDays: data.MessageRetentionDays,
+		UpdatedAt:            &data.UpdatedAt,
+	}, nil
+}
+
+func (s *Service) UpdateRetentionPolicyService(ctx context.Context, req UpdateRetentionPolicyRequest) (RetentionPolicyResponse, error) {
+	previous, err := s.GetRetentionPolicyService(ctx, GetRetentionPolicyRequest{OrgId: req.OrgId})
+	if err != nil {
+		return RetentionPolicyResponse{}, err
+	}
+
+	data := RetentionPolicyData{
+		OrgId:                req.OrgId,
+		MessageRetentionDays: req.MessageRetentionDays,
+		UpdatedAt:            time.Now(),
+	}
+
+	err = s.storage.UpsertRetentionPolicyStorage(ctx, data)
+	if err != nil {
+		return RetentionPolicyResponse{}, fmt.Errorf("failed to update retention policy in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminRetentionUpdate,
+		TargetType: audit.TargetOrg,
+		TargetId:   req.OrgId,
+		Before:     map[string]any{"messageRetentionDays": previous.MessageRetentionDays},
+		After:      map[string]any{"messageRetentionDays": data.MessageRetentionDays},
+	})
+
+	return RetentionPolicyResponse{
+		OrgId:                data.OrgId,
+		MessageRetentionDays: data.MessageRetentionDays,
+		UpdatedAt:            &data.UpdatedAt,
+	}, nil
+}
+
+func (s *Service) CreateOrganizationService(ctx context.Context, req CreateOrganizationRequest) (OrganizationResponse, error) {
+	data := OrganizationData{
+		OrgId:     uuid.NewString(),
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+	}
+
+	err := s.storage.CreateOrganizationStorage(ctx, data)
+	if err != nil {
+		return OrganizationResponse{}, fmt.Errorf("failed to create organization in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminOrgCreate,
+		TargetType: audit.TargetOrg,
+		TargetId:   data.OrgId,
+		After:      map[string]any{"name": data.Name},
+	})
+
+	return OrganizationResponse{
+		OrgId:     data.OrgId,
+		Name:      data.Name,
+		CreatedAt: data.CreatedAt,
+	}, nil
+}
+
+// AddMemberService moves the user into the organization, its PII and retention policies apply to them from then on
+func (s *Service) AddMemberService(ctx context.Context, req MemberRequest) error {
+	previous, err := s.storage.SetUserOrganizationStorage(ctx, req.MemberUserId, req.OrgId)
+	if err != nil {
+		return fmt.Errorf("failed to set user organization in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminOrgMemberAdd,
+		TargetType: audit.TargetUser,
+		TargetId:   req.MemberUserId,
+		Before:     map[string]any{"orgId": previous},
+		After:      map[string]any{"orgId": req.OrgId},
+	})
+	return nil
+}
+
+// RemoveMemberService takes the user out of the organization, the PII_* config defaults apply to them again
+func (s *Service) RemoveMemberService(ctx context.Context, req MemberRequest) error {
+	err := s.storage.RemoveUserOrganizationStorage(ctx, req.MemberUserId, req.OrgId)
+	if err != nil {
+		return fmt.Errorf("failed to remove user organization in storage error : %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminOrgMemberRemove,
+		TargetType: audit.TargetUser,
+		TargetId:   req.MemberUserId,
+		Before:     map[string]any{"orgId": req.OrgId},
+		After:      map[string]any{"orgId": nil},
+	})
+	return nil
+}
@@ -0,0 +1,193 @@
+package orgpolicy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+// GetPIIPolicyStorage returns nil when the organization exists but has no policy of its own
+func (s *Storage) GetPIIPolicyStorage(ctx context.Context, orgId string) (*PIIPolicyData, error) {
+	query := `
+		SELECT o.org_id, p.enabled, p.entity_types, p.restore_response, p.store_mode, p.updated_at
+		FROM organizations o
+		LEFT JOIN org_pii_policies p ON p.org_id = o.org_id
+		WHERE o.org_id = $1
+	`
+
+	var data PIIPolicyData
+	var enabled, restoreResponse *bool
+	var storeMode *string
+	var updatedAt *time.Time
+	err := s.db.QueryRow(ctx, query, orgId).Scan(
+		&data.OrgId,
+		&enabled,
+		&data.Policy.Entities,
+		&restoreResponse,
+		&storeMode,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	if enabled == nil {
+		return nil, nil
+	}
+
+	data.Policy.Enabled = *enabled
+	data.Policy.RestoreResponse = *restoreResponse
+	data.Policy.StoreMode = *storeMode
+	data.UpdatedAt = *updatedAt
+	return &data, nil
+}
+
+func (s *Storage) UpsertPIIPolicyStorage(ctx context.Context, data PIIPolicyData) error {
+	query := `
+		INSERT INTO org_pii_policies (org_id, enabled, entity_types, restore_response, store_mode, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (org_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			entity_types = EXCLUDED.entity_types,
+			restore_response = EXCLUDED.restore_response,
+			store_mode = EXCLUDED.store_mode,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := s.db.Exec(ctx, query,
+		data.OrgId,
+		data.Policy.Enabled,
+		data.Policy.Entities,
+		data.Policy.RestoreResponse,
+		data.Policy.StoreMode,
+		data.UpdatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrganizationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetRetentionPolicyStorage returns nil when the organization exists but has no policy of its own
+func (s *Storage) GetRetentionPolicyStorage(ctx context.Context, orgId string) (*RetentionPolicyData, error) {
+	query := `
+		SELECT o.org_id, r.message_retention_days, r.updated_at
+		FROM organizations o
+		LEFT JOIN org_retention_policies r ON r.org_id = o.org_id
+		WHERE o.org_id = $1
+	`
+
+	var data RetentionPolicyData
+	var updatedAt *time.Time
+	err := s.db.QueryRow(ctx, query, orgId).Scan(
+		&data.OrgId,
+		&data.MessageRetentionDays,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	if updatedAt == nil {
+		return nil, nil
+	}
+
+	data.UpdatedAt = *updatedAt
+	return &data, nil
+}
+
+func (s *Storage) UpsertRetentionPolicyStorage(ctx context.Context, data RetentionPolicyData) error {
+	query := `
+		INSERT INTO org_retention_policies (org_id, message_retention_days, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (org_id) DO UPDATE
+		SET message_retention_days = EXCLUDED.message_retention_days,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := s.db.Exec(ctx, query, data.OrgId, data.MessageRetentionDays, data.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrganizationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) CreateOrganizationStorage(ctx context.Context, data OrganizationData) error {
+	query := `
+		INSERT INTO organizations (org_id, name, created_at)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := s.db.Exec(ctx, query, data.OrgId, data.Name, data.CreatedAt)
+	return err
+}
+
+// SetUserOrganizationStorage moves the user into the organization and returns the organization they were in before
+func (s *Storage) SetUserOrganizationStorage(ctx context.Context, userId, orgId string) (*string, error) {
+	query := `
+		WITH previous AS (
+			SELECT user_id, org_id FROM users WHERE user_id = $1 FOR UPDATE
+		)
+		UPDATE users u
+		SET org_id = $2
+		FROM previous p
+		WHERE u.user_id = p.user_id
+		RETURNING p.org_id
+	`
+
+	var previous *string
+	err := s.db.QueryRow(ctx, query, userId, orgId).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return previous, nil
+}
+
+// RemoveUserOrganizationStorage returns ErrUserNotFound when the user is not a member of the organization
+func (s *Storage) RemoveUserOrganizationStorage(ctx context.Context, userId, orgId string) error {
+	query := `
+		UPDATE users
+		SET org_id = NULL
+		WHERE user_id = $1 AND org_id = $2
+	`
+
+	cmdTag, err := s.db.Exec(ctx, query, userId, orgId)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
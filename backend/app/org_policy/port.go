@@ -0,0 +1,112 @@
+package orgpolicy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/pii"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+type PolicyService interface {
+	GetPIIPolicyService(ctx context.Context, req GetPIIPolicyRequest) (PIIPolicyResponse, error)
+	UpdatePIIPolicyService(ctx context.Context, req UpdatePIIPolicyRequest) (PIIPolicyResponse, error)
+	GetRetentionPolicyService(ctx context.Context, req GetRetentionPolicyRequest) (RetentionPolicyResponse, error)
+	UpdateRetentionPolicyService(ctx context.Context, req UpdateRetentionPolicyRequest) (RetentionPolicyResponse, error)
+	CreateOrganizationService(ctx context.Context, req CreateOrganizationRequest) (OrganizationResponse, error)
+	AddMemberService(ctx context.Context, req MemberRequest) error
+	RemoveMemberService(ctx context.Context, req MemberRequest) error
+}
+
+type PolicyStorage interface {
+	GetPIIPolicyStorage(ctx context.Context, orgId string) (*PIIPolicyData, error)
+	UpsertPIIPolicyStorage(ctx context.Context, data PIIPolicyData) error
+	GetRetentionPolicyStorage(ctx context.Context, orgId string) (*RetentionPolicyData, error)
+	UpsertRetentionPolicyStorage(ctx context.Context, data RetentionPolicyData) error
+	CreateOrganizationStorage(ctx context.Context, data OrganizationData) error
+	SetUserOrganizationStorage(ctx context.Context, userId, orgId string) (*string, error)
+	RemoveUserOrganizationStorage(ctx context.Context, userId, orgId string) error
+}
+
+type GetPIIPolicyRequest struct {
+	OrgId string `json:"orgId" validate:"required,uuid"`
+}
+
+type UpdatePIIPolicyRequest struct {
+	OrgId           string   `json:"orgId" validate:"required,uuid"`
+	Enabled         bool     `json:"enabled"`
+	Entities        []string `json:"entities" validate:"unique,dive,oneof=national_id phone email bank_account address"`
+	RestoreResponse bool     `json:"restoreResponse"`
+	StoreMode       string   `json:"storeMode" validate:"required,oneof=redacted original"`
+	// UserId is the admin changing the policy
+	UserId string `json:"-"`
+}
+
+type PIIPolicyResponse struct {
+	OrgId     string     `json:"orgId"`
+	IsDefault bool       `json:"isDefault"`
+	Policy    pii.Policy `json:"policy"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+type PIIPolicyData struct {
+	OrgId     string     `db:"org_id"`
+	Policy    pii.Policy `db:"-"`
+	UpdatedAt time.Time  `db:"updated_at"`
+}
+
+type GetRetentionPolicyRequest struct {
+	OrgId string `json:"orgId" validate:"required,uuid"`
+}
+
+type UpdateRetentionPolicyRequest struct {
+	OrgId string `json:"orgId" validate:"required,uuid"`
+	// MessageRetentionDays is nil to use the retention of each user's plan
+	MessageRetentionDays *int `json:"messageRetentionDays" validate:"omitempty,min=1,max=36500"`
+	// UserId is the admin changing the policy
+	UserId string `json:"-"`
+}
+
+type RetentionPolicyResponse struct {
+	OrgId                string     `json:"orgId"`
+	IsDefault            bool       `json:"isDefault"`
+	MessageRetentionDays *int       `json:"messageRetentionDays"`
+	UpdatedAt            *time.Time `json:"updatedAt"`
+}
+
+type RetentionPolicyData struct {
+	OrgId                string    `db:"org_id"`
+	MessageRetentionDays *int      `db:"message_retention_days"`
+	UpdatedAt            time.Time `db:"updated_at"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=200"`
+	// UserId is the admin creating the organization
+	UserId string `json:"-"`
+}
+
+type OrganizationResponse struct {
+	OrgId     string    `json:"orgId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type OrganizationData struct {
+	OrgId     string    `db:"org_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// MemberRequest adds a user to an organization or removes them from it, a user belongs to one organization at a time
+type MemberRequest struct {
+	OrgId        string `json:"orgId" validate:"required,uuid"`
+	MemberUserId string `json:"userId" validate:"required,uuid"`
+	// UserId is the admin changing the membership
+	UserId string `json:"-"`
+}
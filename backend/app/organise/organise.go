@@ -0,0 +1,42 @@
+package organise
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier is satisfied by both the pool and a transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NormalizeTags collapses whitespace and drops empty or duplicated tags
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// FolderOwned reports whether the folder exists and belongs to the user
+func FolderOwned(ctx context.Context, db Querier, userId, folderId string) (bool, error) {
+	query := `SELECT COUNT(1) FROM session_folders WHERE user_id = $1 AND folder_id = $2`
+
+	var count int
+	err := db.QueryRow(ctx, query, userId, folderId).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking folder owner: %v", err)
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,130 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Entity types that can be detected
+const (
+	EntityEmail       = "email"
+	EntityBankAccount = "bank_account"
+	EntityNationalID  = "national_id"
+	EntityPhone       = "phone"
+	EntityAddress     = "address"
+)
+
+// AllEntities lists every entity type in the order they are detected
+var AllEntities = []string{EntityEmail, EntityBankAccount, EntityNationalID, EntityPhone, EntityAddress}
+
+// rule replaces every match of pattern (or of its first group when it has one) with a placeholder naming the kind of data removed
+type rule struct {
+	entity      string
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// rules run in order: email first so the local part is not picked up as a phone number,
+// bank accounts before phone numbers and national IDs because both are plain digit runs
+var rules = []rule{
+	{EntityEmail, regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "EMAIL"},
+	// account numbers written as 123-4-56789-0, or any digit run following "บัญชี" / "account"
+	{EntityBankAccount, regexp.MustCompile(`(?i)(?:บัญชี(?:เลขที่)?|account(?:\s*no\.?)?)\s*[:：]?\s*(\d[\d -]{7,16}\d)`), "BANK_ACCOUNT"},
+	{EntityBankAccount, regexp.MustCompile(`\b\d{3}-\d-\d{5}-\d\b`), "BANK_ACCOUNT"},
+	// Thai national ID, 13 digits written plain or as 1-2345-67890-12-3
+	{EntityNationalID, regexp.MustCompile(`\b\d[ -]?\d{4}[ -]?\d{5}[ -]?\d{2}[ -]?\d\b`), "NATIONAL_ID"},
+	// Thai mobile and landline numbers, local or +66 prefixed
+	{EntityPhone, regexp.MustCompile(`(?:\+66[ -]?|\b0)[1-9](?:[ -]?\d){7,8}\b`), "PHONE"},
+	// house number followed by the usual address parts up to an optional postcode
+	{EntityAddress, regexp.MustCompile(`(?:บ้านเลขที่|เลขที่)\s*\d+(?:/\d+)?(?:\s*(?:หมู่(?:ที่)?|ม\.|ซอย|ซ\.|ถนน|ถ\.|ตำบล|ต\.|แขวง|อำเภอ|อ\.|เขต|จังหวัด|จ\.)\s*[^\s,]+)*(?:\s*\d{5})?`), "ADDRESS"},
+}
+
+// Scrub removes every kind of personal data from text, replacing it with an unnumbered placeholder such as [EMAIL]
+func Scrub(text string) string {
+	for _, r := range rules {
+		text = replace(r, text, func(string) string {
+			return "[" + r.placeholder + "]"
+		})
+	}
+	return text
+}
+
+// Redact replaces the entity types listed in entities with numbered placeholders such as [PHONE_1]
+// and returns the vault needed to put the original values back
+func Redact(text string, entities []string) (string, *Vault) {
+	vault := NewVault()
+	for _, r := range rules {
+		if !slices.Contains(entities, r.entity) {
+			continue
+		}
+		text = replace(r, text, func(value string) string {
+			return vault.placeholderFor(r.placeholder, value)
+		})
+	}
+	return text, vault
+}
+
+func replace(r rule, text string, placeholder func(value string) string) string {
+	if r.pattern.NumSubexp() == 0 {
+		return r.pattern.ReplaceAllStringFunc(text, placeholder)
+	}
+
+	// only the captured group is personal data, the keyword in front of it is kept
+	var out []byte
+	last := 0
+	for _, loc := range r.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2], loc[3]
+		out = append(out, text[last:start]...)
+		out = append(out, placeholder(text[start:end])...)
+		last = end
+	}
+	return string(append(out, text[last:]...))
+}
+
+// Vault remembers which value every placeholder replaced
+type Vault struct {
+	values  map[string]string
+	byValue map[string]string
+	counts  map[string]int
+}
+
+func NewVault() *Vault {
+	return &Vault{
+		values:  map[string]string{},
+		byValue: map[string]string{},
+		counts:  map[string]int{},
+	}
+}
+
+// placeholderFor returns the placeholder of value, the same value always gets the same placeholder
+func (v *Vault) placeholderFor(kind, value string) string {
+	if placeholder, ok := v.byValue[value]; ok {
+		return placeholder
+	}
+	v.counts[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, v.counts[kind])
+	v.values[placeholder] = value
+	v.byValue[value] = placeholder
+	return placeholder
+}
+
+// Len returns the number of distinct values redacted
+func (v *Vault) Len() int {
+	return len(v.values)
+}
+
+var placeholderPattern = regexp.MustCompile(`\[[A-Z_]+_\d+\]`)
+
+// Restore puts the original values back in place of the placeholders found in text
+func (v *Vault) Restore(text string) string {
+	if v.Len() == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := v.values[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
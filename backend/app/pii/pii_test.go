@@ -0,0 +1,124 @@
+package pii
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []string
+		want     string
+	}{
+		{
+			name:     "email",
+			text:     "ติดต่อ somchai.j@example.co.th ได้เลย",
+			entities: AllEntities,
+			want:     "ติดต่อ [EMAIL_1] ได้เลย",
+		},
+		{
+			name:     "mobile phone",
+			text:     "โทร 081-234-5678 หรือ 0812345678",
+			entities: AllEntities,
+			want:     "โทร [PHONE_1] หรือ [PHONE_2]",
+		},
+		{
+			name:     "international phone",
+			text:     "call +66 81 234 5678",
+			entities: AllEntities,
+			want:     "call [PHONE_1]",
+		},
+		{
+			name:     "national id",
+			text:     "เลขบัตร 1-2345-67890-12-3 และ 1234567890123",
+			entities: AllEntities,
+			want:     "เลขบัตร [NATIONAL_ID_1] และ [NATIONAL_ID_2]",
+		},
+		{
+			name:     "bank account keeps the keyword",
+			text:     "โอนเข้าบัญชีเลขที่ 123-4-56789-0 ครับ",
+			entities: AllEntities,
+			want:     "โอนเข้าบัญชีเลขที่ [BANK_ACCOUNT_1] ครับ",
+		},
+		{
+			name:     "address",
+			text:     "อยู่บ้านเลขที่ 12/3 หมู่ 4 ตำบล บางพลี จังหวัด สมุทรปราการ 10540 ค่ะ",
+			entities: AllEntities,
+			want:     "อยู่[ADDRESS_1] ค่ะ",
+		},
+		{
+			name:     "same value gets the same placeholder",
+			text:     "a@b.co ส่งถึง a@b.co และ c@d.co",
+			entities: AllEntities,
+			want:     "[EMAIL_1] ส่งถึง [EMAIL_1] และ [EMAIL_2]",
+		},
+		{
+			name:     "only the listed entities",
+			text:     "a@b.co โทร 0812345678",
+			entities: []string{EntityPhone},
+			want:     "a@b.co โทร [PHONE_1]",
+		},
+		{
+			name:     "nothing listed",
+			text:     "a@b.co โทร 0812345678",
+			entities: nil,
+			want:     "a@b.co โทร 0812345678",
+		},
+		{
+			name:     "no personal data",
+			text:     "มาตรา 112 ของประมวลกฎหมายอาญา",
+			entities: AllEntities,
+			want:     "มาตรา 112 ของประมวลกฎหมายอาญา",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, vault := Redact(tt.text, tt.entities)
+			if got != tt.want {
+				t.Fatalf("Redact() = %q, want %q", got, tt.want)
+			}
+			if restored := vault.Restore(got); restored != tt.text {
+				t.Fatalf("Restore() = %q, want %q", restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	_, vault := Redact("a@b.co 0812345678", AllEntities)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"known placeholders", "ส่งไปที่ [EMAIL_1] และโทร [PHONE_1]", "ส่งไปที่ a@b.co และโทร 0812345678"},
+		{"repeated placeholder", "[EMAIL_1], [EMAIL_1]", "a@b.co, a@b.co"},
+		{"unknown placeholder is kept", "[EMAIL_2] [NATIONAL_ID_1]", "[EMAIL_2] [NATIONAL_ID_1]"},
+		{"brackets that are not placeholders", "[1] [email_1] [EMAIL]", "[1] [email_1] [EMAIL]"},
+		{"no placeholders", "ไม่มีข้อมูลส่วนตัว", "ไม่มีข้อมูลส่วนตัว"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vault.Restore(tt.text); got != tt.want {
+				t.Fatalf("Restore() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreEmptyVault(t *testing.T) {
+	text := "[EMAIL_1] stays"
+	if got := NewVault().Restore(text); got != text {
+		t.Fatalf("Restore() = %q, want %q", got, text)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	got := Scrub("a@b.co โทร 081-234-5678 บัตร 1234567890123")
+	want := "[EMAIL] โทร [PHONE] บัตร [NATIONAL_ID]"
+	if got != want {
+		t.Fatalf("Scrub() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package pii
+
+import (
+	"fmt"
+	"slices"
+)
+
+// How the question and the answer are stored when redaction is enabled
+const (
+	StoreRedacted = "redacted"
+	StoreOriginal = "original"
+)
+
+// Policy controls the redaction of prompts for the users of an organization
+type Policy struct {
+	Enabled         bool     `json:"enabled"`
+	Entities        []string `json:"entities"`
+	RestoreResponse bool     `json:"restoreResponse"`
+	StoreMode       string   `json:"storeMode"`
+}
+
+// Validate returns an error for a store mode or entity type the redaction does not know,
+// which would otherwise be ignored and fall back to storing redacted text
+func (p Policy) Validate() error {
+	if p.StoreMode != StoreRedacted && p.StoreMode != StoreOriginal {
+		return fmt.Errorf("unknown pii store mode %q, expected %s or %s", p.StoreMode, StoreRedacted, StoreOriginal)
+	}
+	for _, entity := range p.Entities {
+		if !slices.Contains(AllEntities, entity) {
+			return fmt.Errorf("unknown pii entity type %q", entity)
+		}
+	}
+	return nil
+}
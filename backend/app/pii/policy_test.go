@@ -0,0 +1,27 @@
+package pii
+
+import "testing"
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"redacted", Policy{StoreMode: StoreRedacted, Entities: AllEntities}, false},
+		{"original", Policy{StoreMode: StoreOriginal, Entities: []string{EntityEmail}}, false},
+		{"no entities", Policy{StoreMode: StoreRedacted}, false},
+		{"misspelled store mode", Policy{StoreMode: "orignal"}, true},
+		{"empty store mode", Policy{}, true},
+		{"unknown entity", Policy{StoreMode: StoreRedacted, Entities: []string{"phone_number"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
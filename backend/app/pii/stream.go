@@ -0,0 +1,35 @@
+package pii
+
+import "strings"
+
+// maxPlaceholderLen bounds how much text is held back while waiting for the end of a placeholder
+const maxPlaceholderLen = 24
+
+// StreamRestorer restores placeholders in streamed text where a placeholder may be split across chunks
+type StreamRestorer struct {
+	vault   *Vault
+	pending string
+}
+
+func NewStreamRestorer(vault *Vault) *StreamRestorer {
+	return &StreamRestorer{vault: vault}
+}
+
+// Write returns the part of chunk that can be sent, an unfinished placeholder at the end is held back
+func (r *StreamRestorer) Write(chunk string) string {
+	text := r.pending + chunk
+	r.pending = ""
+
+	if idx := strings.LastIndex(text, "["); idx >= 0 && !strings.Contains(text[idx:], "]") && len(text)-idx < maxPlaceholderLen {
+		r.pending = text[idx:]
+		text = text[:idx]
+	}
+	return r.vault.Restore(text)
+}
+
+// Flush returns the text still held back
+func (r *StreamRestorer) Flush() string {
+	text := r.vault.Restore(r.pending)
+	r.pending = ""
+	return text
+}
@@ -0,0 +1,81 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamRestorer(t *testing.T) {
+	_, vault := Redact("a@b.co 0812345678", AllEntities)
+
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{
+			name:   "placeholder in one chunk",
+			chunks: []string{"ติดต่อ [EMAIL_1] ", "ได้เลย"},
+			want:   "ติดต่อ a@b.co ได้เลย",
+		},
+		{
+			name:   "placeholder split in two",
+			chunks: []string{"ติดต่อ [EMA", "IL_1] ได้เลย"},
+			want:   "ติดต่อ a@b.co ได้เลย",
+		},
+		{
+			name:   "placeholder split across many chunks",
+			chunks: []string{"โทร [", "PH", "ONE", "_", "1", "] นะ"},
+			want:   "โทร 0812345678 นะ",
+		},
+		{
+			name:   "split right before the closing bracket",
+			chunks: []string{"[PHONE_1", "]"},
+			want:   "0812345678",
+		},
+		{
+			name:   "two placeholders, the second split",
+			chunks: []string{"[EMAIL_1] และ [PHO", "NE_1]"},
+			want:   "a@b.co และ 0812345678",
+		},
+		{
+			name:   "unfinished placeholder is flushed as is",
+			chunks: []string{"ดู [PHONE_"},
+			want:   "ดู [PHONE_",
+		},
+		{
+			name:   "long bracketed text is not held back",
+			chunks: []string{"[" + strings.Repeat("ก", 30), " จบ]"},
+			want:   "[" + strings.Repeat("ก", 30) + " จบ]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restorer := NewStreamRestorer(vault)
+			var out strings.Builder
+			for _, chunk := range tt.chunks {
+				out.WriteString(restorer.Write(chunk))
+			}
+			out.WriteString(restorer.Flush())
+			if got := out.String(); got != tt.want {
+				t.Fatalf("restored stream = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamRestorerHoldsBackPlaceholder(t *testing.T) {
+	_, vault := Redact("0812345678", AllEntities)
+	restorer := NewStreamRestorer(vault)
+
+	if got := restorer.Write("โทร [PHO"); got != "โทร " {
+		t.Fatalf("Write() = %q, want the text before the placeholder", got)
+	}
+	if got := restorer.Write("NE_1] นะ"); got != "0812345678 นะ" {
+		t.Fatalf("Write() = %q, want the restored placeholder", got)
+	}
+	if got := restorer.Flush(); got != "" {
+		t.Fatalf("Flush() = %q, want nothing held back", got)
+	}
+}
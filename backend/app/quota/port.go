@@ -13,4 +13,6 @@ type QuotaService interface {
 	CheckQuota(ctx context.Context, userID string) (*QuotaStatus, error)
 	ConsumeTokens(ctx context.Context, userID string, totalTokens int64) error
 	CheckPromptLength(text string) (int, error)
+	ClearQuota(ctx context.Context, userID string) error
+	PurgeStaleQuotaKeys(ctx context.Context) (int64, error)
 }
@@ -3,17 +3,23 @@ package quota
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Service struct {
 	redis           *redis.Client
 	dailyLimit      int64
 	maxPromptTokens int
+	failOpen        bool
 }
 
 func NewQuotaService(redisClient *redis.Client, cfg *config.Quota) *Service {
@@ -21,6 +27,7 @@ func NewQuotaService(redisClient *redis.Client, cfg *config.Quota) *Service {
 		redis:           redisClient,
 		dailyLimit:      cfg.DailyLimit,
 		maxPromptTokens: cfg.MaxPromptTokens,
+		failOpen:        cfg.FailOpen,
 	}
 }
 
@@ -30,6 +37,9 @@ func (s *Service) GetUserQuotaKey(userID string) string {
 }
 
 func (s *Service) CheckQuota(ctx context.Context, userID string) (*QuotaStatus, error) {
+	ctx, span := tracing.Start(ctx, "quota.CheckQuota")
+	defer span.End()
+
 	key := s.GetUserQuotaKey(userID)
 
 	used, err := s.redis.Get(ctx, key).Int64()
@@ -37,6 +47,14 @@ func (s *Service) CheckQuota(ctx context.Context, userID string) (*QuotaStatus,
 		// ยังไม่เคยใช้วันนี้
 		used = 0
 	} else if err != nil {
+		span.RecordError(err)
+		if s.failOpen {
+			logging.FromContext(ctx).Warn("quota check failed, letting the request through", "userId", userID, "error", err)
+			return &QuotaStatus{
+				UserID:    userID,
+				Remaining: s.dailyLimit,
+			}, nil
+		}
 		return nil, fmt.Errorf("failed to get quota: %w", err)
 	}
 
@@ -45,7 +63,9 @@ func (s *Service) CheckQuota(ctx context.Context, userID string) (*QuotaStatus,
 	if remaining <= 0 {
 		isExceeded = true
 		remaining = 0
+		metrics.QuotaRejected("daily_quota")
 	}
+	span.SetAttributes(attribute.Bool("quota.exceeded", isExceeded), attribute.Int64("quota.remaining", remaining))
 
 	return &QuotaStatus{
 		UserID:     userID,
@@ -56,6 +76,9 @@ func (s *Service) CheckQuota(ctx context.Context, userID string) (*QuotaStatus,
 }
 
 func (s *Service) ConsumeTokens(ctx context.Context, userID string, totalTokens int64) error {
+	ctx, span := tracing.Start(ctx, "quota.ConsumeTokens", attribute.Int64("quota.tokens", totalTokens))
+	defer span.End()
+
 	key := s.GetUserQuotaKey(userID)
 
 	pipe := s.redis.Pipeline()
@@ -70,12 +93,66 @@ func (s *Service) ConsumeTokens(ctx context.Context, userID string, totalTokens
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to consume tokens: %w", err)
 	}
 
 	return nil
 }
 
+// ClearQuota deletes every daily usage counter of the user
+func (s *Service) ClearQuota(ctx context.Context, userID string) error {
+	pattern := fmt.Sprintf("quota:user:%s:date:*", userID)
+	iter := s.redis.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := s.redis.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("failed to delete quota key: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan quota keys: %w", err)
+	}
+	return nil
+}
+
+// PurgeStaleQuotaKeys deletes the counters of past days that were left without a TTL,
+// like ones whose EXPIRE was lost, and gives today's counters missing one their TTL back
+func (s *Service) PurgeStaleQuotaKeys(ctx context.Context) (int64, error) {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+
+	var purged int64
+	iter := s.redis.Scan(ctx, 0, "quota:user:*:date:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		ttl, err := s.redis.TTL(ctx, key).Result()
+		if err != nil {
+			return purged, fmt.Errorf("failed to get quota key ttl: %w", err)
+		}
+		// -1 is a key without a TTL, the others expire on their own
+		if ttl != -1 {
+			continue
+		}
+
+		date := key[strings.LastIndex(key, ":")+1:]
+		if date >= today {
+			if err := s.redis.ExpireAt(ctx, key, midnight).Err(); err != nil {
+				return purged, fmt.Errorf("failed to set quota key ttl: %w", err)
+			}
+			continue
+		}
+		if err := s.redis.Del(ctx, key).Err(); err != nil {
+			return purged, fmt.Errorf("failed to delete quota key: %w", err)
+		}
+		purged++
+	}
+	if err := iter.Err(); err != nil {
+		return purged, fmt.Errorf("failed to scan quota keys: %w", err)
+	}
+	return purged, nil
+}
+
 func (s *Service) CheckPromptLength(text string) (int, error) {
 	var encoding *tiktoken.Tiktoken
 	encoding, err := tiktoken.EncodingForModel("gpt-4")
@@ -88,6 +165,7 @@ func (s *Service) CheckPromptLength(text string) (int, error) {
 	isWithinLimit := tokenCount <= s.maxPromptTokens
 
 	if !isWithinLimit {
+		metrics.QuotaRejected("prompt_length")
 		return 0, fmt.Errorf("Prompt exceeds maximum token limit")
 	}
 
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills the bucket for the time elapsed since the last call, then takes one token.
+// It returns {allowed, tokens left, milliseconds until a token is available}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens), retry}
+`)
+
+// Rule is a token bucket refilled with PerMinute tokens a minute holding at most Burst tokens
+type Rule struct {
+	Name      string
+	PerMinute int
+	Burst     int
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	// ResetAfter is the time until the bucket is full again
+	ResetAfter time.Duration
+}
+
+type Limiter struct {
+	redis   *redis.Client
+	enabled bool
+}
+
+// NewLimiter returns a limiter that lets everything through when it is disabled or Redis is not available
+func NewLimiter(redisClient *redis.Client, enabled bool) *Limiter {
+	return &Limiter{
+		redis:   redisClient,
+		enabled: enabled && redisClient != nil,
+	}
+}
+
+// Allow takes one token from the bucket of key under rule
+func (l *Limiter) Allow(ctx context.Context, rule Rule, key string) (Result, error) {
+	result := Result{
+		Allowed:   true,
+		Limit:     rule.Burst,
+		Remaining: rule.Burst,
+	}
+	if !l.enabled || rule.PerMinute <= 0 || rule.Burst <= 0 {
+		return result, nil
+	}
+
+	rate := float64(rule.PerMinute) / 60
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", rule.Name, key)
+	values, err := tokenBucketScript.Run(ctx, l.redis, []string{redisKey}, rate, rule.Burst, time.Now().UnixMilli()).Slice()
+	if err != nil {
+		return result, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 3 {
+		return result, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensText, _ := values[1].(string)
+	retryMs, _ := values[2].(int64)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse remaining tokens: %w", err)
+	}
+
+	result.Allowed = allowed == 1
+	result.Remaining = int(math.Floor(tokens))
+	result.RetryAfter = time.Duration(retryMs) * time.Millisecond
+	result.ResetAfter = time.Duration((float64(rule.Burst) - tokens) / rate * float64(time.Second))
+	return result, nil
+}
@@ -4,4 +4,6 @@ type Response struct {
 	Code    string    `json:"code"`
 	Message string `json:"message"`
 	Data    any    `json:"data,omitempty"`
+	// RequestID is filled in on error responses by the RequestID middleware
+	RequestID string `json:"requestId,omitempty"`
 }
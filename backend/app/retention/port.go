@@ -0,0 +1,31 @@
+package retention
+
+import (
+	"context"
+	"time"
+)
+
+type RetentionService interface {
+	// PurgeExpiredMessagesService deletes the sessions whose last message is past the retention of their owner's
+	// organization or plan, together with their messages
+	PurgeExpiredMessagesService(ctx context.Context) (int64, error)
+}
+
+type RetentionStorage interface {
+	// PurgeMessagesStorage deletes up to limit rows of the next kind of expired data and returns how many it deleted,
+	// plans and days pair every plan with its retention in days
+	PurgeMessagesStorage(ctx context.Context, step PurgeStep, plans []string, days []int32, now time.Time, limit int) (int64, error)
+}
+
+// PurgeStep is one kind of data the message retention deletes, in the order they are purged
+type PurgeStep string
+
+const (
+	PurgeSessions        PurgeStep = "chat_sessions"
+	PurgeModerationFlags PurgeStep = "moderation_flags"
+)
+
+var PurgeSteps = []PurgeStep{
+	PurgeSessions,
+	PurgeModerationFlags,
+}
@@ -0,0 +1,61 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/config"
+)
+
+type Service struct {
+	cfg     *config.Retention
+	storage RetentionStorage
+}
+
+func NewService(cfg *config.Retention, storage RetentionStorage) *Service {
+	return &Service{
+		cfg:     cfg,
+		storage: storage,
+	}
+}
+
+// PurgeExpiredMessagesService deletes in batches so no single statement holds locks on a large number of rows
+func (s *Service) PurgeExpiredMessagesService(ctx context.Context) (int64, error) {
+	plans := make([]string, 0, len(s.cfg.MessageDays))
+	days := make([]int32, 0, len(s.cfg.MessageDays))
+	for plan, planDays := range s.cfg.MessageDays {
+		if planDays <= 0 {
+			continue
+		}
+		plans = append(plans, plan)
+		days = append(days, int32(planDays))
+	}
+
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	now := time.Now()
+	var total int64
+	for _, step := range PurgeSteps {
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+
+			purged, err := s.storage.PurgeMessagesStorage(ctx, step, plans, days, now, batchSize)
+			if err != nil {
+				return total, fmt.Errorf("failed to purge %s in storage error : %w", step, err)
+			}
+			total += purged
+
+			if purged < int64(batchSize) {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
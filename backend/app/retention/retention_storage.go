@@ -0,0 +1,77 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// retentionPolicy resolves the retention in days of every user, the one of their organization
+// when it sets one and else the one of their plan. Users with NULL days keep their messages
+const retentionPolicy = `
+	WITH policy AS (
+		SELECT u.user_id, COALESCE(r.message_retention_days, p.days) AS days
+		FROM users u
+		LEFT JOIN org_retention_policies r ON r.org_id = u.org_id
+		LEFT JOIN unnest($1::text[], $2::int[]) AS p (plan, days) ON p.plan = u.plan
+	)
+`
+
+var purgeQueries = map[PurgeStep]string{
+	// messages are deleted a whole session at a time, once the newest of them has expired. Deleting single
+	// messages would leave the rest of the conversation pointing at parents that no longer exist.
+	// The sessions are locked so a message written meanwhile waits for the purge instead of joining it
+	PurgeSessions: retentionPolicy + `,
+	expired AS (
+		SELECT s.session_id
+		FROM chat_sessions s
+		JOIN policy ON policy.user_id = s.user_id
+		WHERE policy.days IS NOT NULL
+			AND COALESCE(s.last_message_at, s.created_at) < $3 - make_interval(days => policy.days)
+		LIMIT $4
+		FOR UPDATE OF s SKIP LOCKED
+	),
+	deleted_model_messages AS (
+		DELETE FROM model_messages WHERE session_id IN (SELECT session_id FROM expired)
+	),
+	deleted_user_messages AS (
+		DELETE FROM user_messages WHERE session_id IN (SELECT session_id FROM expired)
+	)
+	DELETE FROM chat_sessions
+	WHERE session_id IN (SELECT session_id FROM expired)
+	`,
+	PurgeModerationFlags: retentionPolicy + `
+		DELETE FROM moderation_flags
+		WHERE flag_id IN (
+			SELECT f.flag_id
+			FROM moderation_flags f
+			JOIN policy ON policy.user_id = f.user_id
+			WHERE policy.days IS NOT NULL
+				AND f.created_at < $3 - make_interval(days => policy.days)
+			LIMIT $4
+		)
+	`,
+}
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) PurgeMessagesStorage(ctx context.Context, step PurgeStep, plans []string, days []int32, now time.Time, limit int) (int64, error) {
+	query, ok := purgeQueries[step]
+	if !ok {
+		return 0, fmt.Errorf("unknown purge step %q", step)
+	}
+
+	result, err := s.db.Exec(ctx, query, plans, days, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
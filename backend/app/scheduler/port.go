@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Statuses of a finished job run
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is run every Interval by one replica at a time, Run returns how many rows or keys it removed
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+type SchedulerService interface {
+	// Register adds a job, it has to be called before Run. A job without an interval is disabled
+	Register(job Job)
+	// Run runs the due jobs every poll interval until ctx is cancelled
+	Run(ctx context.Context)
+	GetJobsService(ctx context.Context) ([]JobResponse, error)
+	// RunJobService starts a job now whether or not it is due
+	RunJobService(ctx context.Context, req RunJobRequest) error
+}
+
+type SchedulerStorage interface {
+	EnsureJobStorage(ctx context.Context, name string) error
+	// ClaimJobStorage locks the job for owner for lockTTL, it reports false when the job is locked by someone
+	// else or was last started less than interval ago. Times are taken from the database clock so replicas
+	// with skewed clocks agree on who holds the lock
+	ClaimJobStorage(ctx context.Context, name, owner string, interval, lockTTL time.Duration) (bool, error)
+	ExtendLockStorage(ctx context.Context, name, owner string, lockTTL time.Duration) error
+	// FinishJobStorage records the run and releases the lock, it reports false when owner had lost the lock
+	FinishJobStorage(ctx context.Context, data JobRunData) (bool, error)
+	GetJobsStorage(ctx context.Context) ([]JobData, error)
+}
+
+type RunJobRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	// UserId is the admin starting the job
+	UserId string `json:"-"`
+}
+
+type JobResponse struct {
+	Name           string     `json:"name"`
+	Interval       string     `json:"interval"`
+	Running        bool       `json:"running"`
+	LockedBy       *string    `json:"lockedBy"`
+	LockedUntil    *time.Time `json:"lockedUntil"`
+	LastStartedAt  *time.Time `json:"lastStartedAt"`
+	LastFinishedAt *time.Time `json:"lastFinishedAt"`
+	LastStatus     *string    `json:"lastStatus"`
+	LastError      *string    `json:"lastError"`
+	LastAffected   *int64     `json:"lastAffected"`
+	RunCount       int64      `json:"runCount"`
+	FailureCount   int64      `json:"failureCount"`
+	NextRunAt      *time.Time `json:"nextRunAt"`
+}
+
+type JobData struct {
+	Name           string     `db:"name"`
+	LockedBy       *string    `db:"locked_by"`
+	LockedUntil    *time.Time `db:"locked_until"`
+	LastStartedAt  *time.Time `db:"last_started_at"`
+	LastFinishedAt *time.Time `db:"last_finished_at"`
+	LastStatus     *string    `db:"last_status"`
+	LastError      *string    `db:"last_error"`
+	LastAffected   *int64     `db:"last_affected"`
+	RunCount       int64      `db:"run_count"`
+	FailureCount   int64      `db:"failure_count"`
+}
+
+type JobRunData struct {
+	Name     string
+	Owner    string
+	Status   string
+	Error    *string
+	Affected int64
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   SchedulerService
+	validator *validator.Validate
+}
+
+func NewHandler(service SchedulerService) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetJobsHandler lists the registered jobs with the status of their last run
+func (h *Handler) GetJobsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	ctx := c.Request.Context()
+	resp, err := h.service.GetJobsService(ctx)
+	if err != nil {
+		logger.Error("error while get jobs : " + err.Error())
+		c.JSON(http.StatusInternalServerError, app.Response{
+			Code:    app.InternalServerErrorCode,
+			Message: app.InternalServerErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+// RunJobHandler starts a job now, its status shows when the run is over
+func (h *Handler) RunJobHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	req := RunJobRequest{
+		Name:   c.Param("jobName"),
+		UserId: c.GetString("userId"),
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.service.RunJobService(ctx, req)
+	if err != nil {
+		logger.Error("error while run job : " + err.Error())
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			c.JSON(http.StatusNotFound, app.Response{
+				Code:    app.NotFoundErrorCode,
+				Message: app.NotFoundErrorMessage,
+			})
+		case errors.Is(err, ErrJobRunning):
+			c.JSON(http.StatusConflict, app.Response{
+				Code:    app.JobRunningErrorCode,
+				Message: app.JobRunningErrorMessage,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			})
+		}
+		return
+	}
+
+	logger.Info("job started", "job", req.Name, "by", req.UserId)
+	c.JSON(http.StatusAccepted, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
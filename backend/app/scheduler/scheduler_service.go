@@ -0,0 +1,262 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/google/uuid"
+)
+
+// maxErrorLength bounds the error message kept as a job's last error, in bytes
+const maxErrorLength = 1000
+
+// minLockTTL leaves room to extend the lock, it is extended every third of its TTL
+const minLockTTL = time.Second
+
+type Service struct {
+	cfg     *config.Scheduler
+	storage SchedulerStorage
+	audit   audit.AuditService
+	owner   string
+	jobs    []Job
+
+	mu sync.Mutex
+	// runCtx is the context Run was started with, jobs started by an admin stop with it
+	runCtx context.Context
+}
+
+func NewService(cfg *config.Scheduler, storage SchedulerStorage, auditService audit.AuditService) (*Service, error) {
+	if cfg.PollInterval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive, got %s", cfg.PollInterval)
+	}
+	if cfg.LockTTL < minLockTTL {
+		return nil, fmt.Errorf("lock TTL must be at least %s, got %s", minLockTTL, cfg.LockTTL)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &Service{
+		cfg:     cfg,
+		storage: storage,
+		audit:   auditService,
+		// several replicas may share a hostname, the suffix tells their locks apart
+		owner: hostname + "-" + uuid.NewString()[:8],
+	}, nil
+}
+
+func (s *Service) Register(job Job) {
+	if job.Interval <= 0 {
+		slog.Info("scheduled job disabled", "job", job.Name)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+func (s *Service) Run(ctx context.Context) {
+	logger := slog.Default()
+
+	s.mu.Lock()
+	s.runCtx = ctx
+	s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if err := s.storage.EnsureJobStorage(ctx, job.Name); err != nil {
+			logger.Error("failed to register scheduled job", "job", job.Name, "error", err)
+		}
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, job := range s.jobs {
+			if _, err := s.start(ctx, job, job.Interval); err != nil {
+				logger.Error("failed to claim scheduled job", "job", job.Name, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// start claims the job when it was last started at least interval ago and runs it in the background
+func (s *Service) start(ctx context.Context, job Job, interval time.Duration) (bool, error) {
+	claimed, err := s.storage.ClaimJobStorage(ctx, job.Name, s.owner, interval, s.cfg.LockTTL)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	go s.execute(ctx, job)
+	return true, nil
+}
+
+func (s *Service) execute(ctx context.Context, job Job) {
+	logger := slog.Default().With("job", job.Name)
+	started := time.Now()
+
+	stopExtending := make(chan struct{})
+	go s.extendLock(ctx, job.Name, stopExtending)
+
+	affected, err := runJob(ctx, job)
+	close(stopExtending)
+
+	run := JobRunData{
+		Name:     job.Name,
+		Owner:    s.owner,
+		Status:   StatusSucceeded,
+		Affected: affected,
+	}
+	if err != nil {
+		message := truncateError(err.Error())
+		run.Status = StatusFailed
+		run.Error = &message
+		logger.Error("scheduled job failed", "error", err, "affected", affected)
+	} else if affected > 0 {
+		logger.Info("scheduled job finished", "affected", affected, "duration", time.Since(started))
+	}
+
+	// a shutdown cancels ctx mid run, the run is still recorded and the lock released
+	released, err := s.storage.FinishJobStorage(context.WithoutCancel(ctx), run)
+	if err != nil {
+		logger.Error("failed to record scheduled job run", "error", err)
+	} else if !released {
+		logger.Warn("scheduled job lost its lock while running")
+	}
+}
+
+// runJob turns a panic of the job into an error so the run is recorded and the lock released
+func runJob(ctx context.Context, job Job) (affected int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// extendLock keeps the job locked while it runs longer than the lock TTL
+func (s *Service) extendLock(ctx context.Context, name string, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.storage.ExtendLockStorage(ctx, name, s.owner, s.cfg.LockTTL); err != nil {
+				slog.Error("failed to extend scheduled job lock", "job", name, "error", err)
+			}
+		}
+	}
+}
+
+func (s *Service) GetJobsService(ctx context.Context) ([]JobResponse, error) {
+	dataList, err := s.storage.GetJobsStorage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs in storage error : %w", err)
+	}
+
+	byName := make(map[string]JobData, len(dataList))
+	for _, data := range dataList {
+		byName[data.Name] = data
+	}
+
+	now := time.Now()
+	resp := make([]JobResponse, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		data := byName[job.Name]
+		item := JobResponse{
+			Name:           job.Name,
+			Interval:       job.Interval.String(),
+			Running:        data.LockedUntil != nil && data.LockedUntil.After(now),
+			LockedBy:       data.LockedBy,
+			LockedUntil:    data.LockedUntil,
+			LastStartedAt:  data.LastStartedAt,
+			LastFinishedAt: data.LastFinishedAt,
+			LastStatus:     data.LastStatus,
+			LastError:      data.LastError,
+			LastAffected:   data.LastAffected,
+			RunCount:       data.RunCount,
+			FailureCount:   data.FailureCount,
+		}
+		if data.LastStartedAt != nil {
+			next := data.LastStartedAt.Add(job.Interval)
+			item.NextRunAt = &next
+		}
+		resp = append(resp, item)
+	}
+
+	return resp, nil
+}
+
+func (s *Service) RunJobService(ctx context.Context, req RunJobRequest) error {
+	var job *Job
+	for i := range s.jobs {
+		if s.jobs[i].Name == req.Name {
+			job = &s.jobs[i]
+			break
+		}
+	}
+	if job == nil {
+		return ErrJobNotFound
+	}
+
+	// the run outlives the request that started it
+	s.mu.Lock()
+	runCtx := s.runCtx
+	s.mu.Unlock()
+	if runCtx == nil {
+		runCtx = context.WithoutCancel(ctx)
+	}
+
+	if err := s.storage.EnsureJobStorage(ctx, job.Name); err != nil {
+		return fmt.Errorf("failed to register job in storage error : %w", err)
+	}
+
+	// a zero interval makes the job due whenever it is not running
+	started, err := s.start(runCtx, *job, 0)
+	if err != nil {
+		return fmt.Errorf("failed to claim job in storage error : %w", err)
+	}
+	if !started {
+		return ErrJobRunning
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserId,
+		Action:     audit.ActionAdminJobRun,
+		TargetType: audit.TargetJob,
+		TargetId:   req.Name,
+	})
+	return nil
+}
+
+// truncateError cuts message to maxErrorLength on a rune boundary, Postgres rejects text that is not valid UTF-8
+func truncateError(message string) string {
+	message = strings.ToValidUTF8(message, "\uFFFD")
+	if len(message) <= maxErrorLength {
+		return message
+	}
+	end := maxErrorLength
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
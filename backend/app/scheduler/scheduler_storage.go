@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func NewStorage(db *pgxpool.Pool) *Storage {
+	return &Storage{db: db}
+}
+
+func (s *Storage) EnsureJobStorage(ctx context.Context, name string) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO scheduled_jobs (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+	return err
+}
+
+func (s *Storage) ClaimJobStorage(ctx context.Context, name, owner string, interval, lockTTL time.Duration) (bool, error) {
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = $2, locked_until = NOW() + $4::bigint * INTERVAL '1 millisecond', last_started_at = NOW()
+		WHERE name = $1
+			AND (locked_until IS NULL OR locked_until < NOW())
+			AND (last_started_at IS NULL OR last_started_at <= NOW() - $3::bigint * INTERVAL '1 millisecond')
+	`
+
+	result, err := s.db.Exec(ctx, query, name, owner, interval.Milliseconds(), lockTTL.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *Storage) ExtendLockStorage(ctx context.Context, name, owner string, lockTTL time.Duration) error {
+	query := `UPDATE scheduled_jobs SET locked_until = NOW() + $3::bigint * INTERVAL '1 millisecond' WHERE name = $1 AND locked_by = $2`
+
+	_, err := s.db.Exec(ctx, query, name, owner, lockTTL.Milliseconds())
+	return err
+}
+
+func (s *Storage) FinishJobStorage(ctx context.Context, data JobRunData) (bool, error) {
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = NULL,
+			locked_until = NULL,
+			last_finished_at = NOW(),
+			last_status = $3,
+			last_error = $4,
+			last_affected = $5,
+			run_count = run_count + 1,
+			failure_count = failure_count + CASE WHEN $3 = 'failed' THEN 1 ELSE 0 END
+		WHERE name = $1 AND locked_by = $2
+	`
+
+	result, err := s.db.Exec(ctx, query, data.Name, data.Owner, data.Status, data.Error, data.Affected)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *Storage) GetJobsStorage(ctx context.Context) ([]JobData, error) {
+	query := `
+		SELECT name, locked_by, locked_until, last_started_at, last_finished_at,
+			last_status, last_error, last_affected, run_count, failure_count
+		FROM scheduled_jobs
+		ORDER BY name ASC
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []JobData{}
+
+	for rows.Next() {
+		var data JobData
+		err := rows.Scan(
+			&data.Name,
+			&data.LockedBy,
+			&data.LockedUntil,
+			&data.LastStartedAt,
+			&data.LastFinishedAt,
+			&data.LastStatus,
+			&data.LastError,
+			&data.LastAffected,
+			&data.RunCount,
+			&data.FailureCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
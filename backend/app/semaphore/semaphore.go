@@ -0,0 +1,116 @@
+package semaphore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript drops expired leases, then adds a lease when fewer than limit are held.
+// Leases are members of a sorted set scored by their expiry so a crashed replica only holds them until they expire
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+	return 1
+end
+return 0
+`)
+
+// Semaphore bounds how many leases can be held on a key across every backend replica
+type Semaphore struct {
+	redis *redis.Client
+}
+
+// New returns a semaphore that grants every lease when redisClient is nil
+func New(redisClient *redis.Client) *Semaphore {
+	return &Semaphore{redis: redisClient}
+}
+
+type Lease struct {
+	sem *Semaphore
+	key string
+	id  string
+	ttl time.Duration
+}
+
+// TryAcquire takes a lease on key when fewer than limit are held, it returns nil when the key is full.
+// A limit of zero or less means unlimited
+func (s *Semaphore) TryAcquire(ctx context.Context, key string, limit int, ttl time.Duration) (*Lease, error) {
+	lease := &Lease{
+		sem: s,
+		key: "semaphore:" + key,
+		id:  uuid.NewString(),
+		ttl: ttl,
+	}
+	if s.redis == nil || limit <= 0 {
+		return lease, nil
+	}
+
+	now := time.Now()
+	acquired, err := acquireScript.Run(ctx, s.redis, []string{lease.key},
+		now.UnixMilli(),
+		limit,
+		now.Add(ttl).UnixMilli(),
+		lease.id,
+		ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease on %s: %w", key, err)
+	}
+	if acquired == 0 {
+		return nil, nil
+	}
+	return lease, nil
+}
+
+// Acquire waits until a lease on key is available, polling every interval, or ctx is done
+func (s *Semaphore) Acquire(ctx context.Context, key string, limit int, ttl, interval time.Duration) (*Lease, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lease, err := s.TryAcquire(ctx, key, limit, ttl)
+		if err != nil || lease != nil {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh extends the lease by its ttl, leases held longer than their ttl have to be refreshed
+func (l *Lease) Refresh(ctx context.Context) error {
+	if l == nil || l.sem.redis == nil {
+		return nil
+	}
+
+	expiry := time.Now().Add(l.ttl).UnixMilli()
+	pipe := l.sem.redis.TxPipeline()
+	pipe.ZAddXX(ctx, l.key, redis.Z{Score: float64(expiry), Member: l.id})
+	pipe.PExpire(ctx, l.key, l.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to refresh lease on %s: %w", l.key, err)
+	}
+	return nil
+}
+
+// Release gives the lease back
+func (l *Lease) Release(ctx context.Context) error {
+	if l == nil || l.sem.redis == nil {
+		return nil
+	}
+
+	if err := l.sem.redis.ZRem(ctx, l.key, l.id).Err(); err != nil {
+		return fmt.Errorf("failed to release lease on %s: %w", l.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package sessionshistory
+
+import (
+	"net/http"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) GetFoldersHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req FoldersRequest
+
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	resp, err := h.service.GetFoldersService(c.Request.Context(), req)
+	if err != nil {
+		logger.Error("error while get folders : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) CreateFolderHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req CreateFolderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	resp, err := h.service.CreateFolderService(c.Request.Context(), req)
+	if err != nil {
+		logger.Error("error while create folder : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+func (h *Handler) RenameFolderHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req RenameFolderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.FolderId = c.Param("folderID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.RenameFolderService(c.Request.Context(), req); err != nil {
+		logger.Error("error while rename folder : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) DeleteFolderHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req DeleteFolderRequest
+
+	req.UserId = c.GetString("userId")
+	req.FolderId = c.Param("folderID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.DeleteFolderService(c.Request.Context(), req); err != nil {
+		logger.Error("error while delete folder : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
@@ -0,0 +1,64 @@
+package sessionshistory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *Service) GetFoldersService(ctx context.Context, req FoldersRequest) ([]FolderResponse, error) {
+	resp, err := s.storage.GetFoldersStorage(ctx, req.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders in storage error : %w", err)
+	}
+
+	folderResp := []FolderResponse{}
+	for _, data := range resp {
+		folderResp = append(folderResp, FolderResponse{
+			FolderId:     data.FolderId,
+			Name:         data.Name,
+			SessionCount: data.SessionCount,
+			CreatedAt:    data.CreatedAt,
+		})
+	}
+
+	return folderResp, nil
+}
+
+func (s *Service) CreateFolderService(ctx context.Context, req CreateFolderRequest) (*FolderResponse, error) {
+	data := FolderData{
+		FolderId:  uuid.NewString(),
+		UserId:    req.UserId,
+		Name:      strings.TrimSpace(req.Name),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.storage.CreateFolderStorage(ctx, data); err != nil {
+		return nil, fmt.Errorf("failed to create folder in storage error : %w", err)
+	}
+
+	return &FolderResponse{
+		FolderId:  data.FolderId,
+		Name:      data.Name,
+		CreatedAt: data.CreatedAt,
+	}, nil
+}
+
+func (s *Service) RenameFolderService(ctx context.Context, req RenameFolderRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := s.storage.RenameFolderStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to rename folder in storage error : %w", err)
+	}
+	return nil
+}
+
+func (s *Service) DeleteFolderService(ctx context.Context, req DeleteFolderRequest) error {
+	if err := s.storage.DeleteFolderStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to delete folder in storage error : %w", err)
+	}
+	return nil
+}
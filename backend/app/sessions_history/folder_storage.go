@@ -0,0 +1,105 @@
+package sessionshistory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/organise"
+)
+
+func (s *Storage) GetFoldersStorage(ctx context.Context, userId string) ([]FolderData, error) {
+	query := `
+		SELECT
+			f.folder_id,
+			f.user_id,
+			f.name,
+			COUNT(s.session_id) AS session_count,
+			f.created_at
+		FROM session_folders f
+		LEFT JOIN chat_sessions s ON s.folder_id = f.folder_id AND s.deleted_at IS NULL
+		WHERE f.user_id = $1
+		GROUP BY f.folder_id
+		ORDER BY f.name;
+	`
+
+	rows, err := s.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []FolderData{}
+
+	for rows.Next() {
+		var data FolderData
+		err := rows.Scan(
+			&data.FolderId,
+			&data.UserId,
+			&data.Name,
+			&data.SessionCount,
+			&data.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
+
+func (s *Storage) CreateFolderStorage(ctx context.Context, data FolderData) error {
+	query := `INSERT INTO session_folders
+				(folder_id, user_id, name, created_at)
+				VALUES ($1, $2, $3, $4)`
+
+	_, err := s.db.Exec(ctx, query, data.FolderId, data.UserId, data.Name, data.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error when insert folder: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RenameFolderStorage(ctx context.Context, req RenameFolderRequest) error {
+	query := `
+		UPDATE session_folders
+		SET name = $3, updated_at = $4
+		WHERE user_id = $1 AND folder_id = $2
+	`
+	rows, err := s.db.Exec(ctx, query, req.UserId, req.FolderId, req.Name, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrFolderNotFound
+	}
+
+	return nil
+}
+
+// DeleteFolderStorage removes the folder, its sessions are kept and become unfiled
+func (s *Storage) DeleteFolderStorage(ctx context.Context, req DeleteFolderRequest) error {
+	query := `DELETE FROM session_folders WHERE user_id = $1 AND folder_id = $2`
+
+	rows, err := s.db.Exec(ctx, query, req.UserId, req.FolderId)
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrFolderNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) CheckFolderOwnerStorage(ctx context.Context, userId, folderId string) (bool, error) {
+	return organise.FolderOwned(ctx, s.db, userId, folderId)
+}
@@ -2,18 +2,49 @@ package sessionshistory
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrFolderNotFound  = errors.New("folder not found")
+)
+
 type SessionService interface {
 	GetSessionsHistoryService(ctx context.Context, req SessionsHistoryRequest) ([]SessionsHistoryResponse, error)
+	PinSessionService(ctx context.Context, req PinSessionRequest) error
+	ArchiveSessionService(ctx context.Context, req ArchiveSessionRequest) error
+	MoveSessionService(ctx context.Context, req MoveSessionRequest) error
+	SetSessionTagsService(ctx context.Context, req SetSessionTagsRequest) error
+	GetTagsService(ctx context.Context, req TagsRequest) ([]TagResponse, error)
+
+	GetFoldersService(ctx context.Context, req FoldersRequest) ([]FolderResponse, error)
+	CreateFolderService(ctx context.Context, req CreateFolderRequest) (*FolderResponse, error)
+	RenameFolderService(ctx context.Context, req RenameFolderRequest) error
+	DeleteFolderService(ctx context.Context, req DeleteFolderRequest) error
 }
 type SessionStorage interface {
-	GetSessionsHistoryStorage(ctx context.Context, userId string) ([]SessionsHistoryData, error)
+	GetSessionsHistoryStorage(ctx context.Context, req SessionsHistoryRequest) ([]SessionsHistoryData, error)
+	PinSessionStorage(ctx context.Context, req PinSessionRequest) error
+	ArchiveSessionStorage(ctx context.Context, req ArchiveSessionRequest) error
+	MoveSessionStorage(ctx context.Context, req MoveSessionRequest) error
+	SetSessionTagsStorage(ctx context.Context, req SetSessionTagsRequest) error
+	GetTagsStorage(ctx context.Context, userId string) ([]TagData, error)
+
+	GetFoldersStorage(ctx context.Context, userId string) ([]FolderData, error)
+	CreateFolderStorage(ctx context.Context, data FolderData) error
+	RenameFolderStorage(ctx context.Context, req RenameFolderRequest) error
+	DeleteFolderStorage(ctx context.Context, req DeleteFolderRequest) error
+	CheckFolderOwnerStorage(ctx context.Context, userId, folderId string) (bool, error)
 }
 
 type SessionsHistoryRequest struct {
-	UserId string `json:"userId" validate:"required,uuid4"`
+	UserId   string  `json:"userId" validate:"required,uuid4"`
+	FolderId *string `form:"folderId" validate:"omitempty,uuid4"`
+	Tag      *string `form:"tag" validate:"omitempty,max=50"`
+	Pinned   *bool   `form:"pinned"`
+	Archived bool    `form:"archived"`
 }
 
 type SessionsHistoryResponse struct {
@@ -22,12 +53,93 @@ type SessionsHistoryResponse struct {
 	Title         string    `json:"title"`
 	CreatedAt     time.Time `json:"createdAt"`
 	LastMessageAt time.Time `json:"lastMessageAt"`
+	FolderId      *string   `json:"folderId"`
+	Tags          []string  `json:"tags"`
+	Pinned        bool      `json:"pinned"`
+	Archived      bool      `json:"archived"`
 }
 
 type SessionsHistoryData struct {
-	UserId        string    `db:"user_id"`
-	SessionId     string    `db:"session_id"`
-	Title         string    `db:"title"`
-	CreatedAt     time.Time `db:"created_at"`
-	LastMessageAt time.Time `db:"last_message_at"`
+	UserId        string     `db:"user_id"`
+	SessionId     string     `db:"session_id"`
+	Title         string     `db:"title"`
+	CreatedAt     time.Time  `db:"created_at"`
+	LastMessageAt time.Time  `db:"last_message_at"`
+	FolderId      *string    `db:"folder_id"`
+	Tags          []string   `db:"tags"`
+	PinnedAt      *time.Time `db:"pinned_at"`
+	ArchivedAt    *time.Time `db:"archived_at"`
+}
+
+type PinSessionRequest struct {
+	UserId    string `json:"userId" validate:"required"`
+	SessionId string `json:"sessionId" validate:"required,uuid4"`
+	Pinned    *bool  `json:"pinned" validate:"required"`
+}
+
+type ArchiveSessionRequest struct {
+	UserId    string `json:"userId" validate:"required"`
+	SessionId string `json:"sessionId" validate:"required,uuid4"`
+	Archived  *bool  `json:"archived" validate:"required"`
+}
+
+type MoveSessionRequest struct {
+	UserId    string  `json:"userId" validate:"required"`
+	SessionId string  `json:"sessionId" validate:"required,uuid4"`
+	FolderId  *string `json:"folderId" validate:"omitempty,uuid4"`
+}
+
+type SetSessionTagsRequest struct {
+	UserId    string   `json:"userId" validate:"required"`
+	SessionId string   `json:"sessionId" validate:"required,uuid4"`
+	Tags      []string `json:"tags" validate:"max=20,dive,required,max=50"`
+}
+
+type TagsRequest struct {
+	UserId string `json:"userId" validate:"required"`
+}
+
+type TagResponse struct {
+	Tag          string `json:"tag"`
+	SessionCount int    `json:"sessionCount"`
+}
+
+type TagData struct {
+	Tag          string `db:"tag"`
+	SessionCount int    `db:"session_count"`
+}
+
+type FoldersRequest struct {
+	UserId string `json:"userId" validate:"required"`
+}
+
+type CreateFolderRequest struct {
+	UserId string `json:"userId" validate:"required"`
+	Name   string `json:"name" validate:"required,max=100"`
+}
+
+type RenameFolderRequest struct {
+	UserId   string `json:"userId" validate:"required"`
+	FolderId string `json:"folderId" validate:"required,uuid4"`
+	Name     string `json:"name" validate:"required,max=100"`
+}
+
+type DeleteFolderRequest struct {
+	UserId   string `json:"userId" validate:"required"`
+	FolderId string `json:"folderId" validate:"required,uuid4"`
+}
+
+type FolderResponse struct {
+	FolderId     string    `json:"folderId"`
+	Name         string    `json:"name"`
+	SessionCount int       `json:"sessionCount"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type FolderData struct {
+	FolderId     string    `db:"folder_id"`
+	UserId       string    `db:"user_id"`
+	Name         string    `db:"name"`
+	SessionCount int       `db:"session_count"`
+	CreatedAt    time.Time `db:"created_at"`
 }
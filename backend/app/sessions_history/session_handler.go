@@ -1,10 +1,11 @@
 package sessionshistory
 
 import (
-	"log/slog"
+	"errors"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -22,9 +23,18 @@ func NewHandler(service SessionService) *Handler {
 }
 
 func (h *Handler) GetSessionHistory(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req SessionsHistoryRequest
 
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("invalid request query : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
 	req.UserId = c.GetString("userId")
 
 	if err := h.validator.Struct(req); err != nil {
@@ -53,3 +63,196 @@ func (h *Handler) GetSessionHistory(c *gin.Context) {
 		Data:    resp,
 	})
 }
+
+func (h *Handler) PinSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req PinSessionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.SessionId = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.PinSessionService(c.Request.Context(), req); err != nil {
+		logger.Error("error while pin session : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) ArchiveSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req ArchiveSessionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.SessionId = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.ArchiveSessionService(c.Request.Context(), req); err != nil {
+		logger.Error("error while archive session : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) MoveSessionHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req MoveSessionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.SessionId = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.MoveSessionService(c.Request.Context(), req); err != nil {
+		logger.Error("error while move session : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) SetSessionTagsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req SetSessionTagsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	req.UserId = c.GetString("userId")
+	req.SessionId = c.Param("sessionID")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	if err := h.service.SetSessionTagsService(c.Request.Context(), req); err != nil {
+		logger.Error("error while set session tags : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+	})
+}
+
+func (h *Handler) GetTagsHandler(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+	var req TagsRequest
+
+	req.UserId = c.GetString("userId")
+
+	if err := h.validator.Struct(req); err != nil {
+		logger.Error("invalid request body : " + err.Error())
+		c.JSON(http.StatusBadRequest, app.Response{
+			Code:    app.InvalidRequestErrorCode,
+			Message: app.InvalidRequestErrorMessage,
+		})
+		return
+	}
+
+	resp, err := h.service.GetTagsService(c.Request.Context(), req)
+	if err != nil {
+		logger.Error("error while get tags : " + err.Error())
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app.Response{
+		Code:    app.SUCCESS_CODE,
+		Message: app.SUCCESS_MSG,
+		Data:    resp,
+	})
+}
+
+// writeServiceError maps not found errors to 404 and everything else to 500
+func writeServiceError(c *gin.Context, err error) {
+	if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrFolderNotFound) {
+		c.JSON(http.StatusNotFound, app.Response{
+			Code:    app.NotFoundErrorCode,
+			Message: app.NotFoundErrorMessage,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, app.Response{
+		Code:    app.InternalServerErrorCode,
+		Message: app.InternalServerErrorMessage,
+	})
+}
@@ -1,6 +1,11 @@
 package sessionshistory
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/organise"
+)
 
 type Service struct {
 	storage SessionStorage
@@ -13,7 +18,7 @@ func NewService(storage SessionStorage) *Service {
 }
 
 func (s *Service) GetSessionsHistoryService(ctx context.Context, req SessionsHistoryRequest) ([]SessionsHistoryResponse, error) {
-	resp, err := s.storage.GetSessionsHistoryStorage(ctx, req.UserId)
+	resp, err := s.storage.GetSessionsHistoryStorage(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -26,8 +31,69 @@ func (s *Service) GetSessionsHistoryService(ctx context.Context, req SessionsHis
 			Title:         data.Title,
 			CreatedAt:     data.CreatedAt,
 			LastMessageAt: data.LastMessageAt,
+			FolderId:      data.FolderId,
+			Tags:          data.Tags,
+			Pinned:        data.PinnedAt != nil,
+			Archived:      data.ArchivedAt != nil,
 		})
 	}
 
 	return messageResp, nil
 }
+
+func (s *Service) PinSessionService(ctx context.Context, req PinSessionRequest) error {
+	if err := s.storage.PinSessionStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to pin session in storage error : %w", err)
+	}
+	return nil
+}
+
+func (s *Service) ArchiveSessionService(ctx context.Context, req ArchiveSessionRequest) error {
+	if err := s.storage.ArchiveSessionStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to archive session in storage error : %w", err)
+	}
+	return nil
+}
+
+func (s *Service) MoveSessionService(ctx context.Context, req MoveSessionRequest) error {
+	if req.FolderId != nil {
+		owned, err := s.storage.CheckFolderOwnerStorage(ctx, req.UserId, *req.FolderId)
+		if err != nil {
+			return fmt.Errorf("failed to check folder in storage error : %w", err)
+		}
+		if !owned {
+			return ErrFolderNotFound
+		}
+	}
+
+	if err := s.storage.MoveSessionStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to move session in storage error : %w", err)
+	}
+	return nil
+}
+
+func (s *Service) SetSessionTagsService(ctx context.Context, req SetSessionTagsRequest) error {
+	req.Tags = organise.NormalizeTags(req.Tags)
+
+	if err := s.storage.SetSessionTagsStorage(ctx, req); err != nil {
+		return fmt.Errorf("failed to set session tags in storage error : %w", err)
+	}
+	return nil
+}
+
+func (s *Service) GetTagsService(ctx context.Context, req TagsRequest) ([]TagResponse, error) {
+	resp, err := s.storage.GetTagsStorage(ctx, req.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags in storage error : %w", err)
+	}
+
+	tagResp := []TagResponse{}
+	for _, data := range resp {
+		tagResp = append(tagResp, TagResponse{
+			Tag:          data.Tag,
+			SessionCount: data.SessionCount,
+		})
+	}
+
+	return tagResp, nil
+}
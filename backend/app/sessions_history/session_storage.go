@@ -2,6 +2,8 @@ package sessionshistory
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -14,20 +16,30 @@ func NewStorage(db *pgxpool.Pool) *Storage {
 	return &Storage{db: db}
 }
 
-func (s *Storage) GetSessionsHistoryStorage(ctx context.Context, userId string) ([]SessionsHistoryData, error) {
+func (s *Storage) GetSessionsHistoryStorage(ctx context.Context, req SessionsHistoryRequest) ([]SessionsHistoryData, error) {
 	query := `
 		SELECT 
-			user_id,
-			session_id,
-			title,
-			created_at,
-			last_message_at
-		FROM chat_sessions
-		WHERE user_id = $1
-		ORDER BY last_message_at DESC;
+			s.user_id,
+			s.session_id,
+			s.title,
+			s.created_at,
+			s.last_message_at,
+			s.folder_id,
+			ARRAY(SELECT t.tag FROM session_tags t WHERE t.session_id = s.session_id ORDER BY t.tag) AS tags,
+			s.pinned_at,
+			s.archived_at
+		FROM chat_sessions s
+		WHERE s.user_id = $1 AND s.deleted_at IS NULL
+			AND ($2::uuid IS NULL OR s.folder_id = $2)
+			AND ($3::text IS NULL OR EXISTS (
+				SELECT 1 FROM session_tags t WHERE t.session_id = s.session_id AND t.tag = $3
+			))
+			AND ($4::boolean IS NULL OR (s.pinned_at IS NOT NULL) = $4)
+			AND (s.archived_at IS NOT NULL) = $5
+		ORDER BY s.pinned_at DESC NULLS LAST, s.last_message_at DESC;
 	`
 
-	rows, err := s.db.Query(ctx, query, userId)
+	rows, err := s.db.Query(ctx, query, req.UserId, req.FolderId, req.Tag, req.Pinned, req.Archived)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +55,10 @@ func (s *Storage) GetSessionsHistoryStorage(ctx context.Context, userId string)
 			&data.Title,
 			&data.CreatedAt,
 			&data.LastMessageAt,
+			&data.FolderId,
+			&data.Tags,
+			&data.PinnedAt,
+			&data.ArchivedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -56,3 +72,130 @@ func (s *Storage) GetSessionsHistoryStorage(ctx context.Context, userId string)
 
 	return dataList, nil
 }
+
+func (s *Storage) PinSessionStorage(ctx context.Context, req PinSessionRequest) error {
+	query := `
+		UPDATE chat_sessions
+		SET pinned_at = CASE WHEN $3 THEN COALESCE(pinned_at, $4) ELSE NULL END
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+	`
+	rows, err := s.db.Exec(ctx, query, req.UserId, req.SessionId, *req.Pinned, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ArchiveSessionStorage(ctx context.Context, req ArchiveSessionRequest) error {
+	query := `
+		UPDATE chat_sessions
+		SET archived_at = CASE WHEN $3 THEN COALESCE(archived_at, $4) ELSE NULL END
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+	`
+	rows, err := s.db.Exec(ctx, query, req.UserId, req.SessionId, *req.Archived, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) MoveSessionStorage(ctx context.Context, req MoveSessionRequest) error {
+	query := `
+		UPDATE chat_sessions
+		SET folder_id = $3
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+	`
+	rows, err := s.db.Exec(ctx, query, req.UserId, req.SessionId, req.FolderId)
+	if err != nil {
+		return err
+	}
+
+	if rows.RowsAffected() != 1 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// SetSessionTagsStorage replaces the tags of a session
+func (s *Storage) SetSessionTagsStorage(ctx context.Context, req SetSessionTagsRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error when begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	owned, err := tx.Exec(ctx, `
+		SELECT 1 FROM chat_sessions
+		WHERE user_id = $1 AND session_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`,
+		req.UserId, req.SessionId)
+	if err != nil {
+		return err
+	}
+	if owned.RowsAffected() != 1 {
+		return ErrSessionNotFound
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM session_tags WHERE session_id = $1`, req.SessionId)
+	if err != nil {
+		return fmt.Errorf("error when delete tags: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_tags (session_id, tag, created_at)
+		SELECT $1, UNNEST($2::text[]), $3`,
+		req.SessionId, req.Tags, time.Now())
+	if err != nil {
+		return fmt.Errorf("error when insert tags: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error when commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetTagsStorage(ctx context.Context, userId string) ([]TagData, error) {
+	query := `
+		SELECT t.tag, COUNT(1) AS session_count
+		FROM session_tags t
+		JOIN chat_sessions s ON s.session_id = t.session_id
+		WHERE s.user_id = $1 AND s.deleted_at IS NULL
+		GROUP BY t.tag
+		ORDER BY t.tag;
+	`
+
+	rows, err := s.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []TagData{}
+
+	for rows.Next() {
+		var data TagData
+		if err := rows.Scan(&data.Tag, &data.SessionCount); err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataList, nil
+}
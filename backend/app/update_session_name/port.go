@@ -7,7 +7,8 @@ type UpdateSessionNameService interface {
 }
 
 type UpdateSessionNameStorage interface {
-	UpdateSessionNameStorage(ctx context.Context, req UpdateSessionNameRequest) error
+	// UpdateSessionNameStorage renames the session and returns its previous title
+	UpdateSessionNameStorage(ctx context.Context, req UpdateSessionNameRequest) (string, error)
 }
 
 type UpdateSessionNameRequest struct {
@@ -2,10 +2,10 @@ package updatesessionname
 
 import (
 	"fmt"
-	"log/slog"
 	"net/http"
 
 	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -23,7 +23,7 @@ func NewHandler(service UpdateSessionNameService) *Handler {
 }
 
 func (h *Handler) UpdateSessionNameHandler(c *gin.Context) {
-	logger := slog.Default()
+	logger := logging.FromContext(c.Request.Context())
 	var req UpdateSessionNameRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
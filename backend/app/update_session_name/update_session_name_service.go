@@ -3,23 +3,36 @@ package updatesessionname
 import (
 	"context"
 	"fmt"
+
+	"github.com/PatiharnKam/AiLaw/app/audit"
 )
 
 type Service struct {
 	storage UpdateSessionNameStorage
+	audit   audit.AuditService
 }
 
-func NewService(storage UpdateSessionNameStorage) *Service {
+func NewService(storage UpdateSessionNameStorage, auditService audit.AuditService) *Service {
 	return &Service{
 		storage: storage,
+		audit:   auditService,
 	}
 }
 
 func (s *Service) UpdateSessionNameService(ctx context.Context, req UpdateSessionNameRequest) error {
-	err := s.storage.UpdateSessionNameStorage(ctx, req)
+	oldName, err := s.storage.UpdateSessionNameStorage(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to update session name in storage error : %w", err)
 	}
 
+	s.audit.Record(ctx, audit.Event{
+		ActorId:    req.UserID,
+		Action:     audit.ActionSessionRename,
+		TargetType: audit.TargetSession,
+		TargetId:   req.SessionID,
+		Before:     map[string]string{"title": oldName},
+		After:      map[string]string{"title": req.NewName},
+	})
+
 	return nil
 }
@@ -2,9 +2,11 @@ package updatesessionname
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,20 +18,29 @@ func NewStorage(db *pgxpool.Pool) *storage {
 	return &storage{db: db}
 }
 
-func (s *storage) UpdateSessionNameStorage(ctx context.Context, req UpdateSessionNameRequest) error {
-	query := `UPDATE chat_sessions 
-			  SET title = $1, updated_at = $2
-			  WHERE session_id = $3 AND user_id = $4`
-
-	rowAffected, err := s.db.Exec(ctx, query, req.NewName, time.Now(), req.SessionID, req.UserID)
-
+func (s *storage) UpdateSessionNameStorage(ctx context.Context, req UpdateSessionNameRequest) (string, error) {
+	query := `UPDATE chat_sessions s
+			  SET title = $1, title_edited = TRUE, updated_at = $2
+			  FROM (
+				  SELECT session_id, title
+				  FROM chat_sessions
+				  WHERE session_id = $3 AND user_id = $4 AND deleted_at IS NULL
+				  FOR UPDATE
+			  ) old
+			  WHERE s.session_id = old.session_id
+			  RETURNING old.title`
+
+	var oldTitle *string
+	err := s.db.QueryRow(ctx, query, req.NewName, time.Now(), req.SessionID, req.UserID).Scan(&oldTitle)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("session not found or unauthorized")
+		}
+		return "", err
 	}
 
-	if rowAffected.RowsAffected() == 0 {
-		return fmt.Errorf("session not found or unauthorized")
+	if oldTitle == nil {
+		return "", nil
 	}
-
-	return nil
+	return *oldTitle, nil
 }
@@ -0,0 +1,170 @@
+// Command encryption encrypts existing message content and rotates the keys it is encrypted with.
+//
+//	go run ./cmd/encryption encrypt [-batch 500] [-dry-run]
+//	go run ./cmd/encryption rotate [-batch 500]
+//	go run ./cmd/encryption rewrap [-batch 500]
+//
+// encrypt encrypts the message content still stored in plaintext, rotate gives every user a new data key
+// and re-encrypts their content with it, rewrap wraps the data keys of a replaced master key with
+// ENCRYPTION_ACTIVE_MASTER_KEY. Every command can be interrupted and run again
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/caarlos0/env/v11"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "encrypt":
+		err = encryptCommand(os.Args[2:])
+	case "rotate":
+		err = rotateCommand(os.Args[2:])
+	case "rewrap":
+		err = rewrapCommand(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("encryption failed", "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: encryption encrypt [-batch 500] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "       encryption rotate [-batch 500]")
+	fmt.Fprintln(os.Stderr, "       encryption rewrap [-batch 500]")
+}
+
+func encryptCommand(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	batchSize := fs.Int("batch", 500, "messages read per query")
+	dryRun := fs.Bool("dry-run", false, "count the messages that would be encrypted without updating them")
+	fs.Parse(args)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, keyring, err := setup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := encryption.NewMigrator(keyring, encryption.NewStorage(db), *batchSize)
+	return encryptTables(ctx, migrator, false, *dryRun)
+}
+
+func rotateCommand(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	batchSize := fs.Int("batch", 500, "users and messages read per query")
+	fs.Parse(args)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, keyring, err := setup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storage := encryption.NewStorage(db)
+	migrator := encryption.NewMigrator(keyring, storage, *batchSize)
+	rotated, err := migrator.RotateKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rotated the data keys of %d users\n", rotated)
+
+	return encryptTables(ctx, migrator, true, false)
+}
+
+func rewrapCommand(args []string) error {
+	fs := flag.NewFlagSet("rewrap", flag.ExitOnError)
+	batchSize := fs.Int("batch", 500, "data keys read per query")
+	fs.Parse(args)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, keyring, err := setup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rewrapped, err := keyring.RewrapKeys(ctx, *batchSize)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rewrapped %d data keys\n", rewrapped)
+	return nil
+}
+
+func encryptTables(ctx context.Context, migrator *encryption.Migrator, reencrypt bool, dryRun bool) error {
+	for _, table := range []string{encryption.TableUserMessages, encryption.TableModelMessages} {
+		result, err := migrator.EncryptMessages(ctx, table, reencrypt, dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d scanned, %d encrypted, %d skipped, %d conflicts\n",
+			table, result.Scanned, result.Encrypted, result.Skipped, result.Conflicts)
+		if result.Conflicts > 0 {
+			slog.Warn("Messages changed while they were encrypted, run the command again", "table", table, "conflicts", result.Conflicts)
+		}
+	}
+	return nil
+}
+
+func setup() (*pgxpool.Pool, *encryption.Keyring, error) {
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using OS environment variables")
+	}
+	cfg := &config.Config{}
+	if err := env.ParseWithOptions(&cfg.Database, env.Options{Prefix: "POSTGRES_"}); err != nil {
+		return nil, nil, fmt.Errorf("error when parse database config: %v", err)
+	}
+	if err := env.ParseWithOptions(&cfg.Encryption, env.Options{Prefix: "ENCRYPTION_"}); err != nil {
+		return nil, nil, fmt.Errorf("error when parse encryption config: %v", err)
+	}
+	if !cfg.Encryption.Enabled {
+		return nil, nil, fmt.Errorf("ENCRYPTION_ENABLED is not set, the application would keep writing plaintext")
+	}
+
+	db, err := config.NewPostgresDB(cfg.Database.PostgresURL, config.DBConnectionConfig{
+		ConnMaxLifetime:   &cfg.Database.PostgresConnMaxLifetime,
+		ConnMaxIdleTime:   &cfg.Database.PostgresConnMaxIdleTime,
+		MaxOpenConns:      &cfg.Database.PostgresMaxOpenConns,
+		HealthCheckPeriod: &cfg.Database.PostgresHealthCheckPeriod,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error when connect to postgres: %v", err)
+	}
+
+	keyring, err := encryption.NewKeyring(&cfg.Encryption, encryption.NewStorage(db))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, keyring, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// GoldenCase is one question of the golden set, it extends the request body of model/FastAPI/data.json
+// with the expectations the answer is checked against
+type GoldenCase struct {
+	ID               string        `json:"id"`
+	Messages         []GoldenInput `json:"messages"`
+	ExpectedSections []string      `json:"expectedSections"`
+	Keywords         []string      `json:"keywords"`
+}
+
+type GoldenInput struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Question returns the last user message of the case
+func (g GoldenCase) Question() string {
+	for i := len(g.Messages) - 1; i >= 0; i-- {
+		if g.Messages[i].Role == "user" {
+			return g.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// loadGoldenSet reads a JSON array, a single JSON object like data.json, or one object per line (JSONL)
+func loadGoldenSet(path string) ([]GoldenCase, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error when read golden set: %v", err)
+	}
+	content = bytes.TrimSpace(content)
+
+	var cases []GoldenCase
+	switch {
+	case len(content) == 0:
+		return nil, fmt.Errorf("golden set %s is empty", path)
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &cases); err != nil {
+			return nil, fmt.Errorf("error when parse golden set: %v", err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		var single GoldenCase
+		if err := json.Unmarshal(content, &single); err == nil {
+			cases = append(cases, single)
+			break
+		}
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var golden GoldenCase
+			if err := json.Unmarshal(text, &golden); err != nil {
+				return nil, fmt.Errorf("error when parse golden set line %d: %v", line, err)
+			}
+			cases = append(cases, golden)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error when read golden set: %v", err)
+		}
+	}
+
+	for i := range cases {
+		if cases[i].ID == "" {
+			cases[i].ID = strconv.Itoa(i + 1)
+		}
+		if cases[i].Question() == "" {
+			return nil, fmt.Errorf("golden case %s has no user message", cases[i].ID)
+		}
+	}
+	return cases, nil
+}
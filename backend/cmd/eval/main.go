@@ -0,0 +1,132 @@
+// Command eval replays a golden set of legal questions through the model pipeline and compares runs.
+//
+//	go run ./cmd/eval run -golden golden.jsonl -model default -out base.json
+//	go run ./cmd/eval diff -base base.json -head head.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/chatbot"
+	"github.com/PatiharnKam/AiLaw/config"
+	"github.com/caarlos0/env/v11"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = runCommand(os.Args[2:])
+	case "diff":
+		err = diffCommand(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("eval failed", "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: eval run -golden <file> [-model default|COT] [-out run.json] [-concurrency 2] [-timeout 5m]")
+	fmt.Fprintln(os.Stderr, "       eval diff -base <run.json> -head <run.json> [-out report.md]")
+}
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	opts := runOptions{}
+	fs.StringVar(&opts.goldenPath, "golden", "", "golden set in the model/FastAPI/data.json format (JSON, JSON array or JSONL)")
+	fs.StringVar(&opts.modelType, "model", "default", "model type to evaluate: default or COT")
+	fs.StringVar(&opts.outPath, "out", "", "run result file, defaults to eval-<model>-<time>.json")
+	fs.IntVar(&opts.concurrency, "concurrency", 2, "questions evaluated in parallel")
+	fs.DurationVar(&opts.timeout, "timeout", 5*time.Minute, "timeout of a single question")
+	fs.Parse(args)
+
+	if opts.goldenPath == "" {
+		return fmt.Errorf("-golden is required")
+	}
+	if opts.modelType != "default" && opts.modelType != "COT" {
+		return fmt.Errorf("unknown model type %q", opts.modelType)
+	}
+	if opts.outPath == "" {
+		opts.outPath = fmt.Sprintf("eval-%s-%s.json", opts.modelType, time.Now().Format("20060102-150405"))
+	}
+
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using OS environment variables")
+	}
+	cfg := &config.Config{}
+	if err := env.Parse(&cfg.Model); err != nil {
+		return fmt.Errorf("error when parse model config: %v", err)
+	}
+	if err := env.ParseWithOptions(&cfg.ModelClient, env.Options{Prefix: "MODEL_CLIENT_"}); err != nil {
+		return fmt.Errorf("error when parse model client config: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// only the model client is used, the evaluation never writes to storage, consumes quota or runs moderation
+	service := chatbot.NewService(cfg, nil, nil, nil, nil)
+	run, err := runEval(ctx, service, opts)
+	if err != nil {
+		return err
+	}
+	if err := writeRun(opts.outPath, run); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d/%d passed, %d errors, section recall %.2f, keyword recall %.2f, avg latency %.2fs -> %s\n",
+		run.Summary.Passed, run.Summary.Total, run.Summary.Errors,
+		run.Summary.SectionRecall, run.Summary.KeywordRecall, run.Summary.AvgLatencySec, opts.outPath)
+	return nil
+}
+
+func diffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	basePath := fs.String("base", "", "run result used as baseline")
+	headPath := fs.String("head", "", "run result compared with the baseline")
+	outPath := fs.String("out", "", "markdown report file, defaults to stdout")
+	fs.Parse(args)
+
+	if *basePath == "" || *headPath == "" {
+		return fmt.Errorf("-base and -head are required")
+	}
+
+	base, err := readRun(*basePath)
+	if err != nil {
+		return err
+	}
+	head, err := readRun(*headPath)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("error when create report: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writeDiffReport(out, base, head)
+	return nil
+}
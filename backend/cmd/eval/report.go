@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeDiffReport writes a markdown report comparing head against base, case by case
+func writeDiffReport(w io.Writer, base, head Run) {
+	fmt.Fprintf(w, "# Evaluation diff\n\n")
+	fmt.Fprintf(w, "- base: %s (%s, %s)\n", base.GoldenSet, base.ModelType, base.StartedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(w, "- head: %s (%s, %s)\n\n", head.GoldenSet, head.ModelType, head.StartedAt.Format("2006-01-02 15:04"))
+
+	fmt.Fprintf(w, "| metric | base | head | delta |\n|---|---|---|---|\n")
+	fmt.Fprintf(w, "| passed | %d/%d | %d/%d | %+d |\n", base.Summary.Passed, base.Summary.Total, head.Summary.Passed, head.Summary.Total, head.Summary.Passed-base.Summary.Passed)
+	fmt.Fprintf(w, "| errors | %d | %d | %+d |\n", base.Summary.Errors, head.Summary.Errors, head.Summary.Errors-base.Summary.Errors)
+	fmt.Fprintf(w, "| section recall | %.2f | %.2f | %+.2f |\n", base.Summary.SectionRecall, head.Summary.SectionRecall, head.Summary.SectionRecall-base.Summary.SectionRecall)
+	fmt.Fprintf(w, "| keyword recall | %.2f | %.2f | %+.2f |\n", base.Summary.KeywordRecall, head.Summary.KeywordRecall, head.Summary.KeywordRecall-base.Summary.KeywordRecall)
+	fmt.Fprintf(w, "| avg latency (s) | %.2f | %.2f | %+.2f |\n", base.Summary.AvgLatencySec, head.Summary.AvgLatencySec, head.Summary.AvgLatencySec-base.Summary.AvgLatencySec)
+	fmt.Fprintf(w, "| total tokens | %d | %d | %+d |\n\n", base.Summary.TotalUsedToken, head.Summary.TotalUsedToken, head.Summary.TotalUsedToken-base.Summary.TotalUsedToken)
+
+	baseResults := map[string]CaseResult{}
+	for _, result := range base.Results {
+		baseResults[result.ID] = result
+	}
+
+	var regressions, fixes, changed []string
+	for _, result := range head.Results {
+		previous, ok := baseResults[result.ID]
+		if !ok {
+			changed = append(changed, fmt.Sprintf("- `%s` new case, passed=%t", result.ID, result.Passed))
+			continue
+		}
+		delete(baseResults, result.ID)
+
+		line := fmt.Sprintf("- `%s` %s", result.ID, describeCase(result))
+		switch {
+		case previous.Passed && !result.Passed:
+			regressions = append(regressions, line)
+		case !previous.Passed && result.Passed:
+			fixes = append(fixes, line)
+		case strings.Join(previous.CitedSections, ",") != strings.Join(result.CitedSections, ","):
+			changed = append(changed, fmt.Sprintf("- `%s` cited sections %v → %v", result.ID, previous.CitedSections, result.CitedSections))
+		}
+	}
+	for id := range baseResults {
+		changed = append(changed, fmt.Sprintf("- `%s` missing from head run", id))
+	}
+
+	writeSection(w, "Regressions", regressions)
+	writeSection(w, "Fixes", fixes)
+	writeSection(w, "Other changes", changed)
+}
+
+func describeCase(result CaseResult) string {
+	if result.Error != "" {
+		return "error: " + result.Error
+	}
+	parts := []string{}
+	if len(result.MissingSections) > 0 {
+		parts = append(parts, "missing sections "+strings.Join(result.MissingSections, ", "))
+	}
+	if len(result.MissingKeywords) > 0 {
+		parts = append(parts, "missing keywords "+strings.Join(result.MissingKeywords, ", "))
+	}
+	if len(parts) == 0 {
+		return "all expectations met"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func writeSection(w io.Writer, title string, lines []string) {
+	fmt.Fprintf(w, "## %s (%d)\n\n", title, len(lines))
+	if len(lines) == 0 {
+		fmt.Fprintf(w, "none\n\n")
+		return
+	}
+	fmt.Fprintf(w, "%s\n\n", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app/chatbot"
+	"github.com/PatiharnKam/AiLaw/app/citation"
+)
+
+// Run is the result file of one evaluation run, two runs are compared with the diff command
+type Run struct {
+	ModelType  string       `json:"modelType"`
+	GoldenSet  string       `json:"goldenSet"`
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Summary    RunSummary   `json:"summary"`
+	Results    []CaseResult `json:"results"`
+}
+
+type RunSummary struct {
+	Total          int     `json:"total"`
+	Passed         int     `json:"passed"`
+	Errors         int     `json:"errors"`
+	SectionRecall  float64 `json:"sectionRecall"`
+	KeywordRecall  float64 `json:"keywordRecall"`
+	AvgLatencySec  float64 `json:"avgLatencySec"`
+	TotalUsedToken int     `json:"totalUsedTokens"`
+}
+
+type CaseResult struct {
+	ID               string   `json:"id"`
+	Question         string   `json:"question"`
+	Answer           string   `json:"answer"`
+	Error            string   `json:"error,omitempty"`
+	CitedSections    []string `json:"citedSections"`
+	MissingSections  []string `json:"missingSections"`
+	MissingKeywords  []string `json:"missingKeywords"`
+	ExpectedSections int      `json:"expectedSections"`
+	ExpectedKeywords int      `json:"expectedKeywords"`
+	Passed           bool     `json:"passed"`
+	LatencySec       float64  `json:"latencySec"`
+	InputTokens      int      `json:"inputTokens"`
+	OutputTokens     int      `json:"outputTokens"`
+	TotalUsedTokens  int      `json:"totalUsedTokens"`
+}
+
+type runOptions struct {
+	goldenPath  string
+	modelType   string
+	outPath     string
+	concurrency int
+	timeout     time.Duration
+}
+
+func runEval(ctx context.Context, service *chatbot.MessageService, opts runOptions) (Run, error) {
+	cases, err := loadGoldenSet(opts.goldenPath)
+	if err != nil {
+		return Run{}, err
+	}
+
+	run := Run{
+		ModelType: opts.modelType,
+		GoldenSet: opts.goldenPath,
+		StartedAt: time.Now(),
+		Results:   make([]CaseResult, len(cases)),
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(opts.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run.Results[i] = evaluateCase(ctx, service, opts, cases[i])
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s passed=%t latency=%.1fs\n", i+1, len(cases), cases[i].ID, run.Results[i].Passed, run.Results[i].LatencySec)
+			}
+		}()
+	}
+	for i := range cases {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	run.FinishedAt = time.Now()
+	run.Summary = summarize(run.Results)
+	return run, nil
+}
+
+func evaluateCase(ctx context.Context, service *chatbot.MessageService, opts runOptions, golden GoldenCase) CaseResult {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	result := CaseResult{
+		ID:               golden.ID,
+		Question:         golden.Question(),
+		ExpectedSections: len(golden.ExpectedSections),
+		ExpectedKeywords: len(golden.Keywords),
+		CitedSections:    []string{},
+		MissingSections:  []string{},
+		MissingKeywords:  []string{},
+	}
+
+	start := time.Now()
+	detail, err := service.GenerateAnswer(ctx, opts.modelType, result.Question)
+	result.LatencySec = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+		result.MissingSections = append(result.MissingSections, normalizeSections(golden.ExpectedSections)...)
+		result.MissingKeywords = append(result.MissingKeywords, golden.Keywords...)
+		return result
+	}
+
+	result.Answer = detail.Content
+	result.InputTokens = detail.TotalInputTokens
+	result.OutputTokens = detail.TotalOutputTokens
+	result.TotalUsedTokens = detail.TotalUsedTokens
+	result.CitedSections = citation.ExtractSections(detail.Content)
+
+	for _, section := range normalizeSections(golden.ExpectedSections) {
+		if !slices.Contains(result.CitedSections, section) {
+			result.MissingSections = append(result.MissingSections, section)
+		}
+	}
+	answer := strings.ToLower(detail.Content)
+	for _, keyword := range golden.Keywords {
+		if !strings.Contains(answer, strings.ToLower(keyword)) {
+			result.MissingKeywords = append(result.MissingKeywords, keyword)
+		}
+	}
+
+	result.Passed = len(result.MissingSections) == 0 && len(result.MissingKeywords) == 0
+	return result
+}
+
+// normalizeSections accepts "420", "มาตรา 420" or thai digits and returns the form ExtractSections produces
+func normalizeSections(sections []string) []string {
+	normalized := make([]string, 0, len(sections))
+	for _, section := range sections {
+		section = strings.TrimSpace(section)
+		if !strings.HasPrefix(section, "มาตรา") {
+			section = "มาตรา " + section
+		}
+		if found := citation.ExtractSections(section); len(found) > 0 {
+			section = found[0]
+		}
+		normalized = append(normalized, section)
+	}
+	return normalized
+}
+
+func summarize(results []CaseResult) RunSummary {
+	summary := RunSummary{Total: len(results)}
+
+	var expectedSections, foundSections, expectedKeywords, foundKeywords int
+	var latency float64
+	for _, result := range results {
+		if result.Passed {
+			summary.Passed++
+		}
+		if result.Error != "" {
+			summary.Errors++
+		}
+		expectedSections += result.ExpectedSections
+		foundSections += result.ExpectedSections - len(result.MissingSections)
+		expectedKeywords += result.ExpectedKeywords
+		foundKeywords += result.ExpectedKeywords - len(result.MissingKeywords)
+		latency += result.LatencySec
+		summary.TotalUsedToken += result.TotalUsedTokens
+	}
+
+	if expectedSections > 0 {
+		summary.SectionRecall = float64(foundSections) / float64(expectedSections)
+	}
+	if expectedKeywords > 0 {
+		summary.KeywordRecall = float64(foundKeywords) / float64(expectedKeywords)
+	}
+	if len(results) > 0 {
+		summary.AvgLatencySec = latency / float64(len(results))
+	}
+	return summary
+}
+
+func writeRun(path string, run Run) error {
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error when marshal run: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("error when write run: %v", err)
+	}
+	return nil
+}
+
+func readRun(path string) (Run, error) {
+	var run Run
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return run, fmt.Errorf("error when read run: %v", err)
+	}
+	if err := json.Unmarshal(content, &run); err != nil {
+		return run, fmt.Errorf("error when parse run %s: %v", path, err)
+	}
+	return run, nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -27,6 +28,23 @@ type Config struct {
 	Database Database `envPrefix:"POSTGRES_"`
 	Redis    Redis    `envPrefix:"REDIS_"`
 	Quota    Quota    `envPrefix:"QUOTA_"`
+	Export   Export   `envPrefix:"EXPORT_"`
+	Trash    Trash    `envPrefix:"TRASH_"`
+	PII      PII      `envPrefix:"PII_"`
+	Moderation Moderation `envPrefix:"MODERATION_"`
+	RateLimit  RateLimit  `envPrefix:"RATE_LIMIT_"`
+	Concurrency Concurrency `envPrefix:"CONCURRENCY_"`
+	ModelQueue ModelQueue `envPrefix:"MODEL_QUEUE_"`
+	ModelClient ModelClient `envPrefix:"MODEL_CLIENT_"`
+	ModelFallback ModelFallback `envPrefix:"MODEL_FALLBACK_"`
+	Health Health `envPrefix:"HEALTH_"`
+	Metrics Metrics `envPrefix:"METRICS_"`
+	Tracing Tracing `envPrefix:"TRACING_"`
+	Audit Audit `envPrefix:"AUDIT_"`
+	Account Account `envPrefix:"ACCOUNT_"`
+	Scheduler Scheduler `envPrefix:"SCHEDULER_"`
+	Retention Retention `envPrefix:"RETENTION_"`
+	Encryption Encryption `envPrefix:"ENCRYPTION_"`
 	AllowedOrigin []string `env:"ALLOWED_ORIGIN" envSeparator:","`
 }
 
@@ -41,9 +59,14 @@ type Google struct {
 	RedirectURI  string `env:"GOOGLE_REDIRECT_URI"`
 }
 
+// Server configures the HTTP server. The client IP used by rate limits and the audit log is only read from
+// X-Forwarded-For when the request comes from one of TrustedProxies (IPs or CIDRs), or from the TrustedPlatform
+// header such as CF-Connecting-IP when the backend runs behind that platform. With neither set the peer address is used
 type Server struct {
-	Hostname string `env:"HOSTNAME"`
-	Port     string `env:"PORT,notEmpty"`
+	Hostname        string   `env:"HOSTNAME"`
+	Port            string   `env:"PORT,notEmpty"`
+	TrustedProxies  []string `env:"TRUSTED_PROXIES" envSeparator:","`
+	TrustedPlatform string   `env:"TRUSTED_PLATFORM"`
 }
 
 type Model struct {
@@ -53,6 +76,8 @@ type Model struct {
 	ModelStreamURL    string `env:"MODEL_STREAM_URL"`
 	ModelCOTStreamURL string `env:"MODEL_COT_STREAM_URL"`
 	ModelCancelURL    string `env:"MODEL_CANCEL_URL"`
+	ModelTitleURL     string `env:"MODEL_TITLE_URL"`
+	ModelHealthURL    string `env:"MODEL_HEALTH_URL"`
 }
 
 type Database struct {
@@ -71,7 +96,166 @@ type Redis struct {
 	URL      string `env:"URL"`
 }
 
+// Quota is counted in Redis, FailOpen lets requests through instead of rejecting them while Redis is down
 type Quota struct {
 	DailyLimit      int64 `env:"DAILY_LIMIT"`
 	MaxPromptTokens int   `env:"MAX_PROMPT_TOKENS"`
+	FailOpen        bool  `env:"FAIL_OPEN" envDefault:"false"`
+}
+
+type Export struct {
+	PDFFontPath string `env:"PDF_FONT_PATH" envDefault:"/usr/share/fonts/noto/NotoSansThai-Regular.ttf"`
+}
+
+type Trash struct {
+	RetentionDays int           `env:"RETENTION_DAYS" envDefault:"30"`
+	PurgeInterval time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+}
+
+// PII is the redaction policy used for users whose organization has no policy of its own,
+// an unknown STORE_MODE or entity type stops the startup
+type PII struct {
+	Enabled         bool     `env:"ENABLED" envDefault:"true"`
+	Entities        []string `env:"ENTITIES" envSeparator:"," envDefault:"national_id,phone,email,bank_account,address"`
+	RestoreResponse bool     `env:"RESTORE_RESPONSE" envDefault:"true"`
+	StoreMode       string   `env:"STORE_MODE" envDefault:"redacted"`
+}
+
+// Moderation configures the pre-flight checks run on prompts before they reach the model,
+// every *_ACTION is one of allow, warn, flag or block
+type Moderation struct {
+	Enabled          bool     `env:"ENABLED" envDefault:"true"`
+	Keywords         []string `env:"KEYWORDS" envSeparator:","`
+	KeywordAction    string   `env:"KEYWORD_ACTION" envDefault:"block"`
+	Patterns         []string `env:"PATTERNS" envSeparator:";;"`
+	PatternAction    string   `env:"PATTERN_ACTION" envDefault:"flag"`
+	JailbreakAction  string   `env:"JAILBREAK_ACTION" envDefault:"block"`
+	MaxPromptRunes   int      `env:"MAX_PROMPT_RUNES" envDefault:"8000"`
+	LengthAction     string   `env:"LENGTH_ACTION" envDefault:"block"`
+	EntropyThreshold float64  `env:"ENTROPY_THRESHOLD" envDefault:"5.0"`
+	EntropyMinRunes  int      `env:"ENTROPY_MIN_RUNES" envDefault:"64"`
+	EntropyAction    string   `env:"ENTROPY_ACTION" envDefault:"flag"`
+}
+
+// RateLimit configures the token buckets, every limit is a number of requests a minute with its burst size
+type RateLimit struct {
+	Enabled            bool `env:"ENABLED" envDefault:"true"`
+	APIPerMinute       int  `env:"API_PER_MINUTE" envDefault:"120"`
+	APIBurst           int  `env:"API_BURST" envDefault:"40"`
+	AuthPerMinute      int  `env:"AUTH_PER_MINUTE" envDefault:"20"`
+	AuthBurst          int  `env:"AUTH_BURST" envDefault:"10"`
+	SessionPerMinute   int  `env:"SESSION_PER_MINUTE" envDefault:"10"`
+	SessionBurst       int  `env:"SESSION_BURST" envDefault:"5"`
+	WSConnectPerMinute int  `env:"WS_CONNECT_PER_MINUTE" envDefault:"10"`
+	WSConnectBurst     int  `env:"WS_CONNECT_BURST" envDefault:"5"`
+	WSChatPerMinute    int  `env:"WS_CHAT_PER_MINUTE" envDefault:"20"`
+	WSChatBurst        int  `env:"WS_CHAT_BURST" envDefault:"5"`
+}
+
+// Concurrency bounds open WebSockets and in-flight model generations per user across replicas,
+// a generation over the limit waits up to QueueTimeout for a slot, zero rejects it at once
+type Concurrency struct {
+	MaxConnectionsPerUser int           `env:"MAX_CONNECTIONS_PER_USER" envDefault:"5"`
+	MaxGenerationsPerUser int           `env:"MAX_GENERATIONS_PER_USER" envDefault:"2"`
+	QueueTimeout          time.Duration `env:"QUEUE_TIMEOUT" envDefault:"30s"`
+	LeaseTTL              time.Duration `env:"LEASE_TTL" envDefault:"2m"`
+}
+
+// ModelQueue bounds the calls each replica has in flight to the model service, calls over MaxInFlight wait
+// in line up to MaxWait with the plan tiers listed first in Tiers served first, zero MaxInFlight disables the queue
+type ModelQueue struct {
+	MaxInFlight int           `env:"MAX_IN_FLIGHT" envDefault:"8"`
+	MaxQueued   int           `env:"MAX_QUEUED" envDefault:"100"`
+	MaxWait     time.Duration `env:"MAX_WAIT" envDefault:"60s"`
+	Tiers       []string      `env:"TIERS" envSeparator:"," envDefault:"enterprise,pro,free"`
+}
+
+// ModelClient configures the calls to the model service. FirstByteTimeout also bounds the non-streaming
+// endpoints, which only answer once the whole answer is generated, so it is never retried. MaxRetries covers
+// connection errors and 502/503 only. Zero BreakerFailures disables the breaker
+type ModelClient struct {
+	ConnectTimeout   time.Duration `env:"CONNECT_TIMEOUT" envDefault:"5s"`
+	FirstByteTimeout time.Duration `env:"FIRST_BYTE_TIMEOUT" envDefault:"120s"`
+	IdleTimeout      time.Duration `env:"IDLE_TIMEOUT" envDefault:"60s"`
+	MaxRetries       int           `env:"MAX_RETRIES" envDefault:"2"`
+	RetryBackoff     time.Duration `env:"RETRY_BACKOFF" envDefault:"500ms"`
+	BreakerFailures  int           `env:"BREAKER_FAILURES" envDefault:"5"`
+	BreakerCooldown  time.Duration `env:"BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+// ModelFallback lists per model type the pipelines tried in order when the previous one is unavailable
+// or timed out, every step is <model type>:<stream|sync>
+type ModelFallback struct {
+	Enabled bool     `env:"ENABLED" envDefault:"true"`
+	Default []string `env:"DEFAULT" envSeparator:"," envDefault:"default:stream,default:sync"`
+	COT     []string `env:"COT" envSeparator:"," envDefault:"COT:stream,default:stream,default:sync"`
+}
+
+// Health configures /readyz, a required dependency that is down makes the replica not ready,
+// the others only mark it degraded. Postgres is always required
+type Health struct {
+	CheckTimeout time.Duration `env:"CHECK_TIMEOUT" envDefault:"2s"`
+	RequireRedis bool          `env:"REQUIRE_REDIS" envDefault:"false"`
+	RequireModel bool          `env:"REQUIRE_MODEL" envDefault:"false"`
+}
+
+// Metrics configures the Prometheus /metrics endpoint, a scraper has to send Token as a bearer token when it is set
+type Metrics struct {
+	Enabled bool   `env:"ENABLED" envDefault:"true"`
+	Token   string `env:"TOKEN"`
+}
+
+// Tracing exports OpenTelemetry spans to an OTLP/HTTP collector, Endpoint is its host:port. It is off by default
+type Tracing struct {
+	Enabled     bool    `env:"ENABLED" envDefault:"false"`
+	Endpoint    string  `env:"ENDPOINT" envDefault:"localhost:4318"`
+	Insecure    bool    `env:"INSECURE" envDefault:"true"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"ailaw-backend"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" envDefault:"1"`
+}
+
+// Audit keeps the audit log for RetentionDays, older entries are purged every PurgeInterval
+type Audit struct {
+	RetentionDays int           `env:"RETENTION_DAYS" envDefault:"365"`
+	PurgeInterval time.Duration `env:"PURGE_INTERVAL" envDefault:"24h"`
+}
+
+// Account controls the personal data exports users request, a finished archive can be downloaded
+// for ExportTTL and an export still running after ExportTimeout is given up
+type Account struct {
+	ExportTTL            time.Duration `env:"EXPORT_TTL" envDefault:"24h"`
+	ExportTimeout        time.Duration `env:"EXPORT_TIMEOUT" envDefault:"10m"`
+	MaxConcurrentExports int           `env:"MAX_CONCURRENT_EXPORTS" envDefault:"2"`
+	CleanupInterval      time.Duration `env:"CLEANUP_INTERVAL" envDefault:"1h"`
+}
+
+// Scheduler runs the background jobs, every replica looks for due jobs each PollInterval and only runs one
+// after claiming its lock. A lock is held for LockTTL and extended while the job runs, so the lock of a
+// crashed replica expires
+type Scheduler struct {
+	Enabled      bool          `env:"ENABLED" envDefault:"true"`
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"1m"`
+	LockTTL      time.Duration `env:"LOCK_TTL" envDefault:"5m"`
+}
+
+// Retention configures the retention jobs, zero interval disables a job. Sessions of users on a plan listed
+// in MessageDays as plan:days are deleted with their messages once their last message is that many days old,
+// unless the organization sets its own retention. Sessions of plans not listed are kept
+type Retention struct {
+	MessageDays          map[string]int `env:"MESSAGE_DAYS" envKeyValSeparator:":" envSeparator:","`
+	MessageInterval      time.Duration  `env:"MESSAGE_INTERVAL" envDefault:"24h"`
+	RefreshTokenInterval time.Duration  `env:"REFRESH_TOKEN_INTERVAL" envDefault:"1h"`
+	QuotaKeyInterval     time.Duration  `env:"QUOTA_KEY_INTERVAL" envDefault:"6h"`
+	BatchSize            int            `env:"BATCH_SIZE" envDefault:"1000"`
+}
+
+// Encryption configures envelope encryption of message content. Content is encrypted with a data key of its
+// user and data keys are wrapped by a master key. MasterKeys lists every master key as id:base64 of 32 bytes,
+// ActiveMasterKey wraps new data keys and a replaced master key has to stay listed until the data keys are
+// rewrapped. Without Enabled new content is stored in plaintext, encrypted content is still read
+type Encryption struct {
+	Enabled         bool              `env:"ENABLED" envDefault:"false"`
+	MasterKeys      map[string]string `env:"MASTER_KEYS" envKeyValSeparator:":" envSeparator:","`
+	ActiveMasterKey string            `env:"ACTIVE_MASTER_KEY"`
+	KeyCacheTTL     time.Duration     `env:"KEY_CACHE_TTL" envDefault:"10m"`
 }
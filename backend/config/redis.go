@@ -8,6 +8,8 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// NewRedisClient returns the client together with the ping error when Redis is unreachable,
+// the client reconnects on its own once Redis is back
 func NewRedisClient(redisCfg Redis) (*redis.Client, error) {
 	var client *redis.Client
 
@@ -34,7 +36,7 @@ func NewRedisClient(redisCfg Redis) (*redis.Client, error) {
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		return client, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
 	return client, nil
@@ -8,21 +8,46 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/account"
+	"github.com/PatiharnKam/AiLaw/app/audit"
 	"github.com/PatiharnKam/AiLaw/app/auth"
+	bulkSession "github.com/PatiharnKam/AiLaw/app/bulk_session"
 	service "github.com/PatiharnKam/AiLaw/app/chatbot"
+	datasetExport "github.com/PatiharnKam/AiLaw/app/dataset_export"
 	deleteChatSession "github.com/PatiharnKam/AiLaw/app/delete_session"
+	"github.com/PatiharnKam/AiLaw/app/encryption"
+	exportSession "github.com/PatiharnKam/AiLaw/app/export_session"
 	feedback "github.com/PatiharnKam/AiLaw/app/feedback"
+	healthCheck "github.com/PatiharnKam/AiLaw/app/health"
 	messageshistory "github.com/PatiharnKam/AiLaw/app/messages_history"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/PatiharnKam/AiLaw/app/modelqueue"
+	"github.com/PatiharnKam/AiLaw/app/moderation"
+	orgPolicy "github.com/PatiharnKam/AiLaw/app/org_policy"
+	"github.com/PatiharnKam/AiLaw/app/pii"
 	"github.com/PatiharnKam/AiLaw/app/quota"
+	"github.com/PatiharnKam/AiLaw/app/ratelimit"
+	"github.com/PatiharnKam/AiLaw/app/retention"
+	"github.com/PatiharnKam/AiLaw/app/scheduler"
+	"github.com/PatiharnKam/AiLaw/app/semaphore"
 	sessionshistory "github.com/PatiharnKam/AiLaw/app/sessions_history"
+	"github.com/PatiharnKam/AiLaw/app/tracing"
 	updateSessionName "github.com/PatiharnKam/AiLaw/app/update_session_name"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/PatiharnKam/AiLaw/middleware"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// version and commit are set at build time with -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = "unknown"
 )
 
 const (
@@ -39,19 +64,43 @@ func main() {
 		return
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing, version)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err.Error())
+		return
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownDuration)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err.Error())
+		}
+	}()
+
 	corsConfig := cors.New(cors.Config{
 		AllowOrigins:     cfg.AllowedOrigin,
-		AllowMethods:     []string{"GET", "POST", "PATCH", "DELETE"},
-		AllowHeaders:     []string{"Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-ID"},
 		AllowCredentials: true,
 	})
 	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("Invalid trusted proxies", "error", err.Error())
+		return
+	}
+	r.TrustedPlatform = cfg.Server.TrustedPlatform
 	r.Use(
 		gin.Recovery(),
 		corsConfig,
+		middleware.RequestID(),
+		middleware.AuditClient(),
 		middleware.LoggerMiddleware(),
+		middleware.MetricsMiddleware(),
 	)
+	if cfg.Tracing.Enabled {
+		r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	}
 	r.GET("/health", health())
 
 	db, err := config.NewPostgresDB(cfg.Database.PostgresURL, config.DBConnectionConfig{
@@ -68,20 +117,112 @@ func main() {
 
 	redisClient, err := config.NewRedisClient(cfg.Redis)
 	if err != nil {
-		slog.Error("Failed to connect to Redis", "error", err.Error())
+		if redisClient == nil {
+			slog.Error("Failed to create Redis client", "error", err.Error())
+			return
+		}
+		// keep serving degraded, see /readyz: quota follows QUOTA_FAIL_OPEN, rate and concurrency limits fail open
+		slog.Error("Failed to connect to Redis, running degraded", "error", err.Error())
 	}
 	defer redisClient.Close()
 
+	{
+		healthService := healthCheck.NewService(cfg, db, redisClient, healthCheck.BuildInfo{
+			Version: version,
+			Commit:  commit,
+		})
+		healthHandler := healthCheck.NewHandler(healthService)
+		r.GET("/livez", healthHandler.LivezHandler)
+		r.GET("/readyz", healthHandler.ReadyzHandler)
+	}
+
+	if cfg.Metrics.Enabled {
+		if err := metrics.Register(metrics.NewPoolCollector(db)); err != nil {
+			slog.Error("Failed to register Postgres pool metrics", "error", err.Error())
+		}
+		r.GET("/metrics", middleware.MetricsAuth(cfg.Metrics.Token), gin.WrapH(metrics.Handler()))
+	}
+
 	quotaService := quota.NewQuotaService(redisClient, &cfg.Quota)
+	limiter := ratelimit.NewLimiter(redisClient, cfg.RateLimit.Enabled)
+	slots := semaphore.New(redisClient)
+	modelQueue := modelqueue.New(&cfg.ModelQueue)
+
+	moderationStorage := moderation.NewStorage(db)
+	moderationService, err := moderation.NewService(&cfg.Moderation, moderationStorage)
+	if err != nil {
+		slog.Error("Failed to create moderation rules", "error", err.Error())
+		return
+	}
+
+	defaultPIIPolicy := pii.Policy{
+		Enabled:         cfg.PII.Enabled,
+		Entities:        cfg.PII.Entities,
+		RestoreResponse: cfg.PII.RestoreResponse,
+		StoreMode:       cfg.PII.StoreMode,
+	}
+	if err := defaultPIIPolicy.Validate(); err != nil {
+		slog.Error("Invalid PII config", "error", err.Error())
+		return
+	}
+
+	keyring, err := encryption.NewKeyring(&cfg.Encryption, encryption.NewStorage(db))
+	if err != nil {
+		slog.Error("Failed to create encryption keyring", "error", err.Error())
+		return
+	}
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	auditStorage := audit.NewStorage(db)
+	auditService := audit.NewService(&cfg.Audit, auditStorage)
+
+	// features register their jobs while their routes are set up, the scheduler starts once all are registered
+	schedulerStorage := scheduler.NewStorage(db)
+	schedulerService, err := scheduler.NewService(&cfg.Scheduler, schedulerStorage, auditService)
+	if err != nil {
+		slog.Error("Invalid scheduler config", "error", err.Error())
+		return
+	}
+	schedulerService.Register(scheduler.Job{
+		Name:     "audit_log_retention",
+		Interval: cfg.Audit.PurgeInterval,
+		Run:      auditService.PurgeExpiredAuditLogsService,
+	})
+	schedulerService.Register(scheduler.Job{
+		Name:     "quota_key_cleanup",
+		Interval: cfg.Retention.QuotaKeyInterval,
+		Run:      quotaService.PurgeStaleQuotaKeys,
+	})
+	{
+		retentionStorage := retention.NewStorage(db)
+		retentionService := retention.NewService(&cfg.Retention, retentionStorage)
+		schedulerService.Register(scheduler.Job{
+			Name:     "message_retention",
+			Interval: cfg.Retention.MessageInterval,
+			Run:      retentionService.PurgeExpiredMessagesService,
+		})
+	}
+
+	revocations := auth.NewRevocations(redisClient)
 
 	api := r.Group("/api")
-	api.Use(middleware.GinJWTMiddleware(cfg))
+	api.Use(
+		middleware.GinJWTMiddleware(cfg, revocations),
+		middleware.RateLimitByUser(limiter, ratelimit.Rule{
+			Name:      "api",
+			PerMinute: cfg.RateLimit.APIPerMinute,
+			Burst:     cfg.RateLimit.APIBurst,
+		}),
+	)
 	{
 		{
-			getMessageHistoryStorage := messageshistory.NewStorage(db)
+			getMessageHistoryStorage := messageshistory.NewStorage(db, keyring)
 			getMessageHistoryService := messageshistory.NewService(getMessageHistoryStorage)
 			getMessageHistoryHandler := messageshistory.NewHandler(getMessageHistoryService)
 			api.GET("/messages-history/:sessionID", getMessageHistoryHandler.GetMessageHistory)
+			api.PATCH("/session/:sessionID/branch", getMessageHistoryHandler.SwitchBranchHandler)
 		}
 
 		{
@@ -89,54 +230,166 @@ func main() {
 			getSessionHistoryService := sessionshistory.NewService(getSessionHistoryStorage)
 			getSessionHistoryHandler := sessionshistory.NewHandler(getSessionHistoryService)
 			api.GET("/sessions-history", getSessionHistoryHandler.GetSessionHistory)
+			api.PATCH("/session/:sessionID/pin", getSessionHistoryHandler.PinSessionHandler)
+			api.PATCH("/session/:sessionID/archive", getSessionHistoryHandler.ArchiveSessionHandler)
+			api.PATCH("/session/:sessionID/folder", getSessionHistoryHandler.MoveSessionHandler)
+			api.PATCH("/session/:sessionID/tags", getSessionHistoryHandler.SetSessionTagsHandler)
+			api.GET("/tags", getSessionHistoryHandler.GetTagsHandler)
+			api.GET("/folders", getSessionHistoryHandler.GetFoldersHandler)
+			api.POST("/folders", getSessionHistoryHandler.CreateFolderHandler)
+			api.PATCH("/folders/:folderID", getSessionHistoryHandler.RenameFolderHandler)
+			api.DELETE("/folders/:folderID", getSessionHistoryHandler.DeleteFolderHandler)
 		}
 
 		{
 			deleteChatSessionStorage := deleteChatSession.NewStorage(db)
-			deleteChatSessionService := deleteChatSession.NewService(deleteChatSessionStorage)
+			deleteChatSessionService := deleteChatSession.NewService(&cfg.Trash, deleteChatSessionStorage, auditService)
 			deleteChatSessionHandler := deleteChatSession.NewHandler(deleteChatSessionService)
 			api.DELETE("/session/:sessionID", deleteChatSessionHandler.DeleteChatSessionHandler)
+			api.GET("/trash/sessions", deleteChatSessionHandler.GetTrashSessionsHandler)
+			api.POST("/trash/session/:sessionID/restore", deleteChatSessionHandler.RestoreChatSessionHandler)
+
+			schedulerService.Register(scheduler.Job{
+				Name:     "trash_purge",
+				Interval: cfg.Trash.PurgeInterval,
+				Run:      deleteChatSessionService.PurgeExpiredSessionsService,
+			})
+		}
+
+		{
+			bulkSessionStorage := bulkSession.NewStorage(db)
+			bulkSessionService := bulkSession.NewService(bulkSessionStorage, auditService)
+			bulkSessionHandler := bulkSession.NewHandler(bulkSessionService)
+			api.POST("/sessions/bulk", bulkSessionHandler.BulkSessionHandler)
 		}
 
 		{
 			updateSessionNameStorage := updateSessionName.NewStorage(db)
-			updateSessionNameService := updateSessionName.NewService(updateSessionNameStorage)
+			updateSessionNameService := updateSessionName.NewService(updateSessionNameStorage, auditService)
 			updateSessionNameHandler := updateSessionName.NewHandler(updateSessionNameService)
 			api.PATCH("/name/session/:sessionID", updateSessionNameHandler.UpdateSessionNameHandler)
 		}
 
 		{
-			createChatSessionStorage := service.NewStorage(db)
-			createChatSessionService := service.NewService(cfg, createChatSessionStorage, quotaService)
-			createChatSessionHandler := service.NewHandler(createChatSessionService, cfg)
-			api.POST("/session", createChatSessionHandler.CreateChatSessionHandler)
+			createChatSessionStorage := service.NewStorage(db, keyring)
+			createChatSessionService := service.NewService(cfg, createChatSessionStorage, quotaService, moderationService, modelQueue)
+			createChatSessionHandler := service.NewHandler(createChatSessionService, cfg, limiter, slots)
+			api.POST("/session", middleware.RateLimitByUser(limiter, ratelimit.Rule{
+				Name:      "create_session",
+				PerMinute: cfg.RateLimit.SessionPerMinute,
+				Burst:     cfg.RateLimit.SessionBurst,
+			}), createChatSessionHandler.CreateChatSessionHandler)
 		}
 
 		{
-			getMessageStorage := service.NewStorage(db)
-			getMessageService := service.NewService(cfg, getMessageStorage, quotaService)
-			getMessageHandler := service.NewHandler(getMessageService, cfg)
+			getMessageStorage := service.NewStorage(db, keyring)
+			getMessageService := service.NewService(cfg, getMessageStorage, quotaService, moderationService, modelQueue)
+			getMessageHandler := service.NewHandler(getMessageService, cfg, limiter, slots)
 			api.POST("/model", getMessageHandler.ChatbotProcessModelHandler)
-			api.GET("/ws", getMessageHandler.WebSocketHandler)
+			api.GET("/ws", middleware.RateLimitByUser(limiter, ratelimit.Rule{
+				Name:      "ws_connect",
+				PerMinute: cfg.RateLimit.WSConnectPerMinute,
+				Burst:     cfg.RateLimit.WSConnectBurst,
+			}), getMessageHandler.WebSocketHandler)
+		}
+
+		{
+			exportSessionStorage := exportSession.NewStorage(db)
+			exportMessageStorage := messageshistory.NewStorage(db, keyring)
+			exportSessionService := exportSession.NewService(&cfg.Export, exportSessionStorage, exportMessageStorage)
+			exportSessionHandler := exportSession.NewHandler(exportSessionService)
+			api.GET("/session/:sessionID/export", exportSessionHandler.ExportSessionHandler)
 		}
 
 		{
-			feedbackStorage := feedback.NewStorage(db)
-			feedbackService := feedback.NewService(feedbackStorage)
+			feedbackStorage := feedback.NewStorage(db, keyring)
+			feedbackService := feedback.NewService(feedbackStorage, auditService)
 			feedbackHandler := feedback.NewHandler(feedbackService)
 			api.PATCH("/feedback/:messageID", feedbackHandler.FeedbackHandler)
+			api.PATCH("/feedback/:messageID/preferred", feedbackHandler.PreferredAnswerHandler)
+			api.GET("/feedback/:messageID/history", feedbackHandler.FeedbackHistoryHandler)
+
+			review := api.Group("/review", middleware.RequireRole(db, middleware.RoleReviewer, middleware.RoleAdmin))
+			review.GET("/feedback", feedbackHandler.ReviewQueueHandler)
+
+			moderationHandler := moderation.NewHandler(moderationService)
+			review.GET("/moderation/flags", moderationHandler.GetFlagsHandler)
+		}
+
+		{
+			accountStorage := account.NewStorage(db, keyring)
+			accountService := account.NewService(&cfg.Account, accountStorage, quotaService, revocations, auditService)
+			accountHandler := account.NewHandler(accountService)
+			api.POST("/me/export", accountHandler.RequestExportHandler)
+			api.GET("/me/export/:exportID", accountHandler.GetExportHandler)
+			api.GET("/me/export/:exportID/download", accountHandler.DownloadExportHandler)
+			api.DELETE("/me", accountHandler.DeleteAccountHandler)
+
+			schedulerService.Register(scheduler.Job{
+				Name:     "data_export_cleanup",
+				Interval: cfg.Account.CleanupInterval,
+				Run:      accountService.PurgeExpiredExportsService,
+			})
+		}
+
+		admin := api.Group("/admin", middleware.RequireRole(db, middleware.RoleAdmin))
+		{
+			datasetExportStorage := datasetExport.NewStorage(db, keyring)
+			datasetExportService := datasetExport.NewService(datasetExportStorage, auditService)
+			datasetExportHandler := datasetExport.NewHandler(datasetExportService)
+			admin.GET("/dataset/export", datasetExportHandler.ExportDatasetHandler)
+		}
+
+		{
+			orgPolicyStorage := orgPolicy.NewStorage(db)
+			orgPolicyService := orgPolicy.NewService(&cfg.PII, orgPolicyStorage, auditService)
+			orgPolicyHandler := orgPolicy.NewHandler(orgPolicyService)
+			admin.POST("/orgs", orgPolicyHandler.CreateOrganizationHandler)
+			admin.PUT("/orgs/:orgID/members/:userID", orgPolicyHandler.AddMemberHandler)
+			admin.DELETE("/orgs/:orgID/members/:userID", orgPolicyHandler.RemoveMemberHandler)
+			admin.GET("/orgs/:orgID/pii-policy", orgPolicyHandler.GetPIIPolicyHandler)
+			admin.PUT("/orgs/:orgID/pii-policy", orgPolicyHandler.UpdatePIIPolicyHandler)
+			admin.GET("/orgs/:orgID/retention-policy", orgPolicyHandler.GetRetentionPolicyHandler)
+			admin.PUT("/orgs/:orgID/retention-policy", orgPolicyHandler.UpdateRetentionPolicyHandler)
+		}
+
+		{
+			auditHandler := audit.NewHandler(auditService)
+			admin.GET("/audit-logs", auditHandler.GetAuditLogsHandler)
+			admin.GET("/audit-logs/export", auditHandler.ExportAuditLogsHandler)
+		}
+
+		{
+			schedulerHandler := scheduler.NewHandler(schedulerService)
+			admin.GET("/jobs", schedulerHandler.GetJobsHandler)
+			admin.POST("/jobs/:jobName/run", schedulerHandler.RunJobHandler)
 		}
 
 	}
 
 	{
 		authStorage := auth.NewStorage(db)
-		authService := auth.NewService(cfg, authStorage)
+		authService := auth.NewService(cfg, authStorage, auditService)
 		authHandler := auth.NewHandler(authService)
-		r.GET("/auth/google/login", authHandler.GoogleLogin)
-		r.GET("/auth/google/callback", authHandler.GoogleCallback)
-		r.POST("/auth/refresh", authHandler.RefreshTokenProcess)
-		r.POST("/auth/logout", authHandler.Logout)
+		authGroup := r.Group("/auth", middleware.RateLimitByIP(limiter, ratelimit.Rule{
+			Name:      "auth",
+			PerMinute: cfg.RateLimit.AuthPerMinute,
+			Burst:     cfg.RateLimit.AuthBurst,
+		}))
+		authGroup.GET("/google/login", authHandler.GoogleLogin)
+		authGroup.GET("/google/callback", authHandler.GoogleCallback)
+		authGroup.POST("/refresh", authHandler.RefreshTokenProcess)
+		authGroup.POST("/logout", authHandler.Logout)
+
+		schedulerService.Register(scheduler.Job{
+			Name:     "refresh_token_purge",
+			Interval: cfg.Retention.RefreshTokenInterval,
+			Run:      authService.PurgeExpiredRefreshTokensService,
+		})
+	}
+
+	if cfg.Scheduler.Enabled {
+		go schedulerService.Run(jobCtx)
 	}
 
 	srv := &http.Server{
@@ -182,8 +435,8 @@ func health() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"hostname": h,
-			// "version":  strings.ReplaceAll(version, "\n", ""),
-			// "commit":   commit,
+			"version":  strings.ReplaceAll(version, "\n", ""),
+			"commit":   commit,
 		})
 	}
 }
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"github.com/PatiharnKam/AiLaw/app/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditClient puts the client IP and user agent on the request context for the audit log
+func AuditClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(audit.WithClient(c.Request.Context(), c.ClientIP(), c.Request.UserAgent()))
+		c.Next()
+	}
+}
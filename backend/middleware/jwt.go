@@ -2,19 +2,23 @@ package middleware
 
 import (
 	"errors"
-	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PatiharnKam/AiLaw/app"
 	"github.com/PatiharnKam/AiLaw/app/auth"
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/PatiharnKam/AiLaw/config"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
+// GinJWTMiddleware authenticates the access token, tokens revoked through revocations are rejected
+// while Redis can be reached
+func GinJWTMiddleware(cfg *config.Config, revocations *auth.Revocations) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
 		var tokenString string
 
 		isWebSocket := c.GetHeader("Upgrade") == "websocket" ||
@@ -23,7 +27,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 		if isWebSocket {
 			tokenString = c.Query("token")
 			if tokenString == "" {
-				slog.Error("WebSocket: token query parameter required")
+				logger.Error("WebSocket: token query parameter required")
 				c.JSON(http.StatusUnauthorized, app.Response{
 					Code:    app.UnauthorizedErrorCode,
 					Message: app.UnauthorizedErrorMessage,
@@ -37,7 +41,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 		} else {
 			authHeader := c.GetHeader("Authorization")
 			if authHeader == "" {
-				slog.Error("Authorization header required")
+				logger.Error("Authorization header required")
 				c.JSON(http.StatusUnauthorized, app.Response{
 					Code:    app.UnauthorizedErrorCode,
 					Message: app.UnauthorizedErrorMessage,
@@ -51,7 +55,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 			bearerToken := strings.Split(authHeader, " ")
 			if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
-				slog.Error("Invalid authorization header format")
+				logger.Error("Invalid authorization header format")
 				c.JSON(http.StatusUnauthorized, app.Response{
 					Code:    app.UnauthorizedErrorCode,
 					Message: app.UnauthorizedErrorMessage,
@@ -67,7 +71,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		pubKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cfg.JWT.PublicKey))
 		if err != nil {
-			slog.Error("failed to parse public key")
+			logger.Error("failed to parse public key")
 			c.JSON(http.StatusUnauthorized, app.Response{
 				Code:    app.UnauthorizedErrorCode,
 				Message: app.UnauthorizedErrorMessage,
@@ -88,7 +92,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
-				slog.Error("access token expired go to refresh token", "error", err)
+				logger.Error("access token expired go to refresh token", "error", err)
 				c.JSON(http.StatusUnauthorized, app.Response{
 					Code:    app.UnauthorizedErrorCode,
 					Message: app.UnauthorizedErrorMessage,
@@ -100,7 +104,7 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 				return
 			}
 
-			slog.Error("failed when check JWT token", "error", err)
+			logger.Error("failed when check JWT token", "error", err)
 			c.JSON(http.StatusUnauthorized, app.Response{
 				Code:    app.UnauthorizedErrorCode,
 				Message: app.UnauthorizedErrorMessage,
@@ -113,7 +117,29 @@ func GinJWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(*auth.JWTClaims); ok && token.Valid {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			revoked, err := revocations.IsRevoked(c.Request.Context(), claims.UserID, issuedAt)
+			if err != nil {
+				// fail open like the rate limits, an unavailable Redis must not log everyone out
+				logger.Error("failed to check token revocation", "error", err)
+			} else if revoked {
+				logger.Warn("revoked access token used", "userId", claims.UserID)
+				c.JSON(http.StatusUnauthorized, app.Response{
+					Code:    app.UnauthorizedErrorCode,
+					Message: app.UnauthorizedErrorMessage,
+					Data: JWTErrorActionResponse{
+						Action: app.ActionLogout,
+					},
+				})
+				c.Abort()
+				return
+			}
+
 			c.Set("userId", claims.UserID)
+			c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "userId", claims.UserID))
 			c.Next()
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/PatiharnKam/AiLaw/app/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -31,15 +32,18 @@ func LoggerMiddleware() gin.HandlerFunc {
 			attrs = append(attrs, slog.String("errors", c.Errors.String()))
 		}
 
+		// the context logger carries the request ID and the user set by the auth middleware
+		logger := logging.FromContext(c.Request.Context())
+
 		// Log level
 		status := c.Writer.Status()
 		switch {
 		case status >= 500:
-			slog.LogAttrs(c, slog.LevelError, "Server Error", attrs...)
+			logger.LogAttrs(c, slog.LevelError, "Server Error", attrs...)
 		case status >= 400:
-			slog.LogAttrs(c, slog.LevelWarn, "Client Error", attrs...)
+			logger.LogAttrs(c, slog.LevelWarn, "Client Error", attrs...)
 		default:
-			slog.LogAttrs(c, slog.LevelInfo, "Request", attrs...)
+			logger.LogAttrs(c, slog.LevelInfo, "Request", attrs...)
 		}
 	}
 }
\ No newline at end of file
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware observes the duration of every request by its route template,
+// WebSocket upgrades are left out since their duration is the lifetime of the connection
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsAuth requires "Authorization: Bearer <token>" on /metrics when a token is configured
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, app.Response{
+				Code:    app.UnauthorizedErrorCode,
+				Message: app.UnauthorizedErrorMessage,
+			})
+			return
+		}
+		c.Next()
+	}
+}
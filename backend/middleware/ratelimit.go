@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/PatiharnKam/AiLaw/app/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByUser limits requests per authenticated user, it has to run after GinJWTMiddleware
+func RateLimitByUser(limiter *ratelimit.Limiter, rule ratelimit.Rule) gin.HandlerFunc {
+	return rateLimit(limiter, rule, func(c *gin.Context) string {
+		if userId := c.GetString("userId"); userId != "" {
+			return "user:" + userId
+		}
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// RateLimitByIP limits requests per client IP, for routes used before the user is authenticated
+func RateLimitByIP(limiter *ratelimit.Limiter, rule ratelimit.Rule) gin.HandlerFunc {
+	return rateLimit(limiter, rule, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+func rateLimit(limiter *ratelimit.Limiter, rule ratelimit.Rule, keyFunc func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		result, err := limiter.Allow(c.Request.Context(), rule, key)
+		if err != nil {
+			// fail open, an unavailable Redis must not take the API down
+			logging.FromContext(c.Request.Context()).Error("rate limit check failed", "rule", rule.Name, "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+
+		if !result.Allowed {
+			logging.FromContext(c.Request.Context()).Warn("rate limit exceeded", "rule", rule.Name, "key", key, "path", c.Request.URL.Path)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, app.Response{
+				Code:    app.RateLimitExceededErrorCode,
+				Message: app.RateLimitExceededErrorMessage,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// an X-Request-ID sent by the caller is only kept when it is safe to write in logs and headers
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID keeps the caller's X-Request-ID or generates one. The request context carries it with a logger
+// that also adds the session and message IDs of the route, the ID is echoed in the response header and in
+// the requestId field of every app.Response error
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set("requestId", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		if sessionId := c.Param("sessionID"); sessionId != "" {
+			ctx = logging.With(ctx, "sessionId", sessionId)
+		}
+		if messageId := c.Param("messageID"); messageId != "" {
+			ctx = logging.With(ctx, "messageId", messageId)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Writer = &requestIDWriter{ResponseWriter: c.Writer, requestID: requestID}
+		c.Next()
+	}
+}
+
+// requestIDWriter adds requestId to JSON error bodies shaped like app.Response that do not carry it yet
+type requestIDWriter struct {
+	gin.ResponseWriter
+	requestID string
+}
+
+// Unwrap lets http.ResponseController reach the connection, streaming handlers use it to extend their write deadline
+func (w *requestIDWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *requestIDWriter) Write(data []byte) (int, error) {
+	if w.Status() < http.StatusBadRequest || w.Written() || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil || body["code"] == nil || body["requestId"] != nil {
+		return w.ResponseWriter.Write(data)
+	}
+
+	requestID, _ := json.Marshal(w.requestID)
+	trimmed := bytes.TrimRight(data, " \n")
+	withID := append(append(append(trimmed[:len(trimmed)-1:len(trimmed)-1], `,"requestId":`...), requestID...), '}')
+	if _, err := w.ResponseWriter.Write(withID); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/PatiharnKam/AiLaw/app"
+	"github.com/PatiharnKam/AiLaw/app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	RoleUser     = "user"
+	RoleReviewer = "reviewer"
+	RoleAdmin    = "admin"
+)
+
+// RequireRole allows the request only when the authenticated user has one of roles,
+// it has to run after GinJWTMiddleware which sets userId
+func RequireRole(db *pgxpool.Pool, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetString("userId")
+
+		var role string
+		err := db.QueryRow(c.Request.Context(), `SELECT role FROM users WHERE user_id = $1`, userId).Scan(&role)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logging.FromContext(c.Request.Context()).Error("failed to get user role", "userId", userId, "error", err)
+			c.JSON(http.StatusInternalServerError, app.Response{
+				Code:    app.InternalServerErrorCode,
+				Message: app.InternalServerErrorMessage,
+			})
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(roles, role) {
+			logging.FromContext(c.Request.Context()).Error("permission denied", "userId", userId, "role", role)
+			c.JSON(http.StatusForbidden, app.Response{
+				Code:    app.ForbiddenErrorCode,
+				Message: app.ForbiddenErrorMessage,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -0,0 +1,3 @@
+-- Marks sessions renamed by the user so the auto-generated title never overwrites them
+ALTER TABLE chat_sessions
+    ADD COLUMN IF NOT EXISTS title_edited BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Soft delete for chat sessions, rows are purged with their messages once the trash retention expires
+ALTER TABLE chat_sessions
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_chat_sessions_deleted_at
+    ON chat_sessions (deleted_at)
+    WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Folders, tags and archiving for chat sessions
+CREATE TABLE IF NOT EXISTS session_folders (
+    folder_id   UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_folders_user_id ON session_folders (user_id);
+
+ALTER TABLE chat_sessions
+    ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES session_folders (folder_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id  UUID NOT NULL REFERENCES chat_sessions (session_id) ON DELETE CASCADE,
+    tag         TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (session_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag ON session_tags (tag);
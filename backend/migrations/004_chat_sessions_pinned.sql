@@ -0,0 +1,5 @@
+-- Pinned sessions are listed first in sessions history
+ALTER TABLE chat_sessions
+    ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_chat_sessions_folder_id ON chat_sessions (folder_id);
@@ -0,0 +1,30 @@
+-- Answer branches: every message points to the message it follows, siblings share a parent
+-- and exactly one sibling per parent is active
+ALTER TABLE user_messages
+    ADD COLUMN IF NOT EXISTS parent_message_id UUID,
+    ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE model_messages
+    ADD COLUMN IF NOT EXISTS parent_message_id UUID,
+    ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- model answers follow the user message that asked them
+UPDATE model_messages m
+SET parent_message_id = u.message_id
+FROM user_messages u
+WHERE u.model_answer_message_id = m.message_id
+    AND m.parent_message_id IS NULL;
+
+-- user messages follow the latest model answer before them, the first question has no parent
+UPDATE user_messages u
+SET parent_message_id = (
+    SELECT m.message_id
+    FROM model_messages m
+    WHERE m.session_id = u.session_id AND m.created_at < u.created_at
+    ORDER BY m.created_at DESC
+    LIMIT 1
+)
+WHERE u.parent_message_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_messages_parent ON user_messages (session_id, parent_message_id);
+CREATE INDEX IF NOT EXISTS idx_model_messages_parent ON model_messages (session_id, parent_message_id);
@@ -0,0 +1,8 @@
+-- Compare mode: answers generated side by side for one question share a compare group
+ALTER TABLE model_messages
+    ADD COLUMN IF NOT EXISTS compare_group_id UUID,
+    ADD COLUMN IF NOT EXISTS preferred BOOLEAN;
+
+CREATE INDEX IF NOT EXISTS idx_model_messages_compare_group
+    ON model_messages (compare_group_id)
+    WHERE compare_group_id IS NOT NULL;
@@ -0,0 +1,28 @@
+-- Roles: reviewers (legal QA team) and admins get access to the review and admin endpoints
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+-- Structured feedback: every change is appended here, model_messages keeps the latest rating and detail
+CREATE TABLE IF NOT EXISTS message_feedback (
+    feedback_id          UUID PRIMARY KEY,
+    message_id           UUID NOT NULL REFERENCES model_messages (message_id) ON DELETE CASCADE,
+    user_id              UUID NOT NULL,
+    rating               SMALLINT,
+    categories           TEXT[] NOT NULL DEFAULT '{}',
+    severity             TEXT,
+    suggested_correction TEXT,
+    detail               TEXT,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_feedback_message
+    ON message_feedback (message_id, created_at DESC);
+
+ALTER TABLE model_messages
+    ADD COLUMN IF NOT EXISTS feedback_categories TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS feedback_severity TEXT,
+    ADD COLUMN IF NOT EXISTS feedback_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_model_messages_negative_feedback
+    ON model_messages (feedback_at DESC)
+    WHERE feedback = -1;
@@ -0,0 +1,19 @@
+-- Organizations and their PII redaction policy, users without an organization use the PII_* config defaults.
+-- Admins create organizations and assign users to them through /admin/orgs
+CREATE TABLE IF NOT EXISTS organizations (
+    org_id     UUID PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations (org_id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS org_pii_policies (
+    org_id           UUID PRIMARY KEY REFERENCES organizations (org_id) ON DELETE CASCADE,
+    enabled          BOOLEAN NOT NULL DEFAULT TRUE,
+    entity_types     TEXT[] NOT NULL DEFAULT '{national_id,phone,email,bank_account,address}',
+    restore_response BOOLEAN NOT NULL DEFAULT TRUE,
+    store_mode       TEXT NOT NULL DEFAULT 'redacted' CHECK (store_mode IN ('redacted', 'original')),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+-- Prompts flagged or blocked by the moderation stage, kept for review
+CREATE TABLE IF NOT EXISTS moderation_flags (
+    flag_id    UUID PRIMARY KEY,
+    user_id    UUID NOT NULL,
+    session_id UUID NOT NULL,
+    action     TEXT NOT NULL,
+    violations JSONB NOT NULL DEFAULT '[]',
+    excerpt    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_flags_created_at
+    ON moderation_flags (created_at DESC);
@@ -0,0 +1,3 @@
+-- Plan tier of every user, the model queue serves enterprise and pro users before free users when it is saturated
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS plan TEXT NOT NULL DEFAULT 'free';
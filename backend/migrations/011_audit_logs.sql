@@ -0,0 +1,39 @@
+-- Append-only record of logins, token refreshes, session and feedback changes and admin actions
+CREATE TABLE IF NOT EXISTS audit_logs (
+    audit_id    UUID PRIMARY KEY,
+    actor_id    UUID,
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id   TEXT NOT NULL DEFAULT '',
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    request_id  TEXT NOT NULL DEFAULT '',
+    before      JSONB,
+    after       JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at
+    ON audit_logs (created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id
+    ON audit_logs (actor_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target
+    ON audit_logs (target_type, target_id, created_at DESC);
+
+-- Entries can never be changed, they can only be deleted by the retention purge,
+-- which sets audit.retention_purge for its own transaction
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.retention_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
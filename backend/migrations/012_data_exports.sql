@@ -0,0 +1,18 @@
+-- Personal data archives requested through POST /api/me/export, kept until expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+    export_id    UUID PRIMARY KEY,
+    user_id      UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    status       TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    archive      BYTEA,
+    size_bytes   BIGINT,
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id
+    ON data_exports (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at
+    ON data_exports (expires_at);
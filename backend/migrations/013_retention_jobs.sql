@@ -0,0 +1,30 @@
+-- Message retention an organization sets for its users, NULL keeps the RETENTION_MESSAGE_DAYS of their plan
+CREATE TABLE IF NOT EXISTS org_retention_policies (
+    org_id                 UUID PRIMARY KEY REFERENCES organizations (org_id) ON DELETE CASCADE,
+    message_retention_days INTEGER CHECK (message_retention_days > 0),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per background job, the lock columns make sure a single replica runs it at a time
+-- and the last_* columns are the status shown to admins
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name             TEXT PRIMARY KEY,
+    locked_by        TEXT,
+    locked_until     TIMESTAMPTZ,
+    last_started_at  TIMESTAMPTZ,
+    last_finished_at TIMESTAMPTZ,
+    last_status      TEXT CHECK (last_status IN ('succeeded', 'failed')),
+    last_error       TEXT,
+    last_affected    BIGINT,
+    run_count        BIGINT NOT NULL DEFAULT 0,
+    failure_count    BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at
+    ON refresh_tokens (expires_at);
+
+CREATE INDEX IF NOT EXISTS idx_user_messages_session_created_at
+    ON user_messages (session_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_model_messages_session_created_at
+    ON model_messages (session_id, created_at);
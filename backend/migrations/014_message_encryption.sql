@@ -0,0 +1,19 @@
+-- Data keys message content is encrypted with, wrapped by the master key named in master_key_id.
+-- A user gets a new version on rotation, older versions stay readable for the content still encrypted with them.
+-- Account deletion removes the content first and then the keys
+CREATE TABLE IF NOT EXISTS data_keys (
+    key_id        UUID PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    version       INTEGER NOT NULL CHECK (version > 0),
+    wrapped_key   BYTEA NOT NULL,
+    master_key_id TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_keys_master_key_id
+    ON data_keys (master_key_id);
+
+-- The data key the content is encrypted with, NULL while the content is stored in plaintext
+ALTER TABLE user_messages ADD COLUMN IF NOT EXISTS content_key_id UUID REFERENCES data_keys (key_id);
+ALTER TABLE model_messages ADD COLUMN IF NOT EXISTS content_key_id UUID REFERENCES data_keys (key_id);
@@ -0,0 +1,24 @@
+-- Account deletion anonymises the audit entries of the user, it sets audit.anonymise for its own
+-- transaction and may only clear the client and the before/after state, everything else stays as it was
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.retention_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND current_setting('audit.anonymise', true) = 'on'
+        AND NEW.audit_id = OLD.audit_id
+        AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+        AND NEW.action = OLD.action
+        AND NEW.target_type = OLD.target_type
+        AND NEW.target_id = OLD.target_id
+        AND NEW.request_id = OLD.request_id
+        AND NEW.created_at = OLD.created_at
+        AND NEW.ip = ''
+        AND NEW.user_agent = ''
+        AND NEW.before IS NULL
+        AND NEW.after IS NULL THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,9 @@
+-- Marks sessions whose title generation has already run, it runs once per session whether the
+-- first turn was a single answer or a compare turn
+ALTER TABLE chat_sessions
+    ADD COLUMN IF NOT EXISTS title_generated BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE chat_sessions s
+SET title_generated = TRUE
+WHERE s.title_generated = FALSE
+  AND EXISTS (SELECT 1 FROM model_messages m WHERE m.session_id = s.session_id);
@@ -89,6 +89,16 @@ DETAIL_STREAMING_SYSTEM_PROMTPS = """
             Pleumjai: ให้บังคับตามบทบัญญัติแห่งประมวลกฎหมายแพ่งและพาณิชย์ ว่าด้วยซื้อขาย"
 """
 
+""" Title agent """
+TITLE_SYSTEM_PROMPT = """
+            You write short titles for legal consultation chats about Thailand's Civil and Commercial Code.
+            Read the user's question and the assistant's answer, then summarise the topic as a concise Thai title of at most 8 words.
+            Do not add quotes, punctuation at the end, or section numbers unless they are the main topic.
+
+            Response Format:
+            Return ONLY a JSON object: {"title": "<Thai title>"}
+"""
+
 """ Guard agent """
 GUARD_SYSTEM_PROMPTS = """
             You are an expert Thai legal classifier. Your task is to determine if a question is related to Thai Civil and Commercial Code (ประมวลกฎหมายแพ่งและพาณิชย์) or not.
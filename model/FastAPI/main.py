@@ -19,6 +19,10 @@ from agents.detail_agent import DetailsAgent
 # Streaming agents
 from agents.detail_agent_streaming import DetailsAgentStreaming
 
+from agents.prompts import TITLE_SYSTEM_PROMPT
+from agents.utils import get_chatbot_response
+from json_repair import repair_json
+
 app = FastAPI(title="Legal Chatbot API")
 
 # ---------------------------------------------------------
@@ -259,6 +263,33 @@ async def cancel_chat(request: CancelRequest):
         return {"status": "cancelled", "session_id": request.session_id}
     return {"status": "not_found", "session_id": request.session_id}
 
+# ---------------------------------------------------------
+# Title Endpoint
+# ---------------------------------------------------------
+
+@app.post("/v1/title")
+async def generate_title(request: ChatRequest):
+    """Generate a short Thai title for a chat session"""
+    try:
+        conversation = "\n\n".join(f"{msg.role}: {msg.content}" for msg in request.messages)
+        messages = [
+            {"role": "system", "content": TITLE_SYSTEM_PROMPT},
+            {"role": "user", "content": conversation},
+        ]
+        output, _, _, total_tokens = await get_chatbot_response(
+            detail_agent_streaming.client,
+            detail_agent_streaming.model_name,
+            messages,
+        )
+        title = json.loads(repair_json(output)).get("title", "")
+        return {"title": title, "totalUsedTokens": total_tokens}
+
+    except Exception as e:
+        import traceback
+        error_detail = traceback.format_exc()
+        print(f"Title Error Detail:\n{error_detail}")
+        raise HTTPException(status_code=500, detail=str(e))
+
 # ---------------------------------------------------------
 # Health Check
 # ---------------------------------------------------------